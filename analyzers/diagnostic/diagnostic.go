@@ -0,0 +1,35 @@
+package diagnostic
+
+import (
+	"fmt"
+	"github.com/wevertonbruno/wb-compiler/analyzers/reader"
+	"github.com/wevertonbruno/wb-compiler/analyzers/token"
+)
+
+type Diagnostic struct {
+	Message  string
+	Token    token.Token
+	Position reader.Position
+}
+
+func New(message string, tok token.Token) Diagnostic {
+	return Diagnostic{
+		Message:  message,
+		Token:    tok,
+		Position: tok.Position,
+	}
+}
+
+// At returns a diagnostic for a position that does not start a token the
+// caller still has, such as the operator of an instruction.
+func At(message string, pos reader.Position) Diagnostic {
+	return Diagnostic{Message: message, Position: pos}
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s", d.Position.Line, d.Position.Column, d.Message)
+}
+
+func (d Diagnostic) Error() string {
+	return d.String()
+}
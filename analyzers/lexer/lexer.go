@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"github.com/wevertonbruno/wb-compiler/analyzers/reader"
 	"github.com/wevertonbruno/wb-compiler/analyzers/token"
+	"strconv"
+	"unicode/utf8"
 )
 
 const (
@@ -17,6 +19,20 @@ type Lexer struct {
 
 	currentChar     byte
 	currentSpelling string
+
+	// pending are the tokens to return after the error found in them, so
+	// that the parser does not report them missing as well
+	pending []token.Token
+	// interpolations are the strings whose interpolation is being lexed,
+	// the innermost last
+	interpolations []interpolation
+}
+
+// interpolation is a ${...} of a string literal that starts at start. braces
+// counts the braces opened inside, which its closing brace must match.
+type interpolation struct {
+	start  reader.Position
+	braces int
 }
 
 func NewLexer(reader reader.Reader) *Lexer {
@@ -30,6 +46,11 @@ func NewLexer(reader reader.Reader) *Lexer {
 }
 
 func (l *Lexer) GetToken() token.Token {
+	if len(l.pending) > 0 {
+		tok := l.pending[0]
+		l.pending = l.pending[1:]
+		return tok
+	}
 	l.skipWhiteSpace()
 	l.skipComment()
 	defer l.next()
@@ -48,17 +69,30 @@ func (l *Lexer) GetToken() token.Token {
 	case ')':
 		return token.NewToken(token.R_BRACKET, l.reader.CurrentPosition())
 	case '{':
+		if n := len(l.interpolations); n > 0 {
+			l.interpolations[n-1].braces++
+		}
 		return token.NewToken(token.L_BRACE, l.reader.CurrentPosition())
 	case '}':
+		if n := len(l.interpolations); n > 0 {
+			if l.interpolations[n-1].braces == 0 {
+				start := l.interpolations[n-1].start
+				l.interpolations = l.interpolations[:n-1]
+				return l.readString(start, l.reader.CurrentPosition(), token.STRINGEND, token.STRINGMID)
+			}
+			l.interpolations[n-1].braces--
+		}
 		return token.NewToken(token.R_BRACE, l.reader.CurrentPosition())
 	case ':':
 		return token.NewToken(token.COLON, l.reader.CurrentPosition())
 	case ';':
 		return token.NewToken(token.SEMICOLON, l.reader.CurrentPosition())
+	case ',':
+		return token.NewToken(token.COMMA, l.reader.CurrentPosition())
 	case reader.EOL:
-		return token.NewToken(token.NEWLINE, l.reader.CurrentPosition())
+		return l.endLine(token.NewToken(token.NEWLINE, l.reader.CurrentPosition()))
 	case reader.EOF:
-		return token.NewToken(token.EOF, l.reader.CurrentPosition())
+		return l.endLine(token.NewToken(token.EOF, l.reader.CurrentPosition()))
 	case '=':
 		//Check if next character is an eq symbol
 		if l.peek() == '=' {
@@ -88,17 +122,52 @@ func (l *Lexer) GetToken() token.Token {
 		} else {
 			return token.NewToken(token.NOT, l.reader.CurrentPosition())
 		}
+	case '&', '|':
+		// the operators are doubled, a single & or | means nothing
+		pos := l.reader.CurrentPosition()
+		if l.peek() != l.currentChar {
+			return l.errorToken("Unknown token: "+string(l.currentChar), pos)
+		}
+		l.next()
+		if l.currentChar == '&' {
+			return token.NewToken(token.AND, pos)
+		}
+		return token.NewToken(token.OR, pos)
 	case '"':
+		pos := l.reader.CurrentPosition()
+		return l.readString(pos, pos, token.STRINGLIT, token.STRINGSTART)
+	case '`':
+		// raw strings may span lines and have no escape sequences
 		var str []byte
 		pos := l.reader.CurrentPosition()
-		if l.peek() != '"' {
+		for l.peek() != '`' {
+			if l.peek() == reader.EOF {
+				unterminated := l.errorToken("Unterminated raw string literal", pos)
+				return l.literal(token.NewTokenString(token.STRINGLIT, string(str), pos), &unterminated)
+			}
 			l.next()
-			for l.currentChar != '"' {
+			if l.currentChar != '\r' {
 				str = append(str, l.currentChar)
-				l.next()
 			}
 		}
+		l.next()
 		return token.NewTokenString(token.STRINGLIT, string(str), pos)
+	case '\'':
+		pos := l.reader.CurrentPosition()
+		char, raw, invalid, end := l.readQuoted('\'', false)
+		if end == 0 {
+			unterminated := l.errorToken("Unterminated char literal", pos)
+			invalid = &unterminated
+		}
+		if len(char) != 1 && invalid == nil {
+			tok := l.errorToken("Char literal must hold a single character: '"+string(raw)+"'", pos)
+			invalid = &tok
+		}
+		if len(char) == 0 {
+			// the literal is still returned, with a placeholder value
+			char = []byte{0}
+		}
+		return l.literal(token.NewTokenString(token.CHARLIT, string(char[:1]), pos), invalid)
 	default:
 		if isDigit(l.currentChar) { // Check for numbers
 			var number []byte
@@ -112,7 +181,7 @@ func (l *Lexer) GetToken() token.Token {
 				l.next()
 				number = append(number, l.currentChar)
 				if !isDigit(l.peek()) {
-					l.abort("Illegal character in number: " + string(number) + string(l.peek()))
+					return l.errorToken("Illegal character in number: "+string(number)+string(l.peek()), pos)
 				}
 				for isDigit(l.peek()) {
 					l.next()
@@ -132,10 +201,146 @@ func (l *Lexer) GetToken() token.Token {
 			}
 			return token.NewTokenString(token.IDENTIFIER, string(id), pos)
 		} else {
-			l.abort("Unknown token: " + string(l.currentChar))
+			return l.errorToken("Unknown token: "+string(l.currentChar), l.reader.CurrentPosition())
+		}
+	}
+}
+
+// literal returns tok, unless an error was found in it. The error is
+// returned instead, and tok right after, so that the parser does not find
+// the literal missing.
+func (l *Lexer) literal(tok token.Token, invalid *token.Token) token.Token {
+	if invalid == nil {
+		return tok
+	}
+	l.pending = append(l.pending, tok)
+	return *invalid
+}
+
+// readString reads a string literal, or its rest after an interpolation,
+// up to its closing quote or the next interpolation. The token starts at
+// pos and is of kind closed, or of kind open when an interpolation follows.
+// An unterminated string is reported at start, its opening quote, or at
+// that of the outermost string around it, and is still returned.
+func (l *Lexer) readString(start, pos reader.Position, closed, open token.Kind) token.Token {
+	str, _, invalid, end := l.readQuoted('"', true)
+	kind := closed
+	switch end {
+	case 0:
+		l.pending = append(l.pending, token.NewTokenString(closed, string(str), pos))
+		if len(l.interpolations) > 0 {
+			start = l.interpolations[0].start
 		}
+		return l.unterminated(start, l.reader.CurrentPosition())
+	case '{':
+		l.interpolations = append(l.interpolations, interpolation{start: start})
+		kind = open
 	}
-	return token.Token{}
+	return l.literal(token.NewTokenString(kind, string(str), pos), invalid)
+}
+
+// endLine returns tok, the end of a line, unless a string is left
+// unterminated in one of its interpolations. The error is returned instead,
+// then the ends of the strings and tok.
+func (l *Lexer) endLine(tok token.Token) token.Token {
+	if len(l.interpolations) == 0 {
+		return tok
+	}
+	unterminated := l.unterminated(l.interpolations[0].start, tok.Position)
+	l.pending = append(l.pending, tok)
+	return unterminated
+}
+
+// unterminated reports a string left unterminated at start, its outermost
+// opening quote, and queues the ends of the strings whose interpolations
+// are still open at pos.
+func (l *Lexer) unterminated(start, pos reader.Position) token.Token {
+	for range l.interpolations {
+		l.pending = append(l.pending, token.NewTokenString(token.STRINGEND, "", pos))
+	}
+	l.interpolations = nil
+	return l.errorToken("Unterminated string literal", start)
+}
+
+// readQuoted reads a string or char literal up to its closing quote, which
+// must be on the same line, and decodes its escape sequences. With
+// interpolate, it stops after the ${ starting an interpolation. It returns
+// the value and the characters read, the error of the first invalid escape
+// sequence, if any, and the quote or the brace it stopped at, or 0 when the
+// literal is unterminated.
+func (l *Lexer) readQuoted(quote byte, interpolate bool) (value, raw []byte, invalid *token.Token, end byte) {
+	for l.peek() != quote {
+		if l.peek() == newLineSymbol || l.peek() == reader.EOF {
+			return value, raw, invalid, 0
+		}
+		l.next()
+		if interpolate && l.currentChar == '$' && l.peek() == '{' {
+			l.next()
+			return value, raw, invalid, '{'
+		}
+		raw = append(raw, l.currentChar)
+		if l.currentChar != '\\' {
+			value = append(value, l.currentChar)
+			continue
+		}
+		escapePos := l.reader.CurrentPosition()
+		decoded, sequence, message := l.readEscape()
+		raw = append(raw, sequence...)
+		value = append(value, decoded...)
+		if message != "" && invalid == nil {
+			tok := l.errorToken(message, escapePos)
+			invalid = &tok
+		}
+	}
+	l.next()
+	return value, raw, invalid, quote
+}
+
+// readEscape decodes the escape sequence after a backslash. It returns the
+// bytes it stands for and the characters read, or a message when the
+// sequence is invalid. A backslash ending the line is left to the caller,
+// which finds the literal unterminated.
+func (l *Lexer) readEscape() (value, sequence []byte, message string) {
+	c := l.peek()
+	if c == newLineSymbol || c == reader.EOF {
+		return nil, nil, ""
+	}
+	l.next()
+	sequence = []byte{c}
+	switch c {
+	case 'n':
+		return []byte{'\n'}, sequence, ""
+	case 't':
+		return []byte{'\t'}, sequence, ""
+	case 'r':
+		return []byte{'\r'}, sequence, ""
+	case '\\', '"', '\'', '$':
+		return []byte{c}, sequence, ""
+	case 'u':
+		if l.peek() != '{' {
+			return nil, sequence, "Invalid unicode escape: \\" + string(sequence)
+		}
+		l.next()
+		sequence = append(sequence, '{')
+		var digits []byte
+		for isHexDigit(l.peek()) {
+			l.next()
+			digits = append(digits, l.currentChar)
+		}
+		sequence = append(sequence, digits...)
+		if l.peek() != '}' || len(digits) == 0 || len(digits) > 6 {
+			return nil, sequence, "Invalid unicode escape: \\" + string(sequence)
+		}
+		l.next()
+		sequence = append(sequence, '}')
+		// NUL ends the strings of the native targets
+		code, _ := strconv.ParseUint(string(digits), 16, 32)
+		if r := rune(code); r != 0 && utf8.ValidRune(r) {
+			return []byte(string(r)), sequence, ""
+		}
+		return nil, sequence, "Invalid code point: \\" + string(sequence)
+	}
+	return nil, sequence, "Unknown escape sequence: \\" + string(sequence)
 }
 
 func (l *Lexer) next() {
@@ -146,8 +351,8 @@ func (l *Lexer) peek() byte {
 	return l.reader.Peek()
 }
 
-func (l *Lexer) abort(message string) error {
-	panic(fmt.Errorf(lexicalError, message))
+func (l *Lexer) errorToken(message string, pos reader.Position) token.Token {
+	return token.NewTokenString(token.ERROR, fmt.Sprintf(lexicalError, message), pos)
 }
 
 func (l *Lexer) skipWhiteSpace() {
@@ -180,6 +385,10 @@ func isDigit(b byte) bool {
 	return b >= zero && b <= nine
 }
 
+func isHexDigit(b byte) bool {
+	return isDigit(b) || b >= 'a' && b <= 'f' || b >= 'A' && b <= 'F'
+}
+
 func isAlpha(b byte) bool {
 	a := byte('a')
 	z := byte('z')
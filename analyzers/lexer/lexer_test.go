@@ -0,0 +1,98 @@
+package lexer
+
+import (
+	"github.com/wevertonbruno/wb-compiler/analyzers/reader"
+	"github.com/wevertonbruno/wb-compiler/analyzers/token"
+	"testing"
+)
+
+func TestStringLiterals(t *testing.T) {
+	tok := func(kind token.Kind, spelling string, line, column int) token.Token {
+		return token.Token{Kind: kind, Spelling: spelling, Position: reader.Position{Line: line, Column: column}}
+	}
+	lexical := func(message string, line, column int) token.Token {
+		return tok(token.ERROR, "lexical error. "+message, line, column)
+	}
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{`"\u{1F600}x\t\$\"\\"`, []token.Token{tok(token.STRINGLIT, "😀x\t$\"\\", 1, 1)}},
+		{"`a\\n\r\n\"b\"`", []token.Token{tok(token.STRINGLIT, "a\\n\n\"b\"", 1, 1)}},
+		{`'\n'`, []token.Token{tok(token.CHARLIT, "\n", 1, 1)}},
+		// the errors come before the literals they are found in
+		{`"a\qb"`, []token.Token{
+			lexical(`Unknown escape sequence: \q`, 1, 3),
+			tok(token.STRINGLIT, "ab", 1, 1),
+		}},
+		{`"\u{0}"`, []token.Token{
+			lexical(`Invalid code point: \u{0}`, 1, 2),
+			tok(token.STRINGLIT, "", 1, 1),
+		}},
+		{`"\u{110000}"`, []token.Token{
+			lexical(`Invalid code point: \u{110000}`, 1, 2),
+			tok(token.STRINGLIT, "", 1, 1),
+		}},
+		{`"\u{}"`, []token.Token{
+			lexical(`Invalid unicode escape: \u{`, 1, 2),
+			tok(token.STRINGLIT, "}", 1, 1),
+		}},
+		{`"\u41"`, []token.Token{
+			lexical(`Invalid unicode escape: \u`, 1, 2),
+			tok(token.STRINGLIT, "41", 1, 1),
+		}},
+		{`'ab'`, []token.Token{
+			lexical("Char literal must hold a single character: 'ab'", 1, 1),
+			tok(token.CHARLIT, "a", 1, 1),
+		}},
+		{`"abc`, []token.Token{
+			lexical("Unterminated string literal", 1, 1),
+			tok(token.STRINGLIT, "abc", 1, 1),
+		}},
+		{"\"abc\nd", []token.Token{
+			lexical("Unterminated string literal", 1, 1),
+			tok(token.STRINGLIT, "abc", 1, 1),
+			tok(token.NEWLINE, "<new line>", 2, 0),
+			tok(token.IDENTIFIER, "d", 2, 1),
+		}},
+		{"`ab\ncd", []token.Token{
+			lexical("Unterminated raw string literal", 1, 1),
+			tok(token.STRINGLIT, "ab\ncd", 1, 1),
+		}},
+		{"'a\nx", []token.Token{
+			lexical("Unterminated char literal", 1, 1),
+			tok(token.CHARLIT, "a", 1, 1),
+			tok(token.NEWLINE, "<new line>", 2, 0),
+			tok(token.IDENTIFIER, "x", 2, 1),
+		}},
+		// the strings left open around are closed
+		{`"a${b`, []token.Token{
+			tok(token.STRINGSTART, "a", 1, 1),
+			tok(token.IDENTIFIER, "b", 1, 5),
+			lexical("Unterminated string literal", 1, 1),
+			tok(token.STRINGEND, "", 1, 5),
+		}},
+		{`"a${"b`, []token.Token{
+			tok(token.STRINGSTART, "a", 1, 1),
+			lexical("Unterminated string literal", 1, 1),
+			tok(token.STRINGLIT, "b", 1, 5),
+			tok(token.STRINGEND, "", 1, 6),
+		}},
+	}
+	for _, tt := range tests {
+		var tokens []token.Token
+		l := NewLexer(reader.NewInput(tt.input))
+		for tok := l.GetToken(); tok.Kind != token.EOF; tok = l.GetToken() {
+			tokens = append(tokens, tok)
+		}
+		if len(tokens) != len(tt.expected) {
+			t.Errorf("wrong tokens for %q. want=%v, got=%v", tt.input, tt.expected, tokens)
+			continue
+		}
+		for i, expected := range tt.expected {
+			if tokens[i] != expected {
+				t.Errorf("tokens[%d] wrong for %q. want=%v, got=%v", i, tt.input, expected, tokens[i])
+			}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"github.com/wevertonbruno/wb-compiler/analyzers/diagnostic"
 	"github.com/wevertonbruno/wb-compiler/analyzers/lexer"
 	"github.com/wevertonbruno/wb-compiler/analyzers/token"
 	"github.com/wevertonbruno/wb-compiler/ast"
@@ -11,6 +12,8 @@ import (
 const (
 	_ byte = iota
 	LOWEST
+	LOGICAL_OR
+	LOGICAL_AND
 	EQUALS
 	LESSGREATER
 	SUM
@@ -25,6 +28,8 @@ const (
 var (
 	//precedence table
 	precedences = map[token.Kind]byte{
+		token.OR:        LOGICAL_OR,
+		token.AND:       LOGICAL_AND,
 		token.OP_EQ:     EQUALS,
 		token.OP_NOTEQ:  EQUALS,
 		token.OP_LT:     LESSGREATER,
@@ -35,6 +40,7 @@ var (
 		token.OP_MINUS:  SUM,
 		token.OP_DIVIDE: PRODUCT,
 		token.OP_MULTI:  PRODUCT,
+		token.L_BRACKET: CALL,
 	}
 )
 
@@ -46,22 +52,29 @@ type (
 
 		prefixParseFn map[token.Kind]prefixParseFn
 		infixParseFn  map[token.Kind]infixParseFn
+
+		errors []diagnostic.Diagnostic
 	}
 
 	prefixParseFn func() ast.Expr
 	infixParseFn  func(ast.Expr) ast.Expr
+
+	// bailout unwinds the parser up to the nearest statement boundary
+	bailout struct{}
 )
 
 func NewParser(lex *lexer.Lexer) *Parser {
-	parser := &Parser{
-		lexer:        lex,
-		currentToken: lex.GetToken(),
-		peekToken:    lex.GetToken(),
-	}
+	parser := &Parser{lexer: lex}
+	parser.currentToken = parser.readToken()
+	parser.peekToken = parser.readToken()
 	parser.prefixParseFn = make(map[token.Kind]prefixParseFn)
 	parser.registerPrefix(token.IDENTIFIER, parser.parseIdentifier)
+	parser.registerPrefix(token.PRINT, parser.parseIdentifier)
 	parser.registerPrefix(token.INTLIT, parser.parseIntegerLiteral)
 	parser.registerPrefix(token.DECIMALLIT, parser.parseDecimalLiteral)
+	parser.registerPrefix(token.STRINGLIT, parser.parseStringLiteral)
+	parser.registerPrefix(token.STRINGSTART, parser.parseInterpolatedString)
+	parser.registerPrefix(token.CHARLIT, parser.parseCharLiteral)
 	parser.registerPrefix(token.TRUE, parser.parseBoolean)
 	parser.registerPrefix(token.FALSE, parser.parseBoolean)
 	parser.registerPrefix(token.NOT, parser.parsePrefixExpr)
@@ -80,17 +93,29 @@ func NewParser(lex *lexer.Lexer) *Parser {
 	parser.registerInfix(token.OP_LTE, parser.parseInfixExpr)
 	parser.registerInfix(token.OP_GT, parser.parseInfixExpr)
 	parser.registerInfix(token.OP_GTE, parser.parseInfixExpr)
+	parser.registerInfix(token.AND, parser.parseInfixExpr)
+	parser.registerInfix(token.OR, parser.parseInfixExpr)
+	parser.registerInfix(token.L_BRACKET, parser.parseCallExpr)
 
 	return parser
 }
 
+func (p *Parser) readToken() token.Token {
+	tok := p.lexer.GetToken()
+	for tok.Match(token.ERROR) {
+		p.errors = append(p.errors, diagnostic.New(tok.Spelling, tok))
+		tok = p.lexer.GetToken()
+	}
+	return tok
+}
+
 func (p *Parser) nextToken(ignoreNewLine bool) {
 	p.currentToken = p.peekToken
-	p.peekToken = p.lexer.GetToken()
+	p.peekToken = p.readToken()
 	if ignoreNewLine && p.currentToken.Match(token.NEWLINE) {
 		for p.currentToken.Match(token.NEWLINE) {
 			p.currentToken = p.peekToken
-			p.peekToken = p.lexer.GetToken()
+			p.peekToken = p.readToken()
 		}
 	}
 }
@@ -115,7 +140,7 @@ func (p *Parser) expectedPeek(kind token.Kind) {
 	if p.peekToken.Match(kind) {
 		p.nextToken(false)
 	} else {
-		p.abort(fmt.Sprintf(expectedError, kind.Name(), p.currentToken.Kind.Name()))
+		p.abortAt(p.peekToken, fmt.Sprintf(expectedError, kind.Name(), p.peekToken.Kind.Name()))
 	}
 }
 
@@ -127,8 +152,13 @@ func (p *Parser) checkPeek(k token.Kind) bool {
 	return p.peekToken.Match(k)
 }
 
-func (p *Parser) abort(message string) error {
-	panic(fmt.Errorf(parserError, message))
+func (p *Parser) abort(message string) {
+	p.abortAt(p.currentToken, message)
+}
+
+func (p *Parser) abortAt(tok token.Token, message string) {
+	p.errors = append(p.errors, diagnostic.New(fmt.Sprintf(parserError, message), tok))
+	panic(bailout{})
 }
 
 func (p *Parser) registerPrefix(k token.Kind, fn prefixParseFn) {
@@ -153,18 +183,17 @@ func (p *Parser) currentPrecedence() byte {
 	return LOWEST
 }
 
-func (p *Parser) Parse() *ast.Prog {
+func (p *Parser) Parse() (*ast.Prog, []diagnostic.Diagnostic) {
 	prog := newProgNode()
 	p.ignoreNewLines()
 	for !p.currentToken.Match(token.EOF) {
-		stmt := p.parseStatement()
+		stmt := p.parseStatementOrSync()
 		if stmt != nil {
 			prog.Statements = append(prog.Statements, stmt)
 		}
 		p.nextToken(true)
 	}
-	fmt.Println(prog)
-	return prog
+	return prog, p.errors
 }
 
 func newProgNode() *ast.Prog {
@@ -179,11 +208,53 @@ func (p *Parser) parseStatement() ast.Stmt {
 		return p.parseVarStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FUNCTION:
+		return p.parseFuncDecl()
+	case token.IDENTIFIER:
+		if p.checkPeek(token.ASSIGN) {
+			return p.parseAssignStatement()
+		}
+		return p.parseExpressionStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
 }
 
+// parseStatementOrSync parses a statement and, if it aborts, skips to the
+// next statement boundary so that parsing can go on collecting errors.
+func (p *Parser) parseStatementOrSync() (stmt ast.Stmt) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(bailout); !ok {
+				panic(r)
+			}
+			stmt = nil
+			p.synchronize()
+		}
+	}()
+	return p.parseStatement()
+}
+
+func (p *Parser) synchronize() {
+	depth := 0
+	for !p.check(token.EOF) {
+		switch {
+		case p.check(token.L_BRACE):
+			depth++
+		case p.check(token.R_BRACE):
+			if depth == 0 {
+				return
+			}
+			depth--
+		case depth == 0 && p.checkSeparator():
+			return
+		}
+		p.nextToken(false)
+	}
+}
+
 func (p *Parser) parseNewLine() {
 	p.match(token.NEWLINE)
 	for p.check(token.NEWLINE) {
@@ -215,19 +286,37 @@ func (p *Parser) peekSeparator() bool {
 		p.peekToken.Match(token.NEWLINE)
 }
 
+func (p *Parser) expectedType() token.Token {
+	if !p.peekToken.Kind.IsType() {
+		p.abort(fmt.Sprintf(expectedError, "type", p.peekToken.Kind.Name()))
+	}
+	p.nextToken(false)
+	return p.currentToken
+}
+
 func (p *Parser) parseVarStatement() *ast.DeclStatement {
 	stmt := &ast.DeclStatement{Token: p.currentToken}
 	p.expectedPeek(token.IDENTIFIER)
 	stmt.ID = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Spelling}
 	p.expectedPeek(token.COLON)
+	stmt.Type = p.expectedType()
+	p.expectedPeek(token.ASSIGN)
 	p.nextToken(false)
-	if p.checkPeek(token.INTEGER) || p.checkPeek(token.DECIMAL) || p.checkPeek(token.STRING) || p.checkPeek(token.CHAR) {
+	stmt.Value = p.parseExpression(LOWEST)
+	if p.peekSeparator() {
 		p.nextToken(false)
-		stmt.Type = p.currentToken
 	}
-	p.expectedPeek(token.ASSIGN)
+
+	return stmt
+}
+
+func (p *Parser) parseAssignStatement() *ast.AssignStatement {
+	target := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Spelling}
+	p.nextToken(false)
+	stmt := &ast.AssignStatement{Token: p.currentToken, Target: target}
+	p.nextToken(false)
 	stmt.Value = p.parseExpression(LOWEST)
-	if p.checkSeparator() {
+	if p.peekSeparator() {
 		p.nextToken(false)
 	}
 
@@ -238,13 +327,69 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	ret := &ast.ReturnStatement{
 		Token: p.currentToken,
 	}
-	for !p.checkSeparator() {
+	if !p.peekSeparator() && !p.checkPeek(token.R_BRACE) && !p.checkPeek(token.EOF) {
+		p.nextToken(false)
+		ret.Expr = p.parseExpression(LOWEST)
+	}
+	if p.peekSeparator() {
 		p.nextToken(false)
 	}
 
 	return ret
 }
 
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.currentToken}
+	p.nextToken(false)
+	stmt.Condition = p.parseExpression(LOWEST)
+	p.expectedPeek(token.L_BRACE)
+	stmt.Body = p.parseBlockStatement()
+
+	return stmt
+}
+
+func (p *Parser) parseFuncDecl() *ast.FuncDecl {
+	fn := &ast.FuncDecl{Token: p.currentToken}
+	p.expectedPeek(token.IDENTIFIER)
+	fn.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Spelling}
+	p.expectedPeek(token.L_BRACKET)
+	fn.Parameters = p.parseParameters()
+	if p.checkPeek(token.COLON) {
+		p.nextToken(false)
+		fn.ReturnType = p.expectedType()
+	}
+	p.expectedPeek(token.L_BRACE)
+	fn.Body = p.parseBlockStatement()
+
+	return fn
+}
+
+func (p *Parser) parseParameters() []*ast.Parameter {
+	params := []*ast.Parameter{}
+	if p.checkPeek(token.R_BRACKET) {
+		p.nextToken(false)
+		return params
+	}
+	p.expectedPeek(token.IDENTIFIER)
+	params = append(params, p.parseParameter())
+	for p.checkPeek(token.COMMA) {
+		p.nextToken(false)
+		p.expectedPeek(token.IDENTIFIER)
+		params = append(params, p.parseParameter())
+	}
+	p.expectedPeek(token.R_BRACKET)
+
+	return params
+}
+
+func (p *Parser) parseParameter() *ast.Parameter {
+	param := &ast.Parameter{Token: p.currentToken}
+	param.ID = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Spelling}
+	p.expectedPeek(token.COLON)
+	param.Type = p.expectedType()
+	return param
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExprStatement {
 	expr := &ast.ExprStatement{Token: p.currentToken}
 	expr.Expr = p.parseExpression(LOWEST)
@@ -257,8 +402,12 @@ func (p *Parser) parseExpressionStatement() *ast.ExprStatement {
 func (p *Parser) parseExpression(precedence byte) ast.Expr {
 	prefix := p.prefixParseFn[p.currentToken.Kind]
 	if prefix == nil {
-		p.abort(fmt.Sprintf("no prefix parse function for %s found", p.currentToken.Spelling))
-		return nil
+		// the rest of a string is named by its kind, not by its text
+		found := p.currentToken.Spelling
+		if kind := p.currentToken.Kind; kind == token.STRINGMID || kind == token.STRINGEND {
+			found = kind.Name()
+		}
+		p.abort(fmt.Sprintf("no prefix parse function for %s found", found))
 	}
 	leftExpr := prefix()
 
@@ -281,6 +430,37 @@ func (p *Parser) parseBoolean() ast.Expr {
 	return &ast.Boolean{Token: p.currentToken, Value: p.check(token.TRUE)}
 }
 
+func (p *Parser) parseStringLiteral() ast.Expr {
+	return &ast.StringLiteral{Token: p.currentToken, Value: p.currentToken.Spelling}
+}
+
+// parseInterpolatedString parses the pieces of a string literal and the
+// expressions interpolated between them.
+func (p *Parser) parseInterpolatedString() ast.Expr {
+	str := &ast.InterpolatedString{Token: p.currentToken}
+	for {
+		if p.currentToken.Spelling != "" {
+			str.Parts = append(str.Parts, &ast.StringLiteral{Token: p.currentToken, Value: p.currentToken.Spelling})
+		}
+		if p.check(token.STRINGEND) {
+			return str
+		}
+		p.nextToken(false)
+		if p.check(token.STRINGMID) || p.check(token.STRINGEND) {
+			p.abort("empty interpolation")
+		}
+		str.Parts = append(str.Parts, p.parseExpression(LOWEST))
+		if !p.checkPeek(token.STRINGMID) && !p.checkPeek(token.STRINGEND) {
+			p.abortAt(p.peekToken, fmt.Sprintf(expectedError, token.R_BRACE.Name(), p.peekToken.Kind.Name()))
+		}
+		p.nextToken(false)
+	}
+}
+
+func (p *Parser) parseCharLiteral() ast.Expr {
+	return &ast.CharLiteral{Token: p.currentToken, Value: p.currentToken.Spelling[0]}
+}
+
 func (p *Parser) parseIntegerLiteral() ast.Expr {
 	lit := &ast.IntegerLiteral{Token: p.currentToken}
 
@@ -329,6 +509,30 @@ func (p *Parser) parseGroupedExpr() ast.Expr {
 	return expr
 }
 
+func (p *Parser) parseCallExpr(function ast.Expr) ast.Expr {
+	expr := &ast.CallExpression{Token: p.currentToken, Function: function}
+	expr.Arguments = p.parseExpressionList(token.R_BRACKET)
+	return expr
+}
+
+func (p *Parser) parseExpressionList(end token.Kind) []ast.Expr {
+	list := []ast.Expr{}
+	if p.checkPeek(end) {
+		p.nextToken(false)
+		return list
+	}
+	p.nextToken(false)
+	list = append(list, p.parseExpression(LOWEST))
+	for p.checkPeek(token.COMMA) {
+		p.nextToken(false)
+		p.nextToken(false)
+		list = append(list, p.parseExpression(LOWEST))
+	}
+	p.expectedPeek(end)
+
+	return list
+}
+
 func (p *Parser) parseIfExpr() ast.Expr {
 	ifExpr := &ast.IfExpression{Token: p.currentToken}
 	p.nextToken(false)
@@ -350,12 +554,17 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block.Statements = []ast.Stmt{}
 	p.nextToken(true)
 	for !p.check(token.R_BRACE) && !p.check(token.EOF) {
-		stmt := p.parseStatement()
+		stmt := p.parseStatementOrSync()
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
+		} else if p.check(token.R_BRACE) {
+			break
 		}
 		p.nextToken(true)
 	}
+	if !p.check(token.R_BRACE) {
+		p.abort(fmt.Sprintf(expectedError, token.R_BRACE.Name(), p.currentToken.Kind.Name()))
+	}
 
 	return block
 }
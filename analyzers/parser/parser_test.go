@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"github.com/wevertonbruno/wb-compiler/analyzers/diagnostic"
 	"github.com/wevertonbruno/wb-compiler/analyzers/lexer"
 	"github.com/wevertonbruno/wb-compiler/analyzers/reader"
 	"github.com/wevertonbruno/wb-compiler/ast"
@@ -39,7 +40,8 @@ func TestParsingInfixExpressions(t *testing.T) {
 		r := reader.NewInput(tt.input)
 		l := lexer.NewLexer(r)
 		p := NewParser(l)
-		program := p.Parse()
+		program, errs := p.Parse()
+		checkParserErrors(t, errs)
 		if len(program.Statements) != 1 {
 			t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
 				1, len(program.Statements))
@@ -79,7 +81,8 @@ func TestParsingPrefixExpressions(t *testing.T) {
 		r := reader.NewInput(tt.input)
 		l := lexer.NewLexer(r)
 		p := NewParser(l)
-		program := p.Parse()
+		program, errs := p.Parse()
+		checkParserErrors(t, errs)
 		if len(program.Statements) != 1 {
 			t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
 				1, len(program.Statements))
@@ -132,12 +135,22 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		{"2 / (5 + 5)", "(2 / (5 + 5))"},
 		{"-(5 + 5)", "(-(5 + 5))"},
 		{"!(true == true)", "(!(true == true))"},
+		{"a || b && c", "(a || (b && c))"},
+		{"a && b || c && d", "((a && b) || (c && d))"},
+		{"a || b || c", "((a || b) || c)"},
+		{"a == b && !c", "((a == b) && (!c))"},
+		{"a < b || c + 1 != d", "((a < b) || ((c + 1) != d))"},
+		{"a + add(b * c) + d", "((a + add((b * c))) + d)"},
+		{"add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))", "add(a, b, 1, (2 * 3), (4 + 5), add(6, (7 * 8)))"},
+		{"add(a + b + c * d / f + g)", "add((((a + b) + ((c * d) / f)) + g))"},
+		{"print(-a)", "print((-a))"},
 	}
 	for _, tt := range tests {
 		r := reader.NewInput(tt.input)
 		l := lexer.NewLexer(r)
 		p := NewParser(l)
-		program := p.Parse()
+		program, errs := p.Parse()
+		checkParserErrors(t, errs)
 		if actual := program.String(); actual != tt.expected {
 			t.Errorf("expected=%q, got=%q", tt.expected, actual)
 		}
@@ -165,7 +178,8 @@ func TestIfExpression(t *testing.T) {
 		r := reader.NewInput(in)
 		l := lexer.NewLexer(r)
 		p := NewParser(l)
-		program := p.Parse()
+		program, errs := p.Parse()
+		checkParserErrors(t, errs)
 		if len(program.Statements) != 1 {
 			t.Fatalf("program.Body does not contain %d statements. got=%d\n",
 				1, len(program.Statements))
@@ -202,6 +216,421 @@ func TestIfExpression(t *testing.T) {
 	}
 }
 
+func TestWhileStatement(t *testing.T) {
+	input := []string{
+		`while x < y {
+			x
+		}`,
+		`while (x < y) { x }`,
+		`while x < y {
+			if (x < y) {
+				x
+			}
+			y
+		}`,
+	}
+	for _, in := range input {
+		r := reader.NewInput(in)
+		l := lexer.NewLexer(r)
+		p := NewParser(l)
+		program, errs := p.Parse()
+		checkParserErrors(t, errs)
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+				1, len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.WhileStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.WhileStatement. got=%T",
+				program.Statements[0])
+		}
+		if !testInfixExpression(t, stmt.Condition, "x", "<", "y") {
+			return
+		}
+		if len(stmt.Body.Statements) == 0 {
+			t.Fatalf("stmt.Body is empty")
+		}
+		_, ok = stmt.Body.Statements[0].(*ast.ExprStatement)
+		if !ok {
+			t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T",
+				stmt.Body.Statements[0])
+		}
+	}
+}
+
+func TestCallExpression(t *testing.T) {
+	tests := []struct {
+		input     string
+		function  string
+		arguments []string
+	}{
+		{"add(1, 2 * 3, 4 + 5)", "add", []string{"1", "(2 * 3)", "(4 + 5)"}},
+		{"main()", "main", []string{}},
+		{"print(a)\n", "print", []string{"a"}},
+	}
+	for _, tt := range tests {
+		r := reader.NewInput(tt.input)
+		l := lexer.NewLexer(r)
+		p := NewParser(l)
+		program, errs := p.Parse()
+		checkParserErrors(t, errs)
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+				1, len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.ExprStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+				program.Statements[0])
+		}
+		exp, ok := stmt.Expr.(*ast.CallExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T", stmt.Expr)
+		}
+		if !testIdentifier(t, exp.Function, tt.function) {
+			return
+		}
+		if len(exp.Arguments) != len(tt.arguments) {
+			t.Fatalf("wrong number of arguments. want=%d, got=%d",
+				len(tt.arguments), len(exp.Arguments))
+		}
+		for i, arg := range tt.arguments {
+			if exp.Arguments[i].String() != arg {
+				t.Errorf("argument %d wrong. want=%q, got=%q", i, arg, exp.Arguments[i].String())
+			}
+		}
+	}
+}
+
+func TestVarStatement(t *testing.T) {
+	tests := []struct {
+		input      string
+		identifier string
+		typeName   string
+		value      interface{}
+	}{
+		{"var a : Integer = 5\n", "a", "Integer", 5},
+		{"var b : Decimal = 2.5;", "b", "Decimal", 2.5},
+		{"var c : Boolean = true", "c", "Boolean", true},
+		{"var d : Integer = e", "d", "Integer", "e"},
+	}
+	for _, tt := range tests {
+		r := reader.NewInput(tt.input)
+		l := lexer.NewLexer(r)
+		p := NewParser(l)
+		program, errs := p.Parse()
+		checkParserErrors(t, errs)
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+				1, len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.DeclStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.DeclStatement. got=%T",
+				program.Statements[0])
+		}
+		if !testIdentifier(t, stmt.ID, tt.identifier) {
+			return
+		}
+		if stmt.Type.Spelling != tt.typeName {
+			t.Fatalf("stmt.Type is not '%s'. got=%s", tt.typeName, stmt.Type.Spelling)
+		}
+		if !testLiteral(t, stmt.Value, tt.value) {
+			return
+		}
+	}
+}
+
+func TestAssignStatement(t *testing.T) {
+	tests := []struct {
+		input      string
+		identifier string
+		value      string
+	}{
+		{"a = 5\n", "a", "5"},
+		{"b = b + 1;", "b", "(b + 1)"},
+		{"c = !(d == e)", "c", "(!(d == e))"},
+		{"f = g(1) * 2", "f", "(g(1) * 2)"},
+	}
+	for _, tt := range tests {
+		r := reader.NewInput(tt.input)
+		l := lexer.NewLexer(r)
+		p := NewParser(l)
+		program, errs := p.Parse()
+		checkParserErrors(t, errs)
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+				1, len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.AssignStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.AssignStatement. got=%T",
+				program.Statements[0])
+		}
+		if !testIdentifier(t, stmt.Target, tt.identifier) {
+			return
+		}
+		if stmt.Value.String() != tt.value {
+			t.Fatalf("stmt.Value is not %q. got=%q", tt.value, stmt.Value.String())
+		}
+	}
+
+	// a comparison is still an expression
+	program, errs := NewParser(lexer.NewLexer(reader.NewInput("a == 1"))).Parse()
+	checkParserErrors(t, errs)
+	if _, ok := program.Statements[0].(*ast.ExprStatement); !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExprStatement. got=%T", program.Statements[0])
+	}
+}
+
+func TestStringAndCharLiterals(t *testing.T) {
+	input := `var s : String = "hello world"
+	s == ""
+	'a' < 'b'
+	`
+	program, errs := NewParser(lexer.NewLexer(reader.NewInput(input))).Parse()
+	checkParserErrors(t, errs)
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			3, len(program.Statements))
+	}
+
+	decl := program.Statements[0].(*ast.DeclStatement)
+	str, ok := decl.Value.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("decl.Value is not ast.StringLiteral. got=%T", decl.Value)
+	}
+	if str.Value != "hello world" {
+		t.Errorf("str.Value is not %q. got=%q", "hello world", str.Value)
+	}
+
+	comparison := program.Statements[1].(*ast.ExprStatement).Expr.(*ast.InfixExpression)
+	if empty, ok := comparison.Right.(*ast.StringLiteral); !ok || empty.Value != "" {
+		t.Errorf("comparison.Right is not an empty ast.StringLiteral. got=%s", comparison.Right)
+	}
+
+	order := program.Statements[2].(*ast.ExprStatement).Expr.(*ast.InfixExpression)
+	for i, expected := range []byte{'a', 'b'} {
+		char, ok := []ast.Expr{order.Left, order.Right}[i].(*ast.CharLiteral)
+		if !ok || char.Value != expected {
+			t.Errorf("operand %d is not the ast.CharLiteral %q. got=%v", i, expected, char)
+		}
+	}
+	if got := order.String(); got != "('a' < 'b')" {
+		t.Errorf("order.String() is not %q. got=%q", "('a' < 'b')", got)
+	}
+
+	escapes := []struct {
+		input    string
+		expected string
+	}{
+		{`"tab\tnew line\nquote\"backslash\\"`, "tab\tnew line\nquote\"backslash\\"},
+		{`"caf\u{E9} \u{1F600}"`, "café 😀"},
+		{"`raw \\n\n\"text\"`", "raw \\n\n\"text\""},
+	}
+	for _, tt := range escapes {
+		program, errs := NewParser(lexer.NewLexer(reader.NewInput(tt.input))).Parse()
+		checkParserErrors(t, errs)
+		str, ok := program.Statements[0].(*ast.ExprStatement).Expr.(*ast.StringLiteral)
+		if !ok || str.Value != tt.expected {
+			t.Errorf("wrong literal for %s. want=%q, got=%v", tt.input, tt.expected, program.Statements[0])
+		}
+	}
+}
+
+func TestInterpolatedString(t *testing.T) {
+	input := `"count: ${a + 1} of ${f("${b}")}\${c}"`
+	program, errs := NewParser(lexer.NewLexer(reader.NewInput(input))).Parse()
+	checkParserErrors(t, errs)
+
+	str, ok := program.Statements[0].(*ast.ExprStatement).Expr.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("expression is not ast.InterpolatedString. got=%T", program.Statements[0].(*ast.ExprStatement).Expr)
+	}
+	expected := []string{`"count: "`, "(a + 1)", `" of "`, `f("${b}")`, `"${c}"`}
+	if len(str.Parts) != len(expected) {
+		t.Fatalf("str.Parts does not contain %d parts. got=%d", len(expected), len(str.Parts))
+	}
+	for i, part := range str.Parts {
+		if part.String() != expected[i] {
+			t.Errorf("part %d is not %s. got=%s", i, expected[i], part)
+		}
+	}
+	if got := str.String(); got != `"count: ${(a + 1)} of ${f("${b}")}\${c}"` {
+		t.Errorf("str.String() wrong. got=%s", got)
+	}
+
+	// an unterminated interpolation is reported once, by the lexer
+	errors := []struct {
+		input    string
+		expected []string
+	}{
+		{`"${}"`, []string{"parser error. empty interpolation"}},
+		{`"${a b}"`, []string{"parser error. expected }, got <identifier>"}},
+		{"\"${a\nb", []string{"lexical error. Unterminated string literal"}},
+		{"\"${ {a} }\"", []string{"parser error. no prefix parse function for { found"}},
+		{`"x${1 + }"`, []string{"parser error. no prefix parse function for <string end> found"}},
+		{`"x${1 + }y${2}"`, []string{"parser error. no prefix parse function for <string middle> found"}},
+	}
+	for _, tt := range errors {
+		_, errs := NewParser(lexer.NewLexer(reader.NewInput(tt.input))).Parse()
+		if len(errs) != len(tt.expected) {
+			t.Errorf("wrong number of errors for %q. want=%d, got=%d: %v", tt.input, len(tt.expected), len(errs), errs)
+			continue
+		}
+		for i, e := range tt.expected {
+			if errs[i].Message != e {
+				t.Errorf("errs[%d] wrong for %q. want=%q, got=%q", i, tt.input, e, errs[i].Message)
+			}
+		}
+	}
+}
+
+func TestFunctionDeclaration(t *testing.T) {
+	input := `func add(x : Integer, y : Integer) : Integer {
+		var z : Integer = x + y
+		return z
+	}
+
+	func main() {
+		return
+	}`
+	r := reader.NewInput(input)
+	l := lexer.NewLexer(r)
+	p := NewParser(l)
+	program, errs := p.Parse()
+	checkParserErrors(t, errs)
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			2, len(program.Statements))
+	}
+	fn, ok := program.Statements[0].(*ast.FuncDecl)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.FuncDecl. got=%T",
+			program.Statements[0])
+	}
+	if !testIdentifier(t, fn.Name, "add") {
+		return
+	}
+	if len(fn.Parameters) != 2 {
+		t.Fatalf("fn.Parameters does not contain 2 parameters. got=%d", len(fn.Parameters))
+	}
+	for i, name := range []string{"x", "y"} {
+		if !testIdentifier(t, fn.Parameters[i].ID, name) {
+			return
+		}
+		if fn.Parameters[i].Type.Spelling != "Integer" {
+			t.Fatalf("fn.Parameters[%d].Type is not Integer. got=%s", i, fn.Parameters[i].Type.Spelling)
+		}
+	}
+	if !fn.HasReturnType() || fn.ReturnType.Spelling != "Integer" {
+		t.Fatalf("fn.ReturnType is not Integer. got=%q", fn.ReturnType.Spelling)
+	}
+	if len(fn.Body.Statements) != 2 {
+		t.Fatalf("fn.Body does not contain 2 statements. got=%d", len(fn.Body.Statements))
+	}
+	ret, ok := fn.Body.Statements[1].(*ast.ReturnStatement)
+	if !ok {
+		t.Fatalf("fn.Body.Statements[1] is not ast.ReturnStatement. got=%T", fn.Body.Statements[1])
+	}
+	if !testIdentifier(t, ret.Expr, "z") {
+		return
+	}
+
+	main, ok := program.Statements[1].(*ast.FuncDecl)
+	if !ok {
+		t.Fatalf("program.Statements[1] is not ast.FuncDecl. got=%T",
+			program.Statements[1])
+	}
+	if len(main.Parameters) != 0 || main.HasReturnType() {
+		t.Fatalf("main should have no parameters nor return type. got=%s", main.String())
+	}
+	if len(main.Body.Statements) != 1 {
+		t.Fatalf("main.Body does not contain 1 statement. got=%d", len(main.Body.Statements))
+	}
+	if ret, ok := main.Body.Statements[0].(*ast.ReturnStatement); !ok || ret.Expr != nil {
+		t.Fatalf("main.Body.Statements[0] is not an empty return. got=%s", main.Body.Statements[0])
+	}
+}
+
+func TestParserErrors(t *testing.T) {
+	input := `var a : = 5
+var b : Integer = 3
+var c Integer = 4
+foo(1, 2
+if (x < ) { y }
+print(b $)
+b`
+	r := reader.NewInput(input)
+	l := lexer.NewLexer(r)
+	p := NewParser(l)
+	program, errs := p.Parse()
+	expected := []string{
+		"parser error. expected type, got =",
+		"parser error. expected :, got Integer",
+		"parser error. expected ), got <new line>",
+		"parser error. no prefix parse function for ) found",
+		"lexical error. Unknown token: $",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("wrong number of errors. want=%d, got=%d: %v", len(expected), len(errs), errs)
+	}
+	for i, e := range expected {
+		if errs[i].Message != e {
+			t.Errorf("errs[%d] wrong. want=%q, got=%q", i, e, errs[i].Message)
+		}
+	}
+	if pos := errs[4].Position; pos.Line != 6 || pos.Column != 9 {
+		t.Errorf("errs[4] has wrong position. want=6:9, got=%d:%d", pos.Line, pos.Column)
+	}
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d: %s\n",
+			3, len(program.Statements), program)
+	}
+}
+
+func TestUnterminatedBlock(t *testing.T) {
+	r := reader.NewInput("func main() {\n  x\n")
+	l := lexer.NewLexer(r)
+	p := NewParser(l)
+	_, errs := p.Parse()
+	if len(errs) != 1 {
+		t.Fatalf("wrong number of errors. want=1, got=%d: %v", len(errs), errs)
+	}
+}
+
+func TestUnterminatedLiterals(t *testing.T) {
+	// the lexer reports the literal and still returns it to the parser
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"var r : String = \"abc\nprint(r)", "lexical error. Unterminated string literal"},
+		{"var r : String = \"a${\"b${1}c\nprint(r)", "lexical error. Unterminated string literal"},
+		{"var r : String = `abc", "lexical error. Unterminated raw string literal"},
+		{"var c : Char = 'a\nprint(c)", "lexical error. Unterminated char literal"},
+		{"var c : Char = '", "lexical error. Unterminated char literal"},
+		{"var c : Char = ''", "lexical error. Char literal must hold a single character: ''"},
+	}
+	for _, tt := range tests {
+		_, errs := NewParser(lexer.NewLexer(reader.NewInput(tt.input))).Parse()
+		if len(errs) != 1 || errs[0].Message != tt.expected {
+			t.Errorf("wrong errors for %q. want=%q, got=%v", tt.input, tt.expected, errs)
+		}
+	}
+}
+
+func checkParserErrors(t *testing.T, errs []diagnostic.Diagnostic) {
+	if len(errs) == 0 {
+		return
+	}
+	t.Errorf("parser has %d errors", len(errs))
+	for _, e := range errs {
+		t.Errorf("parser error: %s", e)
+	}
+	t.FailNow()
+}
+
 func testLiteral(t *testing.T, il ast.Expr, value interface{}) bool {
 	switch v := value.(type) {
 	case bool:
@@ -0,0 +1,360 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/wevertonbruno/wb-compiler/analyzers/diagnostic"
+	"github.com/wevertonbruno/wb-compiler/analyzers/token"
+	"github.com/wevertonbruno/wb-compiler/ast"
+	"sort"
+)
+
+const (
+	typeError = "type error. %v"
+
+	mismatchedTypesError = "mismatched types %v and %v for %v"
+	invalidOperandError  = "operator %v not defined on %v"
+	declTypeError        = "cannot use %v as %v in declaration of %s"
+	assignTypeError      = "cannot use %v as %v in assignment to %s"
+	assignTargetError    = "cannot assign to %s, it is a %v"
+	conditionTypeError   = "condition must be Boolean, got %v"
+	returnTypeError      = "cannot use %v as %v in return of %s"
+	missingValueError    = "missing return value in %s, expected %v"
+	unexpectedValueError = "too many return values in %s, it has no return type"
+	missingReturnError   = "missing return at the end of %s"
+	notFunctionError     = "%s is not a function"
+	funcAsValueError     = "function %s used as value"
+	argumentCountError   = "wrong number of arguments in call to %s, want %d, got %d"
+	argumentTypeError    = "cannot use %v as %v in argument %d of %s"
+	noValueError         = "%s has no value"
+)
+
+type checker struct {
+	info     *Info
+	function *ast.FuncDecl
+	errors   []diagnostic.Diagnostic
+}
+
+// Check resolves the identifiers of the program and type checks it, filling
+// Info.Types with the type of every expression.
+func Check(prog *ast.Prog) (*Info, []diagnostic.Diagnostic) {
+	info, errs := Analyze(prog)
+	c := &checker{info: info}
+	c.checkStatements(prog.Statements)
+	errs = append(errs, c.errors...)
+	sort.SliceStable(errs, func(i, j int) bool {
+		pi, pj := errs[i].Position, errs[j].Position
+		return pi.Line < pj.Line || pi.Line == pj.Line && pi.Column < pj.Column
+	})
+	return info, errs
+}
+
+// TypeOf returns the type recorded for the expression, or Invalid if the
+// expression was never checked.
+func (i *Info) TypeOf(expr ast.Expr) Type {
+	return i.Types[expr]
+}
+
+// Terminates reports whether a list of statements always ends in a return,
+// either directly or through an if/else whose branches both return.
+func Terminates(stmts []ast.Stmt) bool {
+	if len(stmts) == 0 {
+		return false
+	}
+	switch node := stmts[len(stmts)-1].(type) {
+	case *ast.ReturnStatement:
+		return true
+	case *ast.BlockStatement:
+		return Terminates(node.Statements)
+	case *ast.ExprStatement:
+		if ifExpr, ok := node.Expr.(*ast.IfExpression); ok && ifExpr.FalseBlockCondition != nil {
+			return Terminates(ifExpr.TrueBlockCondition.Statements) &&
+				Terminates(ifExpr.FalseBlockCondition.Statements)
+		}
+	}
+	return false
+}
+
+func (c *checker) error(tok token.Token, message string) {
+	c.errors = append(c.errors, diagnostic.New(fmt.Sprintf(typeError, message), tok))
+}
+
+func (c *checker) checkStatements(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		c.checkStatement(stmt)
+	}
+}
+
+func (c *checker) checkStatement(stmt ast.Stmt) {
+	switch node := stmt.(type) {
+	case *ast.FuncDecl:
+		if c.function == nil {
+			c.checkFuncDecl(node)
+		}
+	case *ast.DeclStatement:
+		declared := TypeOf(node.Type)
+		if t := c.checkValue(node.Value); t != Invalid && t != declared {
+			c.error(StartToken(node.Value), fmt.Sprintf(declTypeError, t, declared, node.ID.Value))
+		}
+	case *ast.AssignStatement:
+		c.checkAssign(node)
+	case *ast.ReturnStatement:
+		c.checkReturn(node)
+	case *ast.ExprStatement:
+		c.checkExpression(node.Expr)
+	case *ast.WhileStatement:
+		c.checkCondition(node.Condition)
+		c.checkStatements(node.Body.Statements)
+	case *ast.BlockStatement:
+		c.checkStatements(node.Statements)
+	}
+}
+
+func (c *checker) checkFuncDecl(fn *ast.FuncDecl) {
+	c.function = fn
+	c.checkStatements(fn.Body.Statements)
+	if fn.HasReturnType() && !Terminates(fn.Body.Statements) {
+		c.error(fn.Body.Token, fmt.Sprintf(missingReturnError, fn.Name.Value))
+	}
+	c.function = nil
+}
+
+// checkAssign checks that the target of an assignment is a variable or a
+// parameter of the type of the value.
+func (c *checker) checkAssign(assign *ast.AssignStatement) {
+	t := c.checkValue(assign.Value)
+	sym, ok := c.info.Uses[assign.Target]
+	if !ok {
+		return
+	}
+	if sym.Kind != VARIABLE && sym.Kind != PARAMETER {
+		c.error(assign.Target.Token, fmt.Sprintf(assignTargetError, assign.Target.Value, sym.Kind))
+		return
+	}
+	if target := TypeOf(sym.Type); t != Invalid && t != target {
+		c.error(StartToken(assign.Value), fmt.Sprintf(assignTypeError, t, target, assign.Target.Value))
+	}
+}
+
+func (c *checker) checkReturn(ret *ast.ReturnStatement) {
+	if c.function == nil {
+		if ret.Expr != nil {
+			c.checkExpression(ret.Expr)
+		}
+		return
+	}
+	name := c.function.Name.Value
+	expected := TypeOf(c.function.ReturnType)
+	switch {
+	case ret.Expr == nil && expected != Void:
+		c.error(ret.Token, fmt.Sprintf(missingValueError, name, expected))
+	case ret.Expr != nil && expected == Void:
+		c.checkExpression(ret.Expr)
+		c.error(StartToken(ret.Expr), fmt.Sprintf(unexpectedValueError, name))
+	case ret.Expr != nil:
+		if t := c.checkValue(ret.Expr); t != Invalid && t != expected {
+			c.error(StartToken(ret.Expr), fmt.Sprintf(returnTypeError, t, expected, name))
+		}
+	}
+}
+
+func (c *checker) checkCondition(cond ast.Expr) {
+	if t := c.checkValue(cond); t != Invalid && t != Boolean {
+		c.error(StartToken(cond), fmt.Sprintf(conditionTypeError, t))
+	}
+}
+
+// checkValue checks an expression whose value is going to be used.
+func (c *checker) checkValue(expr ast.Expr) Type {
+	t := c.checkExpression(expr)
+	if t == Void {
+		c.error(StartToken(expr), fmt.Sprintf(noValueError, expr.String()))
+		return Invalid
+	}
+	return t
+}
+
+func (c *checker) checkExpression(expr ast.Expr) Type {
+	t := c.typeOf(expr)
+	c.info.Types[expr] = t
+	return t
+}
+
+func (c *checker) typeOf(expr ast.Expr) Type {
+	switch node := expr.(type) {
+	case *ast.IntegerLiteral:
+		return Integer
+	case *ast.DecimalLiteral:
+		return Decimal
+	case *ast.Boolean:
+		return Boolean
+	case *ast.StringLiteral:
+		return String
+	case *ast.CharLiteral:
+		return Char
+	case *ast.InterpolatedString:
+		// every value interpolated is converted to String
+		for _, part := range node.Parts {
+			c.checkValue(part)
+		}
+		return String
+	case *ast.Identifier:
+		return c.checkIdentifier(node)
+	case *ast.PrefixExpression:
+		return c.checkPrefix(node)
+	case *ast.InfixExpression:
+		return c.checkInfix(node)
+	case *ast.CallExpression:
+		return c.checkCall(node)
+	case *ast.IfExpression:
+		c.checkCondition(node.Condition)
+		c.checkStatements(node.TrueBlockCondition.Statements)
+		if node.FalseBlockCondition != nil {
+			c.checkStatements(node.FalseBlockCondition.Statements)
+		}
+		return Void
+	}
+	return Invalid
+}
+
+func (c *checker) checkIdentifier(id *ast.Identifier) Type {
+	sym, ok := c.info.Uses[id]
+	if !ok {
+		return Invalid
+	}
+	if sym.Kind == FUNCTION || sym.Kind == BUILTIN {
+		c.error(id.Token, fmt.Sprintf(funcAsValueError, id.Value))
+		return Invalid
+	}
+	return TypeOf(sym.Type)
+}
+
+func (c *checker) checkPrefix(expr *ast.PrefixExpression) Type {
+	right := c.checkValue(expr.Right)
+	if right == Invalid {
+		return Invalid
+	}
+	switch expr.Token.Kind {
+	case token.OP_MINUS:
+		if right.IsNumeric() {
+			return right
+		}
+	case token.NOT:
+		if right == Boolean {
+			return Boolean
+		}
+	}
+	c.error(expr.Token, fmt.Sprintf(invalidOperandError, expr.Token.Spelling, right))
+	return Invalid
+}
+
+func (c *checker) checkInfix(expr *ast.InfixExpression) Type {
+	left := c.checkValue(expr.Left)
+	right := c.checkValue(expr.Right)
+	if left == Invalid || right == Invalid {
+		return Invalid
+	}
+	if left != right {
+		c.error(expr.Token, fmt.Sprintf(mismatchedTypesError, left, right, expr.Token.Spelling))
+		return Invalid
+	}
+	switch expr.Token.Kind {
+	case token.OP_PLUS, token.OP_MINUS, token.OP_MULTI, token.OP_DIVIDE:
+		if left.IsNumeric() {
+			return left
+		}
+	case token.OP_LT, token.OP_LTE, token.OP_GT, token.OP_GTE:
+		if left.IsOrdered() {
+			return Boolean
+		}
+	case token.OP_EQ, token.OP_NOTEQ:
+		return Boolean
+	case token.AND, token.OR:
+		if left == Boolean {
+			return Boolean
+		}
+	}
+	c.error(expr.Token, fmt.Sprintf(invalidOperandError, expr.Token.Spelling, left))
+	return Invalid
+}
+
+func (c *checker) checkCall(call *ast.CallExpression) Type {
+	id, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		c.checkExpression(call.Function)
+		c.checkArguments(call)
+		c.error(call.Token, fmt.Sprintf(notFunctionError, call.Function.String()))
+		return Invalid
+	}
+	sym, ok := c.info.Uses[id]
+	if !ok {
+		c.checkArguments(call)
+		return Invalid
+	}
+	switch sym.Kind {
+	case BUILTIN:
+		c.info.Types[id] = Void
+		args := c.checkArguments(call)
+		if len(args) != 1 {
+			c.error(call.Token, fmt.Sprintf(argumentCountError, id.Value, 1, len(args)))
+		}
+		return Void
+	case FUNCTION:
+		returnType := TypeOf(sym.Type)
+		c.info.Types[id] = returnType
+		args := c.checkArguments(call)
+		params := sym.Func.Parameters
+		if len(args) != len(params) {
+			c.error(call.Token, fmt.Sprintf(argumentCountError, id.Value, len(params), len(args)))
+			return returnType
+		}
+		for i, param := range params {
+			expected := TypeOf(param.Type)
+			if args[i] != Invalid && args[i] != expected {
+				c.error(StartToken(call.Arguments[i]),
+					fmt.Sprintf(argumentTypeError, args[i], expected, i+1, id.Value))
+			}
+		}
+		return returnType
+	}
+	c.checkArguments(call)
+	c.error(id.Token, fmt.Sprintf(notFunctionError, id.Value))
+	return Invalid
+}
+
+func (c *checker) checkArguments(call *ast.CallExpression) []Type {
+	types := make([]Type, len(call.Arguments))
+	for i, arg := range call.Arguments {
+		types[i] = c.checkValue(arg)
+	}
+	return types
+}
+
+// StartToken returns the leftmost token of an expression, which is where
+// diagnostics about the whole expression are reported.
+func StartToken(expr ast.Expr) token.Token {
+	switch node := expr.(type) {
+	case *ast.InfixExpression:
+		return StartToken(node.Left)
+	case *ast.CallExpression:
+		return StartToken(node.Function)
+	case *ast.Identifier:
+		return node.Token
+	case *ast.PrefixExpression:
+		return node.Token
+	case *ast.IfExpression:
+		return node.Token
+	case *ast.IntegerLiteral:
+		return node.Token
+	case *ast.DecimalLiteral:
+		return node.Token
+	case *ast.Boolean:
+		return node.Token
+	case *ast.StringLiteral:
+		return node.Token
+	case *ast.CharLiteral:
+		return node.Token
+	case *ast.InterpolatedString:
+		return node.Token
+	}
+	return token.Token{}
+}
@@ -0,0 +1,130 @@
+package semantic
+
+import (
+	"github.com/wevertonbruno/wb-compiler/ast"
+	"testing"
+)
+
+func TestTypeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"var a : Integer = 1 + 2 * 3", []string{}},
+		{"var a : Decimal = 1.5 / 2.0", []string{}},
+		{"var a : Boolean = 1 < 2 == !false", []string{}},
+		{"var b : Char = 10", []string{"type error. cannot use Integer as Char in declaration of b"}},
+		{"true + 1.5", []string{"type error. mismatched types Boolean and Decimal for +"}},
+		{"true + false", []string{"type error. operator + not defined on Boolean"}},
+		{"1 + 2.5", []string{"type error. mismatched types Integer and Decimal for +"}},
+		{"-true", []string{"type error. operator - not defined on Boolean"}},
+		{"!1", []string{"type error. operator ! not defined on Integer"}},
+		{"true < false", []string{"type error. operator < not defined on Boolean"}},
+		{"(1 + true) * 2", []string{"type error. mismatched types Integer and Boolean for +"}},
+		{"1 && true", []string{"type error. mismatched types Integer and Boolean for &&"}},
+		{"1 || 2", []string{"type error. operator || not defined on Integer"}},
+		{"true && 1 < 2 || !false", nil},
+		{"while 1 { }", []string{"type error. condition must be Boolean, got Integer"}},
+		{"if (1.5) { }", []string{"type error. condition must be Boolean, got Decimal"}},
+		{`func f(x : Integer) : Integer {
+			return x > 1
+		}`, []string{"type error. cannot use Boolean as Integer in return of f"}},
+		{`func f() : Integer {
+			return
+		}`, []string{"type error. missing return value in f, expected Integer"}},
+		{`func f() {
+			return 1
+		}`, []string{"type error. too many return values in f, it has no return type"}},
+		{`func f(x : Integer) : Integer {
+			if (x > 0) {
+				return x
+			}
+		}`, []string{"type error. missing return at the end of f"}},
+		{`func f(x : Integer) : Integer {
+			if (x > 0) {
+				return x
+			} else {
+				return -x
+			}
+		}`, []string{}},
+		{`func f(x : Integer, y : Decimal) : Decimal {
+			return y
+		}
+		var a : Decimal = f(1.5, 2)`, []string{
+			"type error. cannot use Decimal as Integer in argument 1 of f",
+			"type error. cannot use Integer as Decimal in argument 2 of f",
+		}},
+		{`func f(x : Integer) {
+		}
+		f()
+		var a : Integer = f(1)`, []string{
+			"type error. wrong number of arguments in call to f, want 1, got 0",
+			"type error. f(1) has no value",
+		}},
+		{"var a : Integer = 1\na(2)", []string{"type error. a is not a function"}},
+		{"func f() {\n}\nvar a : Integer = f + 1", []string{"type error. function f used as value"}},
+		{"print(1, 2)", []string{"type error. wrong number of arguments in call to print, want 1, got 2"}},
+		{"var a : Integer = print(1)", []string{"type error. print(1) has no value"}},
+		{"var a : Integer = b + 1", []string{"semantic error. undeclared identifier b"}},
+		{"var a : Integer = 1\na = a + 1", []string{}},
+		{"func f(x : Decimal) {\n x = x * 2.0\n}", []string{}},
+		{"var a : Integer = 1\na = 2.5", []string{"type error. cannot use Decimal as Integer in assignment to a"}},
+		{"var a : Boolean = true\na = print(1)", []string{"type error. print(1) has no value"}},
+		{"func f() {\n}\nf = 1", []string{"type error. cannot assign to f, it is a function"}},
+		{`var s : String = "hi"` + "\nvar b : Boolean = s == \"\" != ('a' <= 'b')", []string{}},
+		{`var s : String = 'a'`, []string{"type error. cannot use Char as String in declaration of s"}},
+		{`var c : Char = "a"`, []string{"type error. cannot use String as Char in declaration of c"}},
+		{`"a" < "b"`, []string{"type error. operator < not defined on String"}},
+		{`"a" + "b"`, []string{"type error. operator + not defined on String"}},
+		{`'a' == "a"`, []string{"type error. mismatched types Char and String for =="}},
+		{"var n : Integer = 1\nvar s : String = \"${n} ${1.5} ${'c'} ${n > 0} ${\"${n}\"}\"", []string{}},
+		{"func f() {\n}\nvar s : String = \"${f()}\"", []string{"type error. f() has no value"}},
+		{`var n : Integer = "${1}"`, []string{"type error. cannot use String as Integer in declaration of n"}},
+	}
+	for _, tt := range tests {
+		_, errs := Check(parse(t, tt.input))
+		if len(errs) != len(tt.expected) {
+			t.Errorf("wrong number of errors for %q. want=%d, got=%d: %v",
+				tt.input, len(tt.expected), len(errs), errs)
+			continue
+		}
+		for i, e := range tt.expected {
+			if errs[i].Message != e {
+				t.Errorf("errs[%d] wrong. want=%q, got=%q", i, e, errs[i].Message)
+			}
+		}
+	}
+}
+
+func TestExpressionTypes(t *testing.T) {
+	input := `func half(x : Decimal) : Decimal {
+		return x / 2.0
+	}
+	var a : Integer = 1
+	var b : Boolean = -a < 3
+	print(half(1.0))`
+	prog := parse(t, input)
+	info, errs := Check(prog)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	b := prog.Statements[2].(*ast.DeclStatement).Value.(*ast.InfixExpression)
+	call := prog.Statements[3].(*ast.ExprStatement).Expr.(*ast.CallExpression)
+	tests := []struct {
+		expr     ast.Expr
+		expected Type
+	}{
+		{b, Boolean},
+		{b.Left, Integer},
+		{b.Left.(*ast.PrefixExpression).Right, Integer},
+		{b.Right, Integer},
+		{call, Void},
+		{call.Arguments[0], Decimal},
+		{call.Arguments[0].(*ast.CallExpression).Arguments[0], Decimal},
+	}
+	for _, tt := range tests {
+		if actual := info.TypeOf(tt.expr); actual != tt.expected {
+			t.Errorf("wrong type for %s. want=%v, got=%v", tt.expr, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package semantic
+
+import (
+	"github.com/wevertonbruno/wb-compiler/analyzers/token"
+	"github.com/wevertonbruno/wb-compiler/ast"
+)
+
+type SymbolKind byte
+
+const (
+	VARIABLE SymbolKind = iota
+	PARAMETER
+	FUNCTION
+	BUILTIN
+)
+
+var (
+	kindNames = map[SymbolKind]string{
+		VARIABLE:  "variable",
+		PARAMETER: "parameter",
+		FUNCTION:  "function",
+		BUILTIN:   "builtin",
+	}
+)
+
+func (k SymbolKind) String() string {
+	return kindNames[k]
+}
+
+type Symbol struct {
+	Name string
+	Kind SymbolKind
+	// Type is the declared type of a variable or parameter, or the
+	// return type of a function.
+	Type  token.Token
+	Decl  *ast.Identifier
+	Func  *ast.FuncDecl
+	Scope *Scope
+}
+
+type Scope struct {
+	parent  *Scope
+	symbols map[string]*Symbol
+	names   []string
+}
+
+func NewScope(parent *Scope) *Scope {
+	return &Scope{
+		parent:  parent,
+		symbols: make(map[string]*Symbol),
+	}
+}
+
+func (s *Scope) Parent() *Scope {
+	return s.parent
+}
+
+// Define adds the symbol to this scope. It returns the previous symbol and
+// false if the name is already declared in the very same scope.
+func (s *Scope) Define(sym *Symbol) (*Symbol, bool) {
+	if prev, exists := s.symbols[sym.Name]; exists {
+		return prev, false
+	}
+	sym.Scope = s
+	s.symbols[sym.Name] = sym
+	s.names = append(s.names, sym.Name)
+	return sym, true
+}
+
+func (s *Scope) Lookup(name string) (*Symbol, bool) {
+	sym, ok := s.symbols[name]
+	return sym, ok
+}
+
+func (s *Scope) Resolve(name string) (*Symbol, bool) {
+	for scope := s; scope != nil; scope = scope.parent {
+		if sym, ok := scope.symbols[name]; ok {
+			return sym, true
+		}
+	}
+	return nil, false
+}
+
+// Symbols returns the symbols of this scope in declaration order.
+func (s *Scope) Symbols() []*Symbol {
+	symbols := make([]*Symbol, 0, len(s.names))
+	for _, name := range s.names {
+		symbols = append(symbols, s.symbols[name])
+	}
+	return symbols
+}
+
+func (s *Scope) IsGlobal() bool {
+	return s.parent != nil && s.parent.parent == nil
+}
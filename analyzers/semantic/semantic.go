@@ -0,0 +1,212 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/wevertonbruno/wb-compiler/analyzers/diagnostic"
+	"github.com/wevertonbruno/wb-compiler/analyzers/token"
+	"github.com/wevertonbruno/wb-compiler/ast"
+)
+
+const (
+	semanticError = "semantic error. %v"
+
+	undeclaredError = "undeclared identifier %s"
+	redeclaredError = "%s redeclared in this scope, previous declaration at %d:%d"
+	outOfScopeError = "%s is out of scope, it was declared at %d:%d"
+	nestedFuncError = "function %s must be declared at the top level"
+	returnOutOfFunc = "return statement outside of a function"
+
+	PrintBuiltin = "print"
+)
+
+type (
+	Info struct {
+		// Defs maps the identifier of every declaration to its symbol.
+		Defs map[*ast.Identifier]*Symbol
+		// Uses maps every identifier in an expression to the symbol it denotes.
+		Uses map[*ast.Identifier]*Symbol
+		// Scopes maps programs, blocks and functions to the scope they open.
+		Scopes map[ast.Node]*Scope
+		// Types maps every checked expression to its type.
+		Types map[ast.Expr]Type
+	}
+
+	Analyzer struct {
+		info     *Info
+		universe *Scope
+		scope    *Scope
+		function *ast.FuncDecl
+		// closed keeps symbols whose scope has already ended, so that a
+		// later use can be told apart from a plain undeclared identifier.
+		closed map[string]*Symbol
+		errors []diagnostic.Diagnostic
+	}
+)
+
+func NewAnalyzer() *Analyzer {
+	universe := NewScope(nil)
+	universe.Define(&Symbol{Name: PrintBuiltin, Kind: BUILTIN})
+	return &Analyzer{
+		info: &Info{
+			Defs:   make(map[*ast.Identifier]*Symbol),
+			Uses:   make(map[*ast.Identifier]*Symbol),
+			Scopes: make(map[ast.Node]*Scope),
+			Types:  make(map[ast.Expr]Type),
+		},
+		universe: universe,
+		scope:    universe,
+		closed:   make(map[string]*Symbol),
+	}
+}
+
+func Analyze(prog *ast.Prog) (*Info, []diagnostic.Diagnostic) {
+	a := NewAnalyzer()
+	a.Analyze(prog)
+	return a.info, a.errors
+}
+
+func (a *Analyzer) Analyze(prog *ast.Prog) {
+	a.openScope(prog)
+	defer a.closeScope()
+
+	// functions are visible from the whole program, so they are declared
+	// before any statement is visited
+	for _, stmt := range prog.Statements {
+		if fn, ok := stmt.(*ast.FuncDecl); ok {
+			a.declare(&Symbol{Name: fn.Name.Value, Kind: FUNCTION, Type: fn.ReturnType, Decl: fn.Name, Func: fn})
+		}
+	}
+	for _, stmt := range prog.Statements {
+		if fn, ok := stmt.(*ast.FuncDecl); ok {
+			a.visitFuncDecl(fn)
+			continue
+		}
+		a.visitStatement(stmt)
+	}
+}
+
+func (a *Analyzer) Info() *Info {
+	return a.info
+}
+
+func (a *Analyzer) Errors() []diagnostic.Diagnostic {
+	return a.errors
+}
+
+func (a *Analyzer) error(tok token.Token, message string) {
+	a.errors = append(a.errors, diagnostic.New(fmt.Sprintf(semanticError, message), tok))
+}
+
+func (a *Analyzer) openScope(node ast.Node) {
+	a.scope = NewScope(a.scope)
+	a.info.Scopes[node] = a.scope
+}
+
+func (a *Analyzer) closeScope() {
+	for _, sym := range a.scope.Symbols() {
+		a.closed[sym.Name] = sym
+	}
+	a.scope = a.scope.Parent()
+}
+
+func (a *Analyzer) declare(sym *Symbol) {
+	prev, ok := a.scope.Define(sym)
+	if !ok {
+		pos := prev.Decl.Token.Position
+		a.error(sym.Decl.Token, fmt.Sprintf(redeclaredError, sym.Name, pos.Line, pos.Column))
+		return
+	}
+	a.info.Defs[sym.Decl] = sym
+}
+
+func (a *Analyzer) resolve(id *ast.Identifier) {
+	if sym, ok := a.scope.Resolve(id.Value); ok {
+		a.info.Uses[id] = sym
+		return
+	}
+	if sym, ok := a.closed[id.Value]; ok {
+		pos := sym.Decl.Token.Position
+		a.error(id.Token, fmt.Sprintf(outOfScopeError, id.Value, pos.Line, pos.Column))
+		return
+	}
+	a.error(id.Token, fmt.Sprintf(undeclaredError, id.Value))
+}
+
+func (a *Analyzer) visitFuncDecl(fn *ast.FuncDecl) {
+	a.function = fn
+	a.openScope(fn)
+	for _, param := range fn.Parameters {
+		a.declare(&Symbol{Name: param.ID.Value, Kind: PARAMETER, Type: param.Type, Decl: param.ID})
+	}
+	// the body shares the scope of the parameters so that they cannot be
+	// redeclared as locals
+	a.visitStatements(fn.Body.Statements)
+	a.closeScope()
+	a.function = nil
+}
+
+func (a *Analyzer) visitBlock(block *ast.BlockStatement) {
+	a.openScope(block)
+	a.visitStatements(block.Statements)
+	a.closeScope()
+}
+
+func (a *Analyzer) visitStatements(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		a.visitStatement(stmt)
+	}
+}
+
+func (a *Analyzer) visitStatement(stmt ast.Stmt) {
+	switch node := stmt.(type) {
+	case *ast.DeclStatement:
+		a.visitExpression(node.Value)
+		a.declare(&Symbol{Name: node.ID.Value, Kind: VARIABLE, Type: node.Type, Decl: node.ID})
+	case *ast.AssignStatement:
+		a.visitExpression(node.Value)
+		a.resolve(node.Target)
+	case *ast.ReturnStatement:
+		if a.function == nil {
+			a.error(node.Token, returnOutOfFunc)
+		}
+		if node.Expr != nil {
+			a.visitExpression(node.Expr)
+		}
+	case *ast.ExprStatement:
+		a.visitExpression(node.Expr)
+	case *ast.WhileStatement:
+		a.visitExpression(node.Condition)
+		a.visitBlock(node.Body)
+	case *ast.BlockStatement:
+		a.visitBlock(node)
+	case *ast.FuncDecl:
+		a.error(node.Name.Token, fmt.Sprintf(nestedFuncError, node.Name.Value))
+	}
+}
+
+func (a *Analyzer) visitExpression(expr ast.Expr) {
+	switch node := expr.(type) {
+	case *ast.Identifier:
+		a.resolve(node)
+	case *ast.PrefixExpression:
+		a.visitExpression(node.Right)
+	case *ast.InfixExpression:
+		a.visitExpression(node.Left)
+		a.visitExpression(node.Right)
+	case *ast.InterpolatedString:
+		for _, part := range node.Parts {
+			a.visitExpression(part)
+		}
+	case *ast.CallExpression:
+		a.visitExpression(node.Function)
+		for _, arg := range node.Arguments {
+			a.visitExpression(arg)
+		}
+	case *ast.IfExpression:
+		a.visitExpression(node.Condition)
+		a.visitBlock(node.TrueBlockCondition)
+		if node.FalseBlockCondition != nil {
+			a.visitBlock(node.FalseBlockCondition)
+		}
+	}
+}
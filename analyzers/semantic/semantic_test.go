@@ -0,0 +1,116 @@
+package semantic
+
+import (
+	"github.com/wevertonbruno/wb-compiler/analyzers/diagnostic"
+	"github.com/wevertonbruno/wb-compiler/analyzers/lexer"
+	"github.com/wevertonbruno/wb-compiler/analyzers/parser"
+	"github.com/wevertonbruno/wb-compiler/analyzers/reader"
+	"github.com/wevertonbruno/wb-compiler/ast"
+	"testing"
+)
+
+func TestScopeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"var a : Integer = 1\nprint(a + 1)", []string{}},
+		{"var a : Integer = b + 1", []string{"semantic error. undeclared identifier b"}},
+		{"var a : Integer = 1\nvar a : Integer = 2",
+			[]string{"semantic error. a redeclared in this scope, previous declaration at 1:5"}},
+		{"var a : Integer = a", []string{"semantic error. undeclared identifier a"}},
+		{`var a : Integer = 1
+		while a < 10 {
+			var b : Integer = a
+			var a : Integer = b
+		}
+		print(b)`, []string{"semantic error. b is out of scope, it was declared at 3:8"}},
+		{`func f(x : Integer, x : Integer) : Integer {
+			var y : Integer = x
+			return y
+		}`, []string{"semantic error. x redeclared in this scope, previous declaration at 1:8"}},
+		{`func f(x : Integer) : Integer {
+			var x : Integer = 1
+			return x
+		}`, []string{"semantic error. x redeclared in this scope, previous declaration at 1:8"}},
+		{`func main() {
+			print(twice(2))
+		}
+		func twice(x : Integer) : Integer {
+			return x * 2
+		}`, []string{}},
+		{`func main() {
+			func inner() {
+			}
+		}`, []string{"semantic error. function inner must be declared at the top level"}},
+		{"return 1", []string{"semantic error. return statement outside of a function"}},
+		{`if (true) {
+			var c : Integer = 1
+		} else {
+			c
+		}`, []string{"semantic error. c is out of scope, it was declared at 2:8"}},
+		{"a = 1", []string{"semantic error. undeclared identifier a"}},
+		{`while true {
+			var d : Integer = 1
+		}
+		d = 2`, []string{"semantic error. d is out of scope, it was declared at 2:8"}},
+	}
+	for _, tt := range tests {
+		_, errs := analyze(t, tt.input)
+		if len(errs) != len(tt.expected) {
+			t.Errorf("wrong number of errors for %q. want=%d, got=%d: %v",
+				tt.input, len(tt.expected), len(errs), errs)
+			continue
+		}
+		for i, e := range tt.expected {
+			if errs[i].Message != e {
+				t.Errorf("errs[%d] wrong. want=%q, got=%q", i, e, errs[i].Message)
+			}
+		}
+	}
+}
+
+func TestResolution(t *testing.T) {
+	input := `var a : Integer = 1
+	func f(a : Decimal) : Decimal {
+		return a
+	}
+	print(a)`
+	prog := parse(t, input)
+	info, errs := Analyze(prog)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	global := prog.Statements[0].(*ast.DeclStatement)
+	fn := prog.Statements[1].(*ast.FuncDecl)
+	ret := fn.Body.Statements[0].(*ast.ReturnStatement).Expr.(*ast.Identifier)
+	call := prog.Statements[2].(*ast.ExprStatement).Expr.(*ast.CallExpression)
+
+	if sym := info.Uses[ret]; sym == nil || sym.Kind != PARAMETER || sym.Type.Spelling != "Decimal" {
+		t.Errorf("return value does not resolve to the parameter. got=%+v", sym)
+	}
+	if sym := info.Uses[call.Arguments[0].(*ast.Identifier)]; sym == nil || sym.Decl != global.ID {
+		t.Errorf("argument does not resolve to the global. got=%+v", sym)
+	}
+	if sym := info.Uses[call.Function.(*ast.Identifier)]; sym == nil || sym.Kind != BUILTIN {
+		t.Errorf("print does not resolve to the builtin. got=%+v", sym)
+	}
+	if sym := info.Defs[global.ID]; sym == nil || !sym.Scope.IsGlobal() || sym.Type.Spelling != "Integer" {
+		t.Errorf("global is not defined in the global scope. got=%+v", sym)
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Prog {
+	l := lexer.NewLexer(reader.NewInput(input))
+	p := parser.NewParser(l)
+	prog, errs := p.Parse()
+	if len(errs) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, errs)
+	}
+	return prog
+}
+
+func analyze(t *testing.T, input string) (*Info, []diagnostic.Diagnostic) {
+	return Analyze(parse(t, input))
+}
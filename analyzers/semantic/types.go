@@ -0,0 +1,58 @@
+package semantic
+
+import (
+	"github.com/wevertonbruno/wb-compiler/analyzers/token"
+)
+
+type Type byte
+
+const (
+	Invalid Type = iota
+	Void
+	Integer
+	Decimal
+	String
+	Char
+	Boolean
+)
+
+var (
+	typeNames = map[Type]string{
+		Invalid: "<invalid>",
+		Void:    "Void",
+		Integer: "Integer",
+		Decimal: "Decimal",
+		String:  "String",
+		Char:    "Char",
+		Boolean: "Boolean",
+	}
+
+	typeKeywords = map[token.Kind]Type{
+		token.INTEGER: Integer,
+		token.DECIMAL: Decimal,
+		token.STRING:  String,
+		token.CHAR:    Char,
+		token.BOOLEAN: Boolean,
+	}
+)
+
+func (t Type) String() string {
+	return typeNames[t]
+}
+
+// TypeOf returns the type named by a type keyword token. Tokens that are not
+// type keywords, such as the missing return type of a function, denote Void.
+func TypeOf(tok token.Token) Type {
+	if t, ok := typeKeywords[tok.Kind]; ok {
+		return t
+	}
+	return Void
+}
+
+func (t Type) IsNumeric() bool {
+	return t == Integer || t == Decimal
+}
+
+func (t Type) IsOrdered() bool {
+	return t == Integer || t == Decimal || t == Char
+}
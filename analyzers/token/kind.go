@@ -6,6 +6,10 @@ func (k Kind) Name() string {
 	return spellMapping[k]
 }
 
+func (k Kind) IsType() bool {
+	return k >= INTEGER && k <= BOOLEAN
+}
+
 const (
 	IDENTIFIER Kind = iota
 	NEWLINE
@@ -13,6 +17,10 @@ const (
 	DECIMALLIT
 	CHARLIT
 	STRINGLIT
+	// the pieces of a string literal split by its interpolations
+	STRINGSTART
+	STRINGMID
+	STRINGEND
 	BOOLEANLIT
 
 	IF
@@ -42,10 +50,13 @@ const (
 	OP_GT
 	OP_GTE
 	NOT
+	AND
+	OR
 
 	ASSIGN
 	SEMICOLON
 	COLON
+	COMMA
 	L_BRACKET
 	R_BRACKET
 	L_BRACE
@@ -57,13 +68,16 @@ const (
 
 var (
 	spellMapping = map[Kind]string{
-		IDENTIFIER: "<identifier>",
-		NEWLINE:    "<new line>",
-		INTLIT:     "<integer>",
-		DECIMALLIT: "<decimal>",
-		CHARLIT:    "<char>",
-		STRINGLIT:  "<string>",
-		BOOLEANLIT: "<boolean>",
+		IDENTIFIER:  "<identifier>",
+		NEWLINE:     "<new line>",
+		INTLIT:      "<integer>",
+		DECIMALLIT:  "<decimal>",
+		CHARLIT:     "<char>",
+		STRINGLIT:   "<string>",
+		STRINGSTART: "<string start>",
+		STRINGMID:   "<string middle>",
+		STRINGEND:   "<string end>",
+		BOOLEANLIT:  "<boolean>",
 
 		IF:       "if",
 		ELSE:     "else",
@@ -92,10 +106,13 @@ var (
 		OP_GT:     ">",
 		OP_GTE:    ">=",
 		NOT:       "!",
+		AND:       "&&",
+		OR:        "||",
 
 		ASSIGN:    "=",
 		SEMICOLON: ";",
 		COLON:     ":",
+		COMMA:     ",",
 		L_BRACKET: "(",
 		R_BRACKET: ")",
 		L_BRACE:   "{",
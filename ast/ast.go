@@ -3,11 +3,15 @@ package ast
 import (
 	"bytes"
 	"github.com/wevertonbruno/wb-compiler/analyzers/token"
+	"strconv"
+	"strings"
 )
 
 /**
 <program> := { <function> | <statement> }
-<function> := func <ident> '(' ')' : <type> '{' {<statement>} '}'
+<function> := func <ident> '(' [ <param> { ',' <param> } ] ')' [ : <type> ] '{' {<statement>} '}'
+<param> := <ident> : <type>
+<assign> := <ident> = <expression>
 */
 
 type Node interface {
@@ -41,6 +45,13 @@ type DeclStatement struct {
 	Value Expr
 }
 
+// AssignStatement stores a new value in a variable declared before.
+type AssignStatement struct {
+	Token  token.Token // the = token
+	Target *Identifier
+	Value  Expr
+}
+
 type Identifier struct {
 	Token token.Token
 	Value string
@@ -72,6 +83,32 @@ type InfixExpression struct {
 	Right Expr
 }
 
+type WhileStatement struct {
+	Token     token.Token
+	Condition Expr
+	Body      *BlockStatement
+}
+
+type FuncDecl struct {
+	Token      token.Token
+	Name       *Identifier
+	Parameters []*Parameter
+	ReturnType token.Token
+	Body       *BlockStatement
+}
+
+type Parameter struct {
+	Token token.Token
+	ID    *Identifier
+	Type  token.Token
+}
+
+type CallExpression struct {
+	Token     token.Token
+	Function  Expr
+	Arguments []Expr
+}
+
 type IfExpression struct {
 	Token               token.Token
 	Condition           Expr
@@ -96,6 +133,24 @@ type Boolean struct {
 	Value bool
 }
 
+type StringLiteral struct {
+	Token token.Token
+	Value string
+}
+
+type CharLiteral struct {
+	Token token.Token
+	Value byte
+}
+
+// InterpolatedString is a string literal with ${...} interpolations. Parts
+// are its text, as StringLiterals, and the expressions interpolated, in
+// order. The empty text between them is left out.
+type InterpolatedString struct {
+	Token token.Token
+	Parts []Expr
+}
+
 // ========= IMPLEMENTATION ============
 
 // string
@@ -103,6 +158,23 @@ func (ls *Identifier) String() string     { return ls.Value }
 func (ls *DecimalLiteral) String() string { return ls.Token.Spelling }
 func (ls *IntegerLiteral) String() string { return ls.Token.Spelling }
 func (ls *Boolean) String() string        { return ls.Token.Spelling }
+func (ls *StringLiteral) String() string  { return strconv.Quote(ls.Value) }
+func (ls *CharLiteral) String() string    { return strconv.QuoteRune(rune(ls.Value)) }
+
+func (ls *InterpolatedString) String() string {
+	out := bytes.Buffer{}
+	out.WriteString("\"")
+	for _, part := range ls.Parts {
+		if text, ok := part.(*StringLiteral); ok {
+			quoted := strconv.Quote(text.Value)
+			out.WriteString(strings.ReplaceAll(quoted[1:len(quoted)-1], "${", "\\${"))
+			continue
+		}
+		out.WriteString("${" + part.String() + "}")
+	}
+	out.WriteString("\"")
+	return out.String()
+}
 
 func (p *Prog) String() string {
 	out := bytes.Buffer{}
@@ -124,6 +196,10 @@ func (ls *DeclStatement) String() string {
 	return out.String()
 }
 
+func (ls *AssignStatement) String() string {
+	return ls.Target.String() + " = " + ls.Value.String() + "\n"
+}
+
 func (ls *ReturnStatement) String() string {
 	out := bytes.Buffer{}
 	out.WriteString(ls.TokenLiteral() + " ")
@@ -150,6 +226,19 @@ func (ls *InfixExpression) String() string {
 	return out.String()
 }
 
+func (ls *CallExpression) String() string {
+	out := bytes.Buffer{}
+	out.WriteString(ls.Function.String() + "(")
+	for i, arg := range ls.Arguments {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(arg.String())
+	}
+	out.WriteString(")")
+	return out.String()
+}
+
 func (ls *IfExpression) String() string {
 	out := bytes.Buffer{}
 	out.WriteString("if ")
@@ -162,6 +251,41 @@ func (ls *IfExpression) String() string {
 	return out.String()
 }
 
+func (ls *WhileStatement) String() string {
+	out := bytes.Buffer{}
+	out.WriteString("while ")
+	out.WriteString(ls.Condition.String())
+	out.WriteString(ls.Body.String())
+	return out.String()
+}
+
+func (ls *FuncDecl) String() string {
+	out := bytes.Buffer{}
+	out.WriteString(ls.TokenLiteral() + " ")
+	out.WriteString(ls.Name.String() + "(")
+	for i, param := range ls.Parameters {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(param.String())
+	}
+	out.WriteString(")")
+	if ls.HasReturnType() {
+		out.WriteString(" : " + ls.ReturnType.Spelling)
+	}
+	out.WriteString(" ")
+	out.WriteString(ls.Body.String())
+	return out.String()
+}
+
+func (ls *FuncDecl) HasReturnType() bool {
+	return ls.ReturnType.Kind.IsType()
+}
+
+func (ls *Parameter) String() string {
+	return ls.ID.String() + " : " + ls.Type.Spelling
+}
+
 func (ls *BlockStatement) String() string {
 	out := bytes.Buffer{}
 	out.WriteString("{ ")
@@ -181,30 +305,48 @@ func (p *Prog) TokenLiteral() string {
 		return ""
 	}
 }
-func (ls *DeclStatement) TokenLiteral() string    { return ls.Token.Spelling }
-func (ls *Identifier) TokenLiteral() string       { return ls.Token.Spelling }
-func (ls *ReturnStatement) TokenLiteral() string  { return ls.Token.Spelling }
-func (ls *ExprStatement) TokenLiteral() string    { return ls.Token.Spelling }
-func (ls *BlockStatement) TokenLiteral() string   { return ls.Token.Spelling }
-func (ls *PrefixExpression) TokenLiteral() string { return ls.Token.Spelling }
-func (ls *InfixExpression) TokenLiteral() string  { return ls.Token.Spelling }
-func (ls *IfExpression) TokenLiteral() string     { return ls.Token.Spelling }
-func (ls *IntegerLiteral) TokenLiteral() string   { return ls.Token.Spelling }
-func (ls *DecimalLiteral) TokenLiteral() string   { return ls.Token.Spelling }
-func (ls *Boolean) TokenLiteral() string          { return ls.Token.Spelling }
+func (ls *DeclStatement) TokenLiteral() string      { return ls.Token.Spelling }
+func (ls *AssignStatement) TokenLiteral() string    { return ls.Token.Spelling }
+func (ls *Identifier) TokenLiteral() string         { return ls.Token.Spelling }
+func (ls *ReturnStatement) TokenLiteral() string    { return ls.Token.Spelling }
+func (ls *ExprStatement) TokenLiteral() string      { return ls.Token.Spelling }
+func (ls *BlockStatement) TokenLiteral() string     { return ls.Token.Spelling }
+func (ls *WhileStatement) TokenLiteral() string     { return ls.Token.Spelling }
+func (ls *FuncDecl) TokenLiteral() string           { return ls.Token.Spelling }
+func (ls *Parameter) TokenLiteral() string          { return ls.Token.Spelling }
+func (ls *PrefixExpression) TokenLiteral() string   { return ls.Token.Spelling }
+func (ls *InfixExpression) TokenLiteral() string    { return ls.Token.Spelling }
+func (ls *IfExpression) TokenLiteral() string       { return ls.Token.Spelling }
+func (ls *CallExpression) TokenLiteral() string     { return ls.Token.Spelling }
+func (ls *IntegerLiteral) TokenLiteral() string     { return ls.Token.Spelling }
+func (ls *DecimalLiteral) TokenLiteral() string     { return ls.Token.Spelling }
+func (ls *Boolean) TokenLiteral() string            { return ls.Token.Spelling }
+func (ls *StringLiteral) TokenLiteral() string      { return ls.Token.Spelling }
+func (ls *CharLiteral) TokenLiteral() string        { return ls.Token.Spelling }
+func (ls *InterpolatedString) TokenLiteral() string { return ls.Token.Spelling }
 
 // Statement
 func (ls *DeclStatement) statementNode()   {}
+func (ls *AssignStatement) statementNode() {}
 func (ls *Identifier) statementNode()      {}
 func (ls *ReturnStatement) statementNode() {}
 func (ls *ExprStatement) statementNode()   {}
 func (ls *BlockStatement) statementNode()  {}
+func (ls *WhileStatement) statementNode()  {}
+func (ls *FuncDecl) statementNode()        {}
+
+// Function
+func (ls *FuncDecl) functionNode() {}
 
 // Expression
-func (ls *IntegerLiteral) expressionNode()   {}
-func (ls *DecimalLiteral) expressionNode()   {}
-func (ls *Identifier) expressionNode()       {}
-func (ls *Boolean) expressionNode()          {}
-func (ls *PrefixExpression) expressionNode() {}
-func (ls *InfixExpression) expressionNode()  {}
-func (ls *IfExpression) expressionNode()     {}
+func (ls *IntegerLiteral) expressionNode()     {}
+func (ls *DecimalLiteral) expressionNode()     {}
+func (ls *Identifier) expressionNode()         {}
+func (ls *Boolean) expressionNode()            {}
+func (ls *StringLiteral) expressionNode()      {}
+func (ls *CharLiteral) expressionNode()        {}
+func (ls *InterpolatedString) expressionNode() {}
+func (ls *PrefixExpression) expressionNode()   {}
+func (ls *InfixExpression) expressionNode()    {}
+func (ls *IfExpression) expressionNode()       {}
+func (ls *CallExpression) expressionNode()     {}
@@ -0,0 +1,841 @@
+// Package amd64 generates x86-64 assembly for the GNU assembler following the
+// System V calling convention, and links it into a Linux executable with the
+// system C toolchain. The code is generated from the intermediate
+// representation, with the variables held in registers by a linear scan
+// allocator.
+package amd64
+
+import (
+	"fmt"
+	"github.com/wevertonbruno/wb-compiler/analyzers/semantic"
+	"github.com/wevertonbruno/wb-compiler/ir"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// prefixes keep the generated symbols apart from each other and from
+	// the C library
+	functionPrefix = "wb_"
+	globalPrefix   = "wbg_"
+
+	wordSize = 8
+)
+
+var (
+	integerRegisters = []string{"%rdi", "%rsi", "%rdx", "%rcx", "%r8", "%r9"}
+	decimalRegisters = []string{"%xmm0", "%xmm1", "%xmm2", "%xmm3", "%xmm4", "%xmm5", "%xmm6", "%xmm7"}
+
+	printRoutines = map[semantic.Type]string{
+		semantic.Integer: "wbrt_print_integer",
+		semantic.Decimal: "wbrt_print_decimal",
+		semantic.Char:    "wbrt_print_char",
+		semantic.Boolean: "wbrt_print_boolean",
+		semantic.String:  "wbrt_print_string",
+	}
+
+	stringRoutines = map[semantic.Type]string{
+		semantic.Integer: "wbrt_string_integer",
+		semantic.Decimal: "wbrt_string_decimal",
+		semantic.Char:    "wbrt_string_char",
+		semantic.Boolean: "wbrt_string_boolean",
+	}
+)
+
+type (
+	generator struct {
+		prog   *ir.Program
+		labels int
+		// literals holds the label of every string constant, which are
+		// written to the read-only data in order
+		literals map[string]string
+		strs     []string
+
+		// state of the function being generated
+		body     *strings.Builder
+		alloc    *allocation
+		blocks   map[*ir.Block]string
+		epilogue string
+	}
+
+	// location of an argument in a call
+	location struct {
+		register string
+		decimal  bool
+	}
+)
+
+// Generate returns the assembly of a program out of SSA form. The C entry
+// point runs the top level statements in order and then calls main, if
+// the program declares one.
+func Generate(p *ir.Program) string {
+	g := &generator{prog: p, literals: make(map[string]string)}
+	out := &strings.Builder{}
+	if len(p.Globals) > 0 {
+		out.WriteString("\t.bss\n\t.align 8\n")
+		for _, global := range p.Globals {
+			fmt.Fprintf(out, "%s:\n\t.zero %d\n", globalPrefix+global.Name, wordSize)
+		}
+	}
+	out.WriteString("\t.text\n\t.globl main\n")
+	g.genFunction(out, p.Entry)
+	for _, fn := range p.Funcs {
+		if fn != p.Entry {
+			g.genFunction(out, fn)
+		}
+	}
+	if len(g.strs) > 0 {
+		out.WriteString("\t.section .rodata\n")
+		for _, str := range g.strs {
+			fmt.Fprintf(out, "%s:\n\t.string %s\n", g.literals[str], quote(str))
+		}
+	}
+	out.WriteString(runtime)
+	return out.String()
+}
+
+// DumpIntervals returns the live intervals of the functions of a program
+// out of SSA form and the registers assigned to them.
+func DumpIntervals(p *ir.Program) string {
+	dumps := make([]string, len(p.Funcs))
+	for i, fn := range p.Funcs {
+		dumps[i] = allocate(fn).String()
+	}
+	return strings.Join(dumps, "\n")
+}
+
+// Build generates the program and links it into the executable output with
+// the C compiler named by $CC, or cc by default.
+func Build(p *ir.Program, output string) error {
+	dir, err := os.MkdirTemp("", "wbc")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, filepath.Base(output)+".s")
+	if err := os.WriteFile(src, []byte(Generate(p)), 0644); err != nil {
+		return err
+	}
+	cc := os.Getenv("CC")
+	if cc == "" {
+		cc = "cc"
+	}
+	if out, err := exec.Command(cc, "-o", output, src).CombinedOutput(); err != nil {
+		return fmt.Errorf("linking %s: %v\n%s", output, err, out)
+	}
+	return nil
+}
+
+// WriteAssembly writes the generated assembly to output.
+func WriteAssembly(p *ir.Program, output string) error {
+	return os.WriteFile(output, []byte(Generate(p)), 0644)
+}
+
+func (g *generator) emit(format string, args ...interface{}) {
+	g.body.WriteByte('\t')
+	fmt.Fprintf(g.body, format, args...)
+	g.body.WriteByte('\n')
+}
+
+func (g *generator) label(name string) {
+	fmt.Fprintf(g.body, "%s:\n", name)
+}
+
+func (g *generator) newLabel() string {
+	g.labels++
+	return fmt.Sprintf(".L%d", g.labels)
+}
+
+// literal returns the label of a string constant.
+func (g *generator) literal(str string) string {
+	label, ok := g.literals[str]
+	if !ok {
+		label = fmt.Sprintf(".Lstring%d", len(g.strs))
+		g.literals[str] = label
+		g.strs = append(g.strs, str)
+	}
+	return label
+}
+
+// quote returns a string in the syntax of the assembler, with the bytes
+// that are not printable in octal.
+func quote(str string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(str); i++ {
+		switch c := str[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func symbol(p *ir.Program, fn *ir.Func) string {
+	if fn == p.Entry {
+		return "main"
+	}
+	return functionPrefix + fn.Name
+}
+
+// classify assigns registers to values of the given types in order, the
+// values left without a register are passed on the stack.
+func classify(types []semantic.Type) ([]location, int) {
+	locations := make([]location, len(types))
+	integers, decimals, stack := 0, 0, 0
+	for i, t := range types {
+		switch {
+		case t == semantic.Decimal && decimals < len(decimalRegisters):
+			locations[i] = location{register: decimalRegisters[decimals], decimal: true}
+			decimals++
+		case t != semantic.Decimal && integers < len(integerRegisters):
+			locations[i] = location{register: integerRegisters[integers]}
+			integers++
+		default:
+			locations[i] = location{decimal: t == semantic.Decimal}
+			stack++
+		}
+	}
+	return locations, stack
+}
+
+func parameterTypes(fn *ir.Func) []semantic.Type {
+	types := make([]semantic.Type, len(fn.Params))
+	for i, param := range fn.Params {
+		types[i] = param.Type
+	}
+	return types
+}
+
+// genFunction writes a function with its prologue, which saves the
+// callee-saved registers the allocator used and reserves the spill slots.
+// The frame is kept 16-byte aligned so that the stack is aligned at calls
+// whenever an even number of words has been pushed.
+func (g *generator) genFunction(out *strings.Builder, fn *ir.Func) {
+	g.body = &strings.Builder{}
+	g.alloc = allocate(fn)
+	g.blocks = make(map[*ir.Block]string)
+	for _, b := range fn.Blocks {
+		g.blocks[b] = g.newLabel()
+	}
+	g.epilogue = g.newLabel()
+
+	locations, _ := classify(parameterTypes(fn))
+	stack := 0
+	for i, param := range fn.Params {
+		switch loc := locations[i]; {
+		case loc.register == "":
+			// above the saved rbp and the return address
+			g.emit("mov %d(%%rbp), %%rax", 2*wordSize+stack*wordSize)
+			g.storeInteger("%rax", param)
+			stack++
+		case loc.decimal:
+			g.storeDecimal(loc.register, param)
+		default:
+			g.storeInteger(loc.register, param)
+		}
+	}
+	for i, b := range fn.Blocks {
+		var next *ir.Block
+		if i+1 < len(fn.Blocks) {
+			next = fn.Blocks[i+1]
+		}
+		g.label(g.blocks[b])
+		for _, instr := range b.Instrs {
+			g.genInstr(fn, instr, next)
+		}
+	}
+
+	frame := 0
+	if g.alloc.slots > 0 {
+		frame = (len(calleeSaved) + g.alloc.slots) * wordSize
+	} else {
+		frame = len(g.alloc.saved) * wordSize
+	}
+	frame = (frame + 15) &^ 15
+	fmt.Fprintf(out, "%s:\n\tpush %%rbp\n\tmov %%rsp, %%rbp\n", symbol(g.prog, fn))
+	if frame > 0 {
+		fmt.Fprintf(out, "\tsub $%d, %%rsp\n", frame)
+	}
+	for i, register := range g.alloc.saved {
+		fmt.Fprintf(out, "\tmov %s, %d(%%rbp)\n", register, -(i+1)*wordSize)
+	}
+	out.WriteString(g.body.String())
+	fmt.Fprintf(out, "%s:\n", g.epilogue)
+	for i, register := range g.alloc.saved {
+		fmt.Fprintf(out, "\tmov %d(%%rbp), %s\n", -(i+1)*wordSize, register)
+	}
+	out.WriteString("\tleave\n\tret\n")
+}
+
+var (
+	integerArithmetic = map[ir.Op]string{
+		ir.OpAdd: "add",
+		ir.OpSub: "sub",
+		ir.OpMul: "imul",
+	}
+
+	integerConditions = map[ir.Op]string{
+		ir.OpEq: "e",
+		ir.OpNe: "ne",
+		ir.OpLt: "l",
+		ir.OpLe: "le",
+		ir.OpGt: "g",
+		ir.OpGe: "ge",
+	}
+
+	decimalArithmetic = map[ir.Op]string{
+		ir.OpAdd: "addsd",
+		ir.OpSub: "subsd",
+		ir.OpMul: "mulsd",
+		ir.OpDiv: "divsd",
+	}
+)
+
+// genInstr generates an instruction, next being the block laid out after
+// the current one, which jumps can fall through to. Operands are brought
+// to the scratch registers, so that a value may share its register with
+// an operand whose interval ends at the same instruction.
+func (g *generator) genInstr(fn *ir.Func, instr *ir.Instr, next *ir.Block) {
+	decimal := len(instr.Args) > 0 && ir.TypeOf(instr.Args[0]) == semantic.Decimal
+	switch op := instr.Op; {
+	case op == ir.OpCopy && decimal:
+		if register := g.register(instr.Dst); register != "" {
+			g.loadDecimal(instr.Args[0], register)
+			return
+		}
+		g.loadBits(instr.Args[0], "%rax")
+		g.storeBits("%rax", instr.Dst)
+	case op == ir.OpCopy:
+		if register := g.register(instr.Dst); register != "" {
+			g.loadInteger(instr.Args[0], register)
+			return
+		}
+		g.loadInteger(instr.Args[0], "%rax")
+		g.storeInteger("%rax", instr.Dst)
+	case op == ir.OpNeg && decimal:
+		g.loadBits(instr.Args[0], "%rax")
+		g.emit("btc $63, %%rax")
+		g.storeBits("%rax", instr.Dst)
+	case op == ir.OpNeg:
+		g.loadInteger(instr.Args[0], "%rax")
+		g.emit("neg %%rax")
+		g.storeInteger("%rax", instr.Dst)
+	case op == ir.OpNot:
+		g.loadInteger(instr.Args[0], "%rax")
+		g.emit("xor $1, %%rax")
+		g.storeInteger("%rax", instr.Dst)
+	case op.IsBinary() && decimal:
+		g.genDecimalBinary(instr)
+	case op.IsBinary() && ir.TypeOf(instr.Args[0]) == semantic.String:
+		// the comparison clobbers no allocatable register, so it is not
+		// treated as a call
+		g.loadInteger(instr.Args[0], "%rdi")
+		g.loadInteger(instr.Args[1], "%rsi")
+		g.emit("call wbrt_equal_strings")
+		if op == ir.OpNe {
+			g.emit("xor $1, %%rax")
+		}
+		g.storeInteger("%rax", instr.Dst)
+	case op.IsBinary():
+		g.genIntegerBinary(instr)
+	case op == ir.OpLoad && g.register(instr.Dst) != "":
+		mov := "mov"
+		if instr.Dst.Type == semantic.Decimal {
+			mov = "movq"
+		}
+		g.emit("%s %s(%%rip), %s", mov, globalPrefix+instr.Name, g.register(instr.Dst))
+	case op == ir.OpLoad:
+		g.emit("mov %s(%%rip), %%rax", globalPrefix+instr.Name)
+		g.storeBits("%rax", instr.Dst)
+	case op == ir.OpStore:
+		g.loadBits(instr.Args[0], "%rax")
+		g.emit("mov %%rax, %s(%%rip)", globalPrefix+instr.Name)
+	case op == ir.OpPrint:
+		for _, arg := range instr.Args {
+			t := ir.TypeOf(arg)
+			if t == semantic.Decimal {
+				g.loadDecimal(arg, "%xmm0")
+			} else {
+				g.loadInteger(arg, "%rdi")
+			}
+			g.emit("call %s", printRoutines[t])
+		}
+	case op == ir.OpStr && decimal:
+		g.loadDecimal(instr.Args[0], "%xmm0")
+		g.emit("call %s", stringRoutines[semantic.Decimal])
+		g.storeInteger("%rax", instr.Dst)
+	case op == ir.OpStr:
+		g.loadInteger(instr.Args[0], "%rdi")
+		g.emit("call %s", stringRoutines[ir.TypeOf(instr.Args[0])])
+		g.storeInteger("%rax", instr.Dst)
+	case op == ir.OpConcat:
+		g.loadInteger(instr.Args[0], "%rdi")
+		g.loadInteger(instr.Args[1], "%rsi")
+		g.emit("call wbrt_concat")
+		g.storeInteger("%rax", instr.Dst)
+	case op == ir.OpCall:
+		g.genCall(instr)
+	case op == ir.OpJump:
+		if instr.Blocks[0] != next {
+			g.emit("jmp %s", g.blocks[instr.Blocks[0]])
+		}
+	case op == ir.OpBranch:
+		g.genBranch(instr, next)
+	case op == ir.OpReturn:
+		switch {
+		case fn == g.prog.Entry:
+			g.emit("xor %%eax, %%eax")
+		case decimal:
+			g.loadDecimal(instr.Args[0], "%xmm0")
+		case len(instr.Args) > 0:
+			g.loadInteger(instr.Args[0], "%rax")
+		}
+		// the epilogue follows the last block
+		if next != nil {
+			g.emit("jmp %s", g.epilogue)
+		}
+	case op == ir.OpUnreachable:
+		g.emit("ud2")
+	}
+}
+
+// genIntegerBinary computes in rax. Booleans and chars are compared as
+// integers.
+func (g *generator) genIntegerBinary(instr *ir.Instr) {
+	left, right := instr.Args[0], instr.Args[1]
+	g.loadInteger(left, "%rax")
+	if op, ok := integerArithmetic[instr.Op]; ok {
+		g.emit("%s %s, %%rax", op, g.source(right))
+		g.storeInteger("%rax", instr.Dst)
+		return
+	}
+	if cond, ok := integerConditions[instr.Op]; ok {
+		g.emit("cmp %s, %%rax", g.source(right))
+		g.emit("set%s %%al", cond)
+		g.emit("movzbq %%al, %%rax")
+		g.storeInteger("%rax", instr.Dst)
+		return
+	}
+	// division by zero is a runtime error, and dividing the smallest
+	// integer by -1 wraps around instead of trapping
+	g.loadInteger(right, "%rcx")
+	divide, negate, end := g.newLabel(), g.newLabel(), g.newLabel()
+	g.emit("test %%rcx, %%rcx")
+	g.emit("jnz %s", divide)
+	g.emit("mov $%d, %%edi", instr.Pos.Line)
+	g.emit("mov $%d, %%esi", instr.Pos.Column)
+	g.emit("call wbrt_division_by_zero")
+	g.label(divide)
+	g.emit("cmp $-1, %%rcx")
+	g.emit("je %s", negate)
+	g.emit("cqo")
+	g.emit("idiv %%rcx")
+	g.emit("jmp %s", end)
+	g.label(negate)
+	g.emit("neg %%rax")
+	g.label(end)
+	g.storeInteger("%rax", instr.Dst)
+}
+
+// genDecimalBinary computes in xmm0 and xmm1. Comparisons are arranged so
+// that they are false when an operand is NaN.
+func (g *generator) genDecimalBinary(instr *ir.Instr) {
+	g.loadDecimal(instr.Args[0], "%xmm0")
+	g.loadDecimal(instr.Args[1], "%xmm1")
+	if op, ok := decimalArithmetic[instr.Op]; ok {
+		g.emit("%s %%xmm1, %%xmm0", op)
+		g.storeDecimal("%xmm0", instr.Dst)
+		return
+	}
+	switch instr.Op {
+	case ir.OpEq:
+		g.emit("ucomisd %%xmm1, %%xmm0")
+		g.emit("sete %%al")
+		g.emit("setnp %%cl")
+		g.emit("and %%cl, %%al")
+	case ir.OpNe:
+		g.emit("ucomisd %%xmm1, %%xmm0")
+		g.emit("setne %%al")
+		g.emit("setp %%cl")
+		g.emit("or %%cl, %%al")
+	case ir.OpLt:
+		g.emit("ucomisd %%xmm0, %%xmm1")
+		g.emit("seta %%al")
+	case ir.OpLe:
+		g.emit("ucomisd %%xmm0, %%xmm1")
+		g.emit("setae %%al")
+	case ir.OpGt:
+		g.emit("ucomisd %%xmm1, %%xmm0")
+		g.emit("seta %%al")
+	case ir.OpGe:
+		g.emit("ucomisd %%xmm1, %%xmm0")
+		g.emit("setae %%al")
+	}
+	g.emit("movzbq %%al, %%rax")
+	g.storeInteger("%rax", instr.Dst)
+}
+
+func (g *generator) genBranch(instr *ir.Instr, next *ir.Block) {
+	switch cond := instr.Args[0].(type) {
+	case *ir.Const:
+		target := instr.Blocks[1]
+		if cond.Int != 0 {
+			target = instr.Blocks[0]
+		}
+		if target != next {
+			g.emit("jmp %s", g.blocks[target])
+		}
+		return
+	case *ir.Var:
+		if register := g.register(cond); register != "" {
+			g.emit("test %s, %s", register, register)
+		} else {
+			g.emit("cmpq $0, %s", g.home(cond))
+		}
+	}
+	then, otherwise := instr.Blocks[0], instr.Blocks[1]
+	switch {
+	case otherwise == next:
+		g.emit("jnz %s", g.blocks[then])
+	case then == next:
+		g.emit("jz %s", g.blocks[otherwise])
+	default:
+		g.emit("jnz %s", g.blocks[then])
+		g.emit("jmp %s", g.blocks[otherwise])
+	}
+}
+
+// genCall pushes the stack arguments, the first one ending up on top, and
+// then moves the others to their registers. Neither the argument registers
+// nor rax are allocatable, so no argument overwrites another.
+func (g *generator) genCall(instr *ir.Instr) {
+	types := make([]semantic.Type, len(instr.Args))
+	for i, arg := range instr.Args {
+		types[i] = ir.TypeOf(arg)
+	}
+	locations, stack := classify(types)
+	pushed := 0
+	if stack%2 != 0 {
+		g.emit("sub $%d, %%rsp", wordSize)
+		pushed++
+	}
+	for i := len(instr.Args) - 1; i >= 0; i-- {
+		if locations[i].register == "" {
+			g.loadBits(instr.Args[i], "%rax")
+			g.emit("push %%rax")
+			pushed++
+		}
+	}
+	for i, loc := range locations {
+		switch {
+		case loc.register == "":
+		case loc.decimal:
+			g.loadDecimal(instr.Args[i], loc.register)
+		default:
+			g.loadInteger(instr.Args[i], loc.register)
+		}
+	}
+	g.emit("call %s", functionPrefix+instr.Name)
+	if pushed > 0 {
+		g.emit("add $%d, %%rsp", pushed*wordSize)
+	}
+	switch {
+	case instr.Dst == nil:
+	case instr.Dst.Type == semantic.Decimal:
+		g.storeDecimal("%xmm0", instr.Dst)
+	default:
+		g.storeInteger("%rax", instr.Dst)
+	}
+}
+
+// register returns the register allocated to v, or "" if v was spilled.
+func (g *generator) register(v *ir.Var) string {
+	return g.alloc.homes[v].register
+}
+
+// home returns where v lives, its register or its stack slot.
+func (g *generator) home(v *ir.Var) string {
+	i := g.alloc.homes[v]
+	if i.register != "" {
+		return i.register
+	}
+	return fmt.Sprintf("%d(%%rbp)", i.slot)
+}
+
+// source returns an operand an integer instruction can read op from. Wide
+// constants are loaded into rcx first.
+func (g *generator) source(op ir.Operand) string {
+	if c, ok := op.(*ir.Const); ok {
+		if c.Int >= math.MinInt32 && c.Int <= math.MaxInt32 {
+			return fmt.Sprintf("$%d", c.Int)
+		}
+		g.loadInteger(c, "%rcx")
+		return "%rcx"
+	}
+	return g.home(op.(*ir.Var))
+}
+
+// loadInteger moves an integer, char, boolean or the address of a string to
+// a general-purpose register.
+func (g *generator) loadInteger(op ir.Operand, register string) {
+	switch op := op.(type) {
+	case *ir.Const:
+		if op.Type == semantic.String {
+			g.emit("lea %s(%%rip), %s", g.literal(op.Str), register)
+			return
+		}
+		if op.Int >= math.MinInt32 && op.Int <= math.MaxInt32 {
+			g.emit("mov $%d, %s", op.Int, register)
+		} else {
+			g.emit("movabs $%d, %s", op.Int, register)
+		}
+	case *ir.Var:
+		if home := g.home(op); home != register {
+			g.emit("mov %s, %s", home, register)
+		}
+	}
+}
+
+func (g *generator) storeInteger(register string, v *ir.Var) {
+	if home := g.home(v); home != register {
+		g.emit("mov %s, %s", register, home)
+	}
+}
+
+// loadDecimal moves a decimal to an SSE register, going through rax for
+// constants.
+func (g *generator) loadDecimal(op ir.Operand, register string) {
+	switch op := op.(type) {
+	case *ir.Const:
+		g.emit("movabs $%#x, %%rax", math.Float64bits(op.Decimal))
+		g.emit("movq %%rax, %s", register)
+	case *ir.Var:
+		home := g.home(op)
+		switch {
+		case home == register:
+		case g.register(op) != "":
+			g.emit("movapd %s, %s", home, register)
+		default:
+			g.emit("movq %s, %s", home, register)
+		}
+	}
+}
+
+func (g *generator) storeDecimal(register string, v *ir.Var) {
+	home := g.home(v)
+	switch {
+	case home == register:
+	case g.register(v) != "":
+		g.emit("movapd %s, %s", register, home)
+	default:
+		g.emit("movq %s, %s", register, home)
+	}
+}
+
+// loadBits moves a value of any type to a general-purpose register,
+// decimals as their bit pattern.
+func (g *generator) loadBits(op ir.Operand, register string) {
+	if ir.TypeOf(op) != semantic.Decimal {
+		g.loadInteger(op, register)
+		return
+	}
+	switch op := op.(type) {
+	case *ir.Const:
+		g.emit("movabs $%#x, %s", math.Float64bits(op.Decimal), register)
+	case *ir.Var:
+		g.emit("movq %s, %s", g.home(op), register)
+	}
+}
+
+// storeBits stores a value held in a general-purpose register, decimals as
+// their bit pattern.
+func (g *generator) storeBits(register string, v *ir.Var) {
+	if v.Type == semantic.Decimal {
+		g.emit("movq %s, %s", register, g.home(v))
+		return
+	}
+	g.storeInteger(register, v)
+}
+
+// runtime implements print and the conversions to String on top of the C
+// library. Decimals are printed like %g, except that every NaN prints as
+// nan. Strings are NUL-terminated, and those built at run time are
+// allocated by asprintf and never freed.
+const runtime = `	.section .rodata
+.Lfmt_integer:
+	.string "%lld\n"
+.Lfmt_decimal:
+	.string "%g\n"
+.Lfmt_char:
+	.string "%c\n"
+.Lfmt_integer_string:
+	.string "%lld"
+.Lfmt_decimal_string:
+	.string "%g"
+.Lfmt_char_string:
+	.string "%c"
+.Lfmt_concat:
+	.string "%s%s"
+.Lstr_true:
+	.string "true"
+.Lstr_false:
+	.string "false"
+.Lstr_nan:
+	.string "nan"
+.Lfmt_division_by_zero:
+	.string "%d:%d: runtime error. integer division by zero\n"
+
+	.text
+wbrt_print_integer:
+	push %rbp
+	mov %rsp, %rbp
+	mov %rdi, %rsi
+	lea .Lfmt_integer(%rip), %rdi
+	xor %eax, %eax
+	call printf@PLT
+	pop %rbp
+	ret
+
+wbrt_print_decimal:
+	push %rbp
+	mov %rsp, %rbp
+	ucomisd %xmm0, %xmm0
+	jp 1f
+	lea .Lfmt_decimal(%rip), %rdi
+	mov $1, %eax
+	call printf@PLT
+	pop %rbp
+	ret
+1:
+	lea .Lstr_nan(%rip), %rdi
+	call puts@PLT
+	pop %rbp
+	ret
+
+wbrt_print_char:
+	push %rbp
+	mov %rsp, %rbp
+	movzbl %dil, %esi
+	lea .Lfmt_char(%rip), %rdi
+	xor %eax, %eax
+	call printf@PLT
+	pop %rbp
+	ret
+
+wbrt_print_boolean:
+	push %rbp
+	mov %rsp, %rbp
+	mov %rdi, %rcx
+	lea .Lstr_true(%rip), %rax
+	lea .Lstr_false(%rip), %rdi
+	test %rcx, %rcx
+	cmovnz %rax, %rdi
+	call puts@PLT
+	pop %rbp
+	ret
+
+wbrt_print_string:
+	push %rbp
+	mov %rsp, %rbp
+	call puts@PLT
+	pop %rbp
+	ret
+
+wbrt_string_integer:
+	push %rbp
+	mov %rsp, %rbp
+	sub $16, %rsp
+	mov %rdi, %rdx
+	lea .Lfmt_integer_string(%rip), %rsi
+	jmp .Lformat
+
+wbrt_string_decimal:
+	push %rbp
+	mov %rsp, %rbp
+	sub $16, %rsp
+	lea .Lstr_nan(%rip), %rax
+	ucomisd %xmm0, %xmm0
+	jp 2f
+	lea .Lfmt_decimal_string(%rip), %rsi
+	lea -8(%rbp), %rdi
+	mov $1, %eax
+	call asprintf@PLT
+	mov -8(%rbp), %rax
+2:
+	leave
+	ret
+
+wbrt_string_char:
+	push %rbp
+	mov %rsp, %rbp
+	sub $16, %rsp
+	movzbl %dil, %edx
+	lea .Lfmt_char_string(%rip), %rsi
+	jmp .Lformat
+
+wbrt_string_boolean:
+	lea .Lstr_true(%rip), %rax
+	lea .Lstr_false(%rip), %rcx
+	test %rdi, %rdi
+	cmovz %rcx, %rax
+	ret
+
+# wbrt_concat(a, b) returns a new string with b appended to a
+wbrt_concat:
+	push %rbp
+	mov %rsp, %rbp
+	sub $16, %rsp
+	mov %rsi, %rcx
+	mov %rdi, %rdx
+	lea .Lfmt_concat(%rip), %rsi
+# the format is in rsi and its arguments in rdx and rcx
+.Lformat:
+	lea -8(%rbp), %rdi
+	xor %eax, %eax
+	call asprintf@PLT
+	mov -8(%rbp), %rax
+	leave
+	ret
+
+# wbrt_equal_strings(a, b) returns 1 when the strings are equal and 0
+# otherwise, touching no register but rax, rdi and rsi
+wbrt_equal_strings:
+	movzbl (%rdi), %eax
+	cmp (%rsi), %al
+	jne 1f
+	inc %rdi
+	inc %rsi
+	test %al, %al
+	jnz wbrt_equal_strings
+1:
+	sete %al
+	movzbl %al, %eax
+	ret
+
+# wbrt_division_by_zero(line, column) reports the error and exits with the
+# status of runtime errors, it never returns
+wbrt_division_by_zero:
+	and $-16, %rsp
+	mov %esi, %ecx
+	mov %edi, %edx
+	mov stderr@GOTPCREL(%rip), %rax
+	mov (%rax), %rdi
+	lea .Lfmt_division_by_zero(%rip), %rsi
+	xor %eax, %eax
+	call fprintf@PLT
+	mov $3, %edi
+	call exit@PLT
+
+	.section .note.GNU-stack,"",@progbits
+`
@@ -0,0 +1,118 @@
+package amd64
+
+import (
+	"github.com/wevertonbruno/wb-compiler/ir"
+	"github.com/wevertonbruno/wb-compiler/ir/opt"
+	"github.com/wevertonbruno/wb-compiler/wbtest"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	p := lower(t, opt.Basic, `var g : Integer = 1
+	func add(a : Integer, b : Decimal) : Decimal {
+		return b
+	}
+	func main() {
+		print(add(g, 2.0))
+	}`)
+	asm := Generate(p)
+
+	expected := []string{
+		"wbg_g:\n\t.zero 8\n",
+		"\t.globl main\nmain:\n\tpush %rbp\n\tmov %rsp, %rbp\n",
+		"\tmov $1, %rax\n\tmov %rax, wbg_g(%rip)\n\tcall wb_main\n",
+		"wb_add:\n\tpush %rbp\n\tmov %rsp, %rbp\n\tmov %rdi, %r10\n\tmovapd %xmm0, %xmm8\n",
+		"\tmov wbg_g(%rip), %r10\n\tmov %r10, %rdi\n\tmovabs $0x4000000000000000, %rax\n\tmovq %rax, %xmm0\n\tcall wb_add\n",
+		"\tmovapd %xmm8, %xmm0\n\tcall wbrt_print_decimal\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(asm, e) {
+			t.Errorf("assembly does not contain %q. got=\n%s", e, asm)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	if _, err := exec.LookPath("cc"); err != nil {
+		t.Skip("no C toolchain to link with")
+	}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`func many(a : Integer, b : Decimal, c : Integer, d : Integer, e : Integer, f : Integer, g : Integer, h : Integer, i : Boolean) : Decimal {
+			print(g - h)
+			print(i)
+			return b * 2.0
+		}
+		func main() {
+			print(many(1, 1.25, 3, 4, 5, 6, 7, 9, true))
+		}`, "-2\ntrue\n2.5\n"},
+		{`func f(a : Integer, b : Integer, c : Integer, d : Integer) : Integer {
+			var e : Integer = a * b
+			var f : Integer = b * c
+			var g : Integer = c * d
+			var h : Integer = d * a
+			var i : Integer = e + f
+			var j : Integer = g + h
+			var k : Integer = e - g
+			var l : Integer = f - h
+			print(e + f + g + h + i + j + k + l)
+			return a + b + c + d + e + f + g + h + i + j + k + l
+		}
+		print(f(1, 2, 3, 4))`, "40\n50\n"},
+		{`func half(x : Decimal) : Decimal {
+			return x / 2.0
+		}
+		func g(x : Decimal, n : Integer) : Decimal {
+			var y : Decimal = x * 3.0
+			var z : Decimal = half(y)
+			if (n > 0) {
+				return g(z + y, n - 1) - x
+			}
+			return z + y + x
+		}
+		print(g(1.0, 3))`, "475.438\n"},
+		{`func sum(n : Integer, acc : Integer) : Integer {
+			if (n == 0) {
+				return acc
+			}
+			return sum(n - 1, acc + n)
+		}
+		print(sum(10000000, 0))`, "50000005000000\n"},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		stdout, err := build(t, dir, tt.input)
+		if err != nil {
+			t.Errorf("program %q failed: %v", tt.input, err)
+			continue
+		}
+		if stdout != tt.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", tt.input, tt.expected, stdout)
+		}
+	}
+	wbtest.RunPrograms(t, func(t *testing.T, source string) (string, error) {
+		return build(t, dir, source)
+	})
+}
+
+func build(t *testing.T, dir, input string) (string, error) {
+	t.Helper()
+	output := filepath.Join(dir, "prog")
+	if err := Build(lower(t, opt.Full, input), output); err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	return wbtest.Exec(exec.Command(output))
+}
+
+// lower checks a program and lowers it to the IR optimized at level.
+func lower(t *testing.T, level int, input string) *ir.Program {
+	t.Helper()
+	p := ir.Lower(wbtest.Check(t, input))
+	opt.Optimize(p, level)
+	return p
+}
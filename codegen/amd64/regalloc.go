@@ -0,0 +1,265 @@
+package amd64
+
+import (
+	"fmt"
+	"github.com/wevertonbruno/wb-compiler/analyzers/semantic"
+	"github.com/wevertonbruno/wb-compiler/ir"
+	"sort"
+	"strings"
+)
+
+var (
+	// The allocatable registers never take part in passing arguments, so
+	// that arguments can be moved to and from them in any order. rax, rcx,
+	// rdx, r11, xmm0, xmm1 and xmm15 are left as scratch registers for the
+	// code generator.
+	calleeSaved = []string{"%rbx", "%r12", "%r13", "%r14", "%r15"}
+	callerSaved = []string{"%r10"}
+	sseSaved    = []string{"%xmm8", "%xmm9", "%xmm10", "%xmm11", "%xmm12", "%xmm13"}
+)
+
+type (
+	// interval is the range of positions where a variable is live, from
+	// its first definition to its last use, holes included.
+	interval struct {
+		v          *ir.Var
+		start, end int
+		// crossesCall is set when a call happens strictly inside the
+		// interval, so that caller-saved registers would be clobbered
+		crossesCall bool
+
+		register string
+		slot     int // offset from rbp when spilled
+	}
+
+	allocation struct {
+		fn        *ir.Func
+		intervals []*interval // sorted by start
+		homes     map[*ir.Var]*interval
+		positions map[*ir.Instr]int
+		slots     int
+		// callee-saved registers to preserve, in the order they were
+		// first assigned
+		saved []string
+	}
+)
+
+func (i *interval) decimal() bool {
+	return i.v.Type == semantic.Decimal
+}
+
+func (i *interval) String() string {
+	home := i.register
+	if home == "" {
+		home = fmt.Sprintf("%d(%%rbp)", i.slot)
+	}
+	call := ""
+	if i.crossesCall {
+		call = " across call"
+	}
+	return fmt.Sprintf("[%d, %d] %s : %s%s", i.start, i.end, i.v, home, call)
+}
+
+// allocate assigns a register or a stack slot to every variable of a
+// function out of SSA form, with the linear scan algorithm of Poletto and
+// Sarkar. The instructions are numbered in layout order from 1, the
+// parameters being defined at 0, and each variable gets a single interval
+// covering every position it is live at. When no register is free, the
+// interval ending last is spilled, and a spilled variable lives in its
+// stack slot for its whole life: the code generator loads it into a
+// scratch register around each instruction that uses it.
+func allocate(fn *ir.Func) *allocation {
+	a := &allocation{fn: fn, homes: make(map[*ir.Var]*interval), positions: make(map[*ir.Instr]int)}
+	a.buildIntervals()
+
+	var free []string
+	var freeSSE []string
+	free = append(append(free, calleeSaved...), callerSaved...)
+	freeSSE = append(freeSSE, sseSaved...)
+
+	var active []*interval // sorted by end
+	for _, current := range a.intervals {
+		// expire the intervals that ended, their last use may share its
+		// instruction with the definition of current
+		kept := active[:0]
+		for _, old := range active {
+			if old.end > current.start {
+				kept = append(kept, old)
+				continue
+			}
+			if old.decimal() {
+				freeSSE = append(freeSSE, old.register)
+			} else {
+				free = append(free, old.register)
+			}
+		}
+		active = kept
+
+		pool := &free
+		if current.decimal() {
+			pool = &freeSSE
+		}
+		if register := a.take(pool, current); register != "" {
+			current.register = register
+			active = insertByEnd(active, current)
+			continue
+		}
+
+		// spill the interval ending last among current and the active
+		// ones whose register current could use
+		var victim *interval
+		for _, old := range active {
+			if old.decimal() == current.decimal() && usable(old.register, current) && (victim == nil || old.end > victim.end) {
+				victim = old
+			}
+		}
+		if victim != nil && victim.end > current.end {
+			current.register = victim.register
+			victim.register = ""
+			a.spill(victim)
+			for i, old := range active {
+				if old == victim {
+					active = append(active[:i], active[i+1:]...)
+					break
+				}
+			}
+			active = insertByEnd(active, current)
+		} else {
+			a.spill(current)
+		}
+	}
+	return a
+}
+
+// take removes and returns a register of the pool that interval can use,
+// or returns "". Caller-saved registers go first when the interval allows
+// them, since the callee-saved ones cost a save and a restore.
+func (a *allocation) take(pool *[]string, i *interval) string {
+	chosen := -1
+	for j, register := range *pool {
+		if !usable(register, i) {
+			continue
+		}
+		if chosen < 0 || !contains(calleeSaved, register) && contains(calleeSaved, (*pool)[chosen]) {
+			chosen = j
+		}
+	}
+	if chosen < 0 {
+		return ""
+	}
+	register := (*pool)[chosen]
+	*pool = append((*pool)[:chosen], (*pool)[chosen+1:]...)
+	if contains(calleeSaved, register) && !contains(a.saved, register) {
+		a.saved = append(a.saved, register)
+	}
+	return register
+}
+
+// usable reports whether an interval may live in a register: every SSE
+// register is caller-saved, so only callee-saved general-purpose
+// registers survive a call.
+func usable(register string, i *interval) bool {
+	return !i.crossesCall || contains(calleeSaved, register)
+}
+
+func (a *allocation) spill(i *interval) {
+	a.slots++
+	i.slot = -(len(calleeSaved) + a.slots) * wordSize
+}
+
+func insertByEnd(active []*interval, i *interval) []*interval {
+	at := sort.Search(len(active), func(j int) bool { return active[j].end > i.end })
+	active = append(active, nil)
+	copy(active[at+1:], active[at:])
+	active[at] = i
+	return active
+}
+
+func (a *allocation) buildIntervals() {
+	live := a.fn.Liveness()
+	var calls []int
+	extend := func(v *ir.Var, pos int) {
+		i := a.homes[v]
+		if i == nil {
+			i = &interval{v: v, start: pos, end: pos}
+			a.homes[v] = i
+			a.intervals = append(a.intervals, i)
+		}
+		if pos < i.start {
+			i.start = pos
+		}
+		if pos > i.end {
+			i.end = pos
+		}
+	}
+	for _, param := range a.fn.Params {
+		extend(param, 0)
+	}
+
+	pos := 0
+	for _, b := range a.fn.Blocks {
+		first := pos + 1
+		for _, instr := range b.Instrs {
+			pos++
+			a.positions[instr] = pos
+			for _, arg := range instr.Args {
+				if v, ok := arg.(*ir.Var); ok {
+					extend(v, pos)
+				}
+			}
+			if instr.Dst != nil {
+				extend(instr.Dst, pos)
+			}
+			switch instr.Op {
+			case ir.OpCall, ir.OpPrint, ir.OpStr, ir.OpConcat:
+				calls = append(calls, pos)
+			}
+		}
+		for _, v := range live.In(b) {
+			extend(v, first)
+		}
+		for _, v := range live.Out(b) {
+			extend(v, pos)
+		}
+	}
+
+	sort.SliceStable(a.intervals, func(i, j int) bool {
+		x, y := a.intervals[i], a.intervals[j]
+		return x.start < y.start || x.start == y.start && x.v.Name < y.v.Name
+	})
+	for _, i := range a.intervals {
+		for _, call := range calls {
+			if i.start < call && call < i.end {
+				i.crossesCall = true
+				break
+			}
+		}
+	}
+}
+
+// String dumps the numbered instructions and the intervals with the
+// register or stack slot assigned to each.
+func (a *allocation) String() string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "func %s\n", a.fn.Name)
+	for _, b := range a.fn.Blocks {
+		fmt.Fprintf(&out, "%s:\n", b)
+		for _, instr := range b.Instrs {
+			fmt.Fprintf(&out, "%4d  %s\n", a.positions[instr], instr)
+		}
+	}
+	out.WriteString("intervals:\n")
+	for _, i := range a.intervals {
+		fmt.Fprintf(&out, "    %s\n", i)
+	}
+	return out.String()
+}
+
+func contains(registers []string, register string) bool {
+	for _, r := range registers {
+		if r == register {
+			return true
+		}
+	}
+	return false
+}
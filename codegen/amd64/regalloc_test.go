@@ -0,0 +1,79 @@
+package amd64
+
+import (
+	"github.com/wevertonbruno/wb-compiler/ir/opt"
+	"strings"
+	"testing"
+)
+
+func TestAllocate(t *testing.T) {
+	p := lower(t, opt.Basic, `func f(a : Integer, b : Integer, x : Decimal) : Decimal {
+		var c : Integer = a * b
+		var d : Integer = a + b
+		var e : Integer = a - b
+		var f : Integer = c * d
+		var g : Integer = d * e
+		var h : Integer = e * c
+		var i : Integer = f + g
+		var j : Integer = g + h
+		var y : Decimal = x * 2.0
+		print(a + b + c + d + e + f + g + h + i + j)
+		return y + x
+	}`)
+	a := allocate(p.Func("f"))
+
+	spilled := 0
+	for _, i := range a.intervals {
+		if i.register == "" {
+			spilled++
+			if i.slot >= -len(calleeSaved)*wordSize {
+				t.Errorf("%s spilled over the saved registers", i)
+			}
+			continue
+		}
+		if i.crossesCall && !contains(calleeSaved, i.register) {
+			t.Errorf("%s lives across a call in a caller-saved register", i)
+		}
+		if i.decimal() != strings.HasPrefix(i.register, "%xmm") {
+			t.Errorf("%s is in a register of the wrong class", i)
+		}
+		for _, other := range a.intervals {
+			if other != i && other.register == i.register && other.start < i.end && i.start < other.end {
+				t.Errorf("%s and %s overlap in the same register", i, other)
+			}
+		}
+	}
+	if spilled == 0 {
+		t.Errorf("expected spills with more live values than registers. got=\n%s", a)
+	}
+	// x and y live across the print, and no SSE register is callee-saved
+	for _, name := range []string{"x", "y"} {
+		for _, i := range a.intervals {
+			if i.v.Name == name && i.register != "" {
+				t.Errorf("%s not spilled across the call", i)
+			}
+		}
+	}
+	if len(a.saved) != len(calleeSaved) {
+		t.Errorf("expected every callee-saved register to be used. got=%v", a.saved)
+	}
+}
+
+func TestDumpIntervals(t *testing.T) {
+	p := lower(t, opt.Basic, `func f(n : Integer) : Integer {
+		return n * 2 + 1
+	}`)
+	expected := `func f
+b0:
+   1  %1 = mul n, 2
+   2  %2 = add %1, 1
+   3  ret %2
+intervals:
+    [0, 1] n : %r10
+    [1, 2] %1 : %r10
+    [2, 3] %2 : %r10
+`
+	if got := DumpIntervals(p); !strings.HasPrefix(got, expected) {
+		t.Errorf("wrong dump. expected=\n%s\ngot=\n%s", expected, got)
+	}
+}
@@ -0,0 +1,568 @@
+// Package cgen transpiles checked programs to a single C99 source file.
+//
+// WBlang integers wrap around on overflow, so the output must be compiled
+// with -fwrapv, as in cc -fwrapv -o prog prog.c.
+package cgen
+
+import (
+	"fmt"
+	"github.com/wevertonbruno/wb-compiler/analyzers/semantic"
+	"github.com/wevertonbruno/wb-compiler/analyzers/token"
+	"github.com/wevertonbruno/wb-compiler/ast"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	entryPoint = "main"
+
+	// generated names start with wb, local variables whose name does too,
+	// or that are reserved in C, are renamed with localPrefix
+	functionPrefix = "wb_"
+	globalPrefix   = "wbg_"
+	localPrefix    = "wbl_"
+	tempPrefix     = "wbt"
+
+	indentation = "    "
+)
+
+var (
+	cTypes = map[semantic.Type]string{
+		semantic.Void:    "void",
+		semantic.Integer: "int64_t",
+		semantic.Decimal: "double",
+		semantic.Char:    "unsigned char",
+		semantic.Boolean: "bool",
+		semantic.String:  "const char *",
+	}
+
+	printRoutines = map[semantic.Type]string{
+		semantic.Integer: "wbrt_print_integer",
+		semantic.Decimal: "wbrt_print_decimal",
+		semantic.Char:    "wbrt_print_char",
+		semantic.Boolean: "wbrt_print_boolean",
+		semantic.String:  "wbrt_print_string",
+	}
+
+	stringRoutines = map[semantic.Type]string{
+		semantic.Integer: "wbrt_string_integer",
+		semantic.Decimal: "wbrt_string_decimal",
+		semantic.Char:    "wbrt_string_char",
+		semantic.Boolean: "wbrt_string_boolean",
+	}
+
+	// reserved lists the C keywords and the names the included headers may
+	// define as macros
+	reserved = map[string]bool{}
+)
+
+func init() {
+	for _, name := range strings.Fields(`auto break case char const continue
+		default do double else enum extern float for goto if inline int long
+		register restrict return short signed sizeof static struct switch
+		typedef union unsigned void volatile while _Bool _Complex _Imaginary
+		bool true false main errno stdin stdout stderr EOF NULL assert
+		isnan isinf INFINITY NAN`) {
+		reserved[name] = true
+	}
+}
+
+type generator struct {
+	info   *semantic.Info
+	out    *strings.Builder
+	indent int
+	temps  int
+
+	// prelude holds the declarations of the temporaries that the
+	// expression being generated needs to evaluate from left to right
+	prelude []string
+}
+
+// Generate returns the C source of a checked program. Its main function
+// runs the top level statements in order and then calls the main function
+// of the program, if it declares one.
+func Generate(prog *ast.Prog, info *semantic.Info) string {
+	out := &strings.Builder{}
+	g := &generator{info: info, out: out}
+
+	out.WriteString(header)
+
+	var funcs []*ast.FuncDecl
+	var main *ast.FuncDecl
+	globals := false
+	for _, stmt := range prog.Statements {
+		switch node := stmt.(type) {
+		case *ast.DeclStatement:
+			fmt.Fprintf(out, "static %s;\n", declare(semantic.TypeOf(node.Type), globalPrefix+node.ID.Value))
+			globals = true
+		case *ast.FuncDecl:
+			funcs = append(funcs, node)
+			if node.Name.Value == entryPoint && len(node.Parameters) == 0 {
+				main = node
+			}
+		}
+	}
+	if globals {
+		out.WriteString("\n")
+	}
+	for _, fn := range funcs {
+		fmt.Fprintf(out, "%s;\n", g.signature(fn))
+	}
+	if len(funcs) > 0 {
+		out.WriteString("\n")
+	}
+	for _, fn := range funcs {
+		fmt.Fprintf(out, "%s {\n", g.signature(fn))
+		g.genBlock(fn.Body.Statements)
+		out.WriteString("}\n\n")
+	}
+
+	out.WriteString("int main(void) {\n")
+	var stmts []ast.Stmt
+	for _, stmt := range prog.Statements {
+		if _, ok := stmt.(*ast.FuncDecl); !ok {
+			stmts = append(stmts, stmt)
+		}
+	}
+	g.genBlock(stmts)
+	g.indent++
+	if main != nil {
+		g.line("%s%s();", functionPrefix, main.Name.Value)
+	}
+	g.line("return 0;")
+	g.indent--
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// WriteSource writes the C source of the program to output.
+func WriteSource(prog *ast.Prog, info *semantic.Info, output string) error {
+	return os.WriteFile(output, []byte(Generate(prog, info)), 0644)
+}
+
+func (g *generator) signature(fn *ast.FuncDecl) string {
+	params := make([]string, len(fn.Parameters))
+	for i, param := range fn.Parameters {
+		params[i] = declare(semantic.TypeOf(param.Type), g.name(g.info.Defs[param.ID]))
+	}
+	if len(params) == 0 {
+		params = []string{"void"}
+	}
+	return fmt.Sprintf("static %s(%s)",
+		declare(semantic.TypeOf(fn.ReturnType), functionPrefix+fn.Name.Value), strings.Join(params, ", "))
+}
+
+// declare returns the declaration of name with the C type of t.
+func declare(t semantic.Type, name string) string {
+	if strings.HasSuffix(cTypes[t], "*") {
+		return cTypes[t] + name
+	}
+	return cTypes[t] + " " + name
+}
+
+// name returns the C name of a symbol.
+func (g *generator) name(sym *semantic.Symbol) string {
+	switch {
+	case sym.Kind == semantic.FUNCTION:
+		return functionPrefix + sym.Name
+	case sym.Scope.IsGlobal():
+		return globalPrefix + sym.Name
+	case reserved[sym.Name] || strings.HasPrefix(sym.Name, "wb"):
+		return localPrefix + sym.Name
+	}
+	return sym.Name
+}
+
+func (g *generator) line(format string, args ...interface{}) {
+	g.out.WriteString(strings.Repeat(indentation, g.indent))
+	fmt.Fprintf(g.out, format, args...)
+	g.out.WriteByte('\n')
+}
+
+func (g *generator) genBlock(stmts []ast.Stmt) {
+	g.indent++
+	for _, stmt := range stmts {
+		g.genStatement(stmt)
+	}
+	g.indent--
+}
+
+// flush writes the temporaries needed by the statement that follows.
+func (g *generator) flush() {
+	for _, decl := range g.prelude {
+		g.line("%s", decl)
+	}
+	g.prelude = nil
+}
+
+func (g *generator) genStatement(stmt ast.Stmt) {
+	switch node := stmt.(type) {
+	case *ast.DeclStatement:
+		sym := g.info.Defs[node.ID]
+		value := g.genTop(node.Value)
+		if !sym.Scope.IsGlobal() && mentions(node.Value, node.ID.Value) {
+			// in C the new variable would already be in scope
+			value = g.temp(semantic.TypeOf(node.Type), value)
+		}
+		g.flush()
+		if sym.Scope.IsGlobal() {
+			g.line("%s = %s;", g.name(sym), value)
+		} else {
+			g.line("%s = %s;", declare(semantic.TypeOf(node.Type), g.name(sym)), value)
+		}
+	case *ast.AssignStatement:
+		value := g.genTop(node.Value)
+		g.flush()
+		g.line("%s = %s;", g.name(g.info.Uses[node.Target]), value)
+	case *ast.ReturnStatement:
+		if node.Expr == nil {
+			g.line("return;")
+			return
+		}
+		value := g.genTop(node.Expr)
+		g.flush()
+		g.line("return %s;", value)
+	case *ast.ExprStatement:
+		if ifExpr, ok := node.Expr.(*ast.IfExpression); ok {
+			g.genIf(ifExpr)
+			return
+		}
+		value := g.genTop(node.Expr)
+		g.flush()
+		g.line("%s;", value)
+	case *ast.WhileStatement:
+		cond := g.genTop(node.Condition)
+		if len(g.prelude) == 0 {
+			g.line("while (%s) {", cond)
+			g.genBlock(node.Body.Statements)
+			g.line("}")
+			return
+		}
+		// the temporaries must be evaluated again on every iteration
+		g.line("for (;;) {")
+		g.indent++
+		g.flush()
+		g.line("if (!(%s)) {", cond)
+		g.line("%sbreak;", indentation)
+		g.line("}")
+		g.indent--
+		g.genBlock(node.Body.Statements)
+		g.line("}")
+	case *ast.BlockStatement:
+		g.line("{")
+		g.genBlock(node.Statements)
+		g.line("}")
+	}
+}
+
+func (g *generator) genIf(node *ast.IfExpression) {
+	cond := g.genTop(node.Condition)
+	g.flush()
+	g.line("if (%s) {", cond)
+	g.genBlock(node.TrueBlockCondition.Statements)
+	if node.FalseBlockCondition != nil {
+		g.line("} else {")
+		g.genBlock(node.FalseBlockCondition.Statements)
+	}
+	g.line("}")
+}
+
+// temp declares a temporary holding the value in the prelude.
+func (g *generator) temp(t semantic.Type, value string) string {
+	g.temps++
+	name := fmt.Sprintf("%s%d", tempPrefix, g.temps)
+	g.prelude = append(g.prelude, fmt.Sprintf("%s = %s;", declare(t, name), value))
+	return name
+}
+
+// genTop generates an expression without the parentheses of its outermost
+// operator.
+func (g *generator) genTop(expr ast.Expr) string {
+	switch node := expr.(type) {
+	case *ast.PrefixExpression:
+		return node.Token.Spelling + g.genExpression(node.Right)
+	case *ast.InfixExpression:
+		if isLogical(node) {
+			return g.genLogical(node, "%s %s %s")
+		}
+		if !g.isDivision(node) {
+			operands := g.genOperands(false, node.Left, node.Right)
+			if g.info.TypeOf(node.Left) == semantic.String {
+				return fmt.Sprintf("strcmp(%s, %s) %s 0", operands[0], operands[1], node.Token.Spelling)
+			}
+			return fmt.Sprintf("%s %s %s", operands[0], node.Token.Spelling, operands[1])
+		}
+	}
+	return g.genExpression(expr)
+}
+
+func (g *generator) genExpression(expr ast.Expr) string {
+	switch node := expr.(type) {
+	case *ast.IntegerLiteral:
+		return strconv.FormatInt(node.Value, 10)
+	case *ast.DecimalLiteral:
+		s := strconv.FormatFloat(node.Value, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s
+	case *ast.Boolean:
+		return strconv.FormatBool(node.Value)
+	case *ast.StringLiteral:
+		return quote(node.Value)
+	case *ast.CharLiteral:
+		return strconv.Itoa(int(node.Value))
+	case *ast.InterpolatedString:
+		parts := g.genOperands(true, node.Parts...)
+		for i, part := range node.Parts {
+			if t := g.info.TypeOf(part); t != semantic.String {
+				parts[i] = fmt.Sprintf("%s(%s)", stringRoutines[t], parts[i])
+			}
+		}
+		str := parts[0]
+		for _, part := range parts[1:] {
+			str = fmt.Sprintf("wbrt_concat(%s, %s)", str, part)
+		}
+		return str
+	case *ast.Identifier:
+		return g.name(g.info.Uses[node])
+	case *ast.PrefixExpression:
+		return fmt.Sprintf("(%s%s)", node.Token.Spelling, g.genExpression(node.Right))
+	case *ast.InfixExpression:
+		if isLogical(node) {
+			return g.genLogical(node, "(%s %s %s)")
+		}
+		if g.isDivision(node) {
+			operands := g.genOperands(true, node.Left, node.Right)
+			pos := node.Token.Position
+			return fmt.Sprintf("wbrt_divide(%s, %s, %d, %d)", operands[0], operands[1], pos.Line, pos.Column)
+		}
+		operands := g.genOperands(false, node.Left, node.Right)
+		if g.info.TypeOf(node.Left) == semantic.String {
+			return fmt.Sprintf("(strcmp(%s, %s) %s 0)", operands[0], operands[1], node.Token.Spelling)
+		}
+		return fmt.Sprintf("(%s %s %s)", operands[0], node.Token.Spelling, operands[1])
+	case *ast.CallExpression:
+		id := node.Function.(*ast.Identifier)
+		args := g.genOperands(true, node.Arguments...)
+		sym := g.info.Uses[id]
+		if sym.Kind == semantic.BUILTIN {
+			return fmt.Sprintf("%s(%s)", printRoutines[g.info.TypeOf(node.Arguments[0])], args[0])
+		}
+		return fmt.Sprintf("%s(%s)", g.name(sym), strings.Join(args, ", "))
+	}
+	return ""
+}
+
+func isLogical(node *ast.InfixExpression) bool {
+	return node.Token.Kind == token.AND || node.Token.Kind == token.OR
+}
+
+// genLogical generates && and ||, which short-circuit in C as well, with
+// the given format. The temporaries of the right operand may only be
+// evaluated along with it, so the operator then becomes an if in the
+// prelude.
+func (g *generator) genLogical(node *ast.InfixExpression, format string) string {
+	left := g.genExpression(node.Left)
+	prelude := g.prelude
+	g.prelude = nil
+	right := g.genExpression(node.Right)
+	if len(g.prelude) == 0 {
+		g.prelude = prelude
+		return fmt.Sprintf(format, left, node.Token.Spelling, right)
+	}
+	inner := g.prelude
+	g.prelude = prelude
+	result := g.temp(semantic.Boolean, left)
+	cond := result
+	if node.Token.Kind == token.OR {
+		cond = "!" + result
+	}
+	g.prelude = append(g.prelude, fmt.Sprintf("if (%s) {", cond))
+	for _, decl := range inner {
+		g.prelude = append(g.prelude, indentation+decl)
+	}
+	g.prelude = append(g.prelude, fmt.Sprintf("%s%s = %s;", indentation, result, right), "}")
+	return result
+}
+
+func (g *generator) isDivision(node *ast.InfixExpression) bool {
+	return node.Token.Kind == token.OP_DIVIDE && g.info.TypeOf(node.Left) == semantic.Integer
+}
+
+// genOperands generates expressions that WBlang evaluates from left to right.
+// C leaves the order unspecified, so an operand followed by a call, which
+// may print or change a global, is evaluated beforehand into a temporary.
+// Arguments, separated by commas, need no parentheses.
+func (g *generator) genOperands(arguments bool, exprs ...ast.Expr) []string {
+	operands := make([]string, len(exprs))
+	for i, expr := range exprs {
+		if arguments {
+			operands[i] = g.genTop(expr)
+		} else {
+			operands[i] = g.genExpression(expr)
+		}
+		if !isLiteral(expr) && hasCall(exprs[i+1:]...) {
+			operands[i] = g.temp(g.info.TypeOf(expr), operands[i])
+		}
+	}
+	return operands
+}
+
+func isLiteral(expr ast.Expr) bool {
+	switch expr.(type) {
+	case *ast.IntegerLiteral, *ast.DecimalLiteral, *ast.Boolean, *ast.StringLiteral, *ast.CharLiteral:
+		return true
+	}
+	return false
+}
+
+func hasCall(exprs ...ast.Expr) bool {
+	for _, expr := range exprs {
+		switch node := expr.(type) {
+		case *ast.CallExpression:
+			return true
+		case *ast.PrefixExpression:
+			if hasCall(node.Right) {
+				return true
+			}
+		case *ast.InfixExpression:
+			if hasCall(node.Left, node.Right) {
+				return true
+			}
+		case *ast.InterpolatedString:
+			if hasCall(node.Parts...) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// quote returns a string as a C literal, with the bytes that are not
+// printable in octal. Question marks are escaped too, so that no trigraph
+// is formed.
+func quote(str string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(str); i++ {
+		switch c := str[i]; {
+		case c == '"' || c == '\\' || c == '?':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// mentions reports whether an identifier with the given name occurs in expr.
+func mentions(expr ast.Expr, name string) bool {
+	switch node := expr.(type) {
+	case *ast.Identifier:
+		return node.Value == name
+	case *ast.PrefixExpression:
+		return mentions(node.Right, name)
+	case *ast.InfixExpression:
+		return mentions(node.Left, name) || mentions(node.Right, name)
+	case *ast.CallExpression:
+		for _, arg := range node.Arguments {
+			if mentions(arg, name) {
+				return true
+			}
+		}
+	case *ast.InterpolatedString:
+		for _, part := range node.Parts {
+			if mentions(part, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// header holds the runtime, which implements print and the conversions to
+// String on top of the C library. Decimals are printed like %g, except that
+// every NaN prints as nan. The strings built at run time are never freed.
+const header = `/* Generated by wbc. Compile with -fwrapv. */
+#include <inttypes.h>
+#include <math.h>
+#include <stdarg.h>
+#include <stdbool.h>
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+static inline void wbrt_print_integer(int64_t v) {
+    printf("%" PRId64 "\n", v);
+}
+
+static inline void wbrt_print_decimal(double v) {
+    if (isnan(v)) {
+        puts("nan");
+    } else {
+        printf("%g\n", v);
+    }
+}
+
+static inline void wbrt_print_char(unsigned char v) {
+    printf("%c\n", v);
+}
+
+static inline void wbrt_print_boolean(bool v) {
+    puts(v ? "true" : "false");
+}
+
+static inline void wbrt_print_string(const char *v) {
+    puts(v);
+}
+
+static inline const char *wbrt_format(const char *format, ...) {
+    va_list args;
+    va_start(args, format);
+    int n = vsnprintf(NULL, 0, format, args);
+    va_end(args);
+    char *s = malloc(n + 1);
+    va_start(args, format);
+    vsnprintf(s, n + 1, format, args);
+    va_end(args);
+    return s;
+}
+
+static inline const char *wbrt_string_integer(int64_t v) {
+    return wbrt_format("%" PRId64, v);
+}
+
+static inline const char *wbrt_string_decimal(double v) {
+    return isnan(v) ? "nan" : wbrt_format("%g", v);
+}
+
+static inline const char *wbrt_string_char(unsigned char v) {
+    return wbrt_format("%c", v);
+}
+
+static inline const char *wbrt_string_boolean(bool v) {
+    return v ? "true" : "false";
+}
+
+static inline const char *wbrt_concat(const char *a, const char *b) {
+    return wbrt_format("%s%s", a, b);
+}
+
+static inline int64_t wbrt_divide(int64_t a, int64_t b, int line, int column) {
+    if (b == 0) {
+        fflush(stdout);
+        fprintf(stderr, "%d:%d: runtime error. integer division by zero\n", line, column);
+        exit(3);
+    }
+    /* the smallest integer divided by -1 wraps around instead of trapping */
+    return b == -1 ? -a : a / b;
+}
+
+`
@@ -0,0 +1,89 @@
+package cgen
+
+import (
+	"github.com/wevertonbruno/wb-compiler/wbtest"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	prog, info := wbtest.Check(t, `var total : Decimal = 1.0
+	func fib(n : Integer) : Integer {
+		if (n < 2) {
+			return n
+		}
+		return fib(n - 1) + fib(n - 2)
+	}
+	func main() {
+		var int : Integer = fib(10) / 2
+		while !(int < 0) {
+			var int : Integer = int - 1
+			print(-int)
+			return
+		}
+	}`)
+	source := Generate(prog, info)
+
+	expected := `static double wbg_total;
+
+static int64_t wb_fib(int64_t n);
+static void wb_main(void);
+
+static int64_t wb_fib(int64_t n) {
+    if (n < 2) {
+        return n;
+    }
+    int64_t wbt1 = wb_fib(n - 1);
+    return wbt1 + wb_fib(n - 2);
+}
+
+static void wb_main(void) {
+    int64_t wbl_int = wbrt_divide(wb_fib(10), 2, 9, 31);
+    while (!(wbl_int < 0)) {
+        int64_t wbt2 = wbl_int - 1;
+        int64_t wbl_int = wbt2;
+        wbrt_print_integer(-wbl_int);
+        return;
+    }
+}
+
+int main(void) {
+    wbg_total = 1.0;
+    wb_main();
+    return 0;
+}
+`
+	if !strings.HasPrefix(source, header) {
+		t.Errorf("source does not start with the runtime. got=\n%s", source)
+	}
+	if got := strings.TrimPrefix(source, header); got != expected {
+		t.Errorf("wrong source. expected=\n%s\ngot=\n%s", expected, got)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	if _, err := exec.LookPath("cc"); err != nil {
+		t.Skip("no C compiler")
+	}
+	dir := t.TempDir()
+	wbtest.RunPrograms(t, func(t *testing.T, source string) (string, error) {
+		return build(t, dir, source)
+	})
+}
+
+func build(t *testing.T, dir, input string) (string, error) {
+	t.Helper()
+	prog, info := wbtest.Check(t, input)
+	source := filepath.Join(dir, "prog.c")
+	output := filepath.Join(dir, "prog")
+	if err := WriteSource(prog, info, source); err != nil {
+		t.Fatal(err)
+	}
+	cc := exec.Command("cc", "-std=c99", "-Wall", "-Werror", "-fwrapv", "-o", output, source)
+	if out, err := cc.CombinedOutput(); err != nil {
+		t.Fatalf("compiling %q failed: %v\n%s", input, err, out)
+	}
+	return wbtest.Exec(exec.Command(output))
+}
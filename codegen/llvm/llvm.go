@@ -0,0 +1,621 @@
+// Package llvm translates checked programs to textual LLVM IR. Variables
+// live in stack slots created with alloca, which LLVM promotes to registers
+// when the module is optimized.
+package llvm
+
+import (
+	"fmt"
+	"github.com/wevertonbruno/wb-compiler/analyzers/semantic"
+	"github.com/wevertonbruno/wb-compiler/analyzers/token"
+	"github.com/wevertonbruno/wb-compiler/ast"
+	"math"
+	"os"
+	"strings"
+)
+
+const (
+	entryPoint = "main"
+
+	functionPrefix = "wb_"
+	globalPrefix   = "wbg_"
+)
+
+var (
+	llvmTypes = map[semantic.Type]string{
+		semantic.Void:    "void",
+		semantic.Integer: "i64",
+		semantic.Decimal: "double",
+		semantic.Char:    "i8",
+		semantic.Boolean: "i1",
+		semantic.String:  "i8*",
+	}
+
+	zeroValues = map[semantic.Type]string{
+		semantic.Integer: "0",
+		semantic.Decimal: "0.0",
+		semantic.Char:    "0",
+		semantic.Boolean: "false",
+		semantic.String:  "null",
+	}
+
+	printRoutines = map[semantic.Type]string{
+		semantic.Integer: "wbrt_print_integer",
+		semantic.Decimal: "wbrt_print_decimal",
+		semantic.Char:    "wbrt_print_char",
+		semantic.Boolean: "wbrt_print_boolean",
+		semantic.String:  "wbrt_print_string",
+	}
+
+	stringRoutines = map[semantic.Type]string{
+		semantic.Integer: "wbrt_string_integer",
+		semantic.Decimal: "wbrt_string_decimal",
+		semantic.Char:    "wbrt_string_char",
+		semantic.Boolean: "wbrt_string_boolean",
+	}
+)
+
+type (
+	generator struct {
+		info *semantic.Info
+		// strs are the string literals, which become constants of the
+		// module named after their index
+		strs []string
+
+		// state of the function being generated
+		allocas    *strings.Builder
+		body       *strings.Builder
+		slots      map[*semantic.Symbol]string
+		names      map[string]int
+		returnType semantic.Type
+		terminated bool
+		// block is the label of the current block
+		block string
+	}
+
+	// value is an operand of an instruction together with its type
+	value struct {
+		operand string
+		t       semantic.Type
+	}
+)
+
+// Generate returns the IR module of a checked program. Its main function
+// runs the top level statements in order and then calls the main function
+// of the program, if it declares one.
+func Generate(prog *ast.Prog, info *semantic.Info) string {
+	g := &generator{info: info}
+	out := &strings.Builder{}
+
+	var main *ast.FuncDecl
+	globals := false
+	for _, stmt := range prog.Statements {
+		switch node := stmt.(type) {
+		case *ast.DeclStatement:
+			t := semantic.TypeOf(node.Type)
+			fmt.Fprintf(out, "@%s%s = internal global %s %s\n", globalPrefix, node.ID.Value, llvmTypes[t], zeroValues[t])
+			globals = true
+		case *ast.FuncDecl:
+			if node.Name.Value == entryPoint && len(node.Parameters) == 0 {
+				main = node
+			}
+		}
+	}
+	if globals {
+		out.WriteString("\n")
+	}
+
+	g.beginFunction(semantic.Void)
+	for _, stmt := range prog.Statements {
+		if _, ok := stmt.(*ast.FuncDecl); !ok {
+			g.genStatement(stmt)
+		}
+	}
+	if main != nil {
+		// the result of a main that returns one is ignored
+		returnType := llvmTypes[semantic.TypeOf(main.ReturnType)]
+		g.emit("call %s @%s%s()", returnType, functionPrefix, main.Name.Value)
+	}
+	g.terminate("ret i32 0")
+	g.endFunction(out, "define i32 @main()")
+
+	for _, stmt := range prog.Statements {
+		if fn, ok := stmt.(*ast.FuncDecl); ok {
+			g.genFunction(out, fn)
+		}
+	}
+	for i, str := range g.strs {
+		fmt.Fprintf(out, "@.str.%d = private unnamed_addr constant [%d x i8] c\"%s\\00\"\n", i, len(str)+1, escape(str))
+	}
+	if len(g.strs) > 0 {
+		out.WriteString("\n")
+	}
+	out.WriteString(runtime)
+	return out.String()
+}
+
+// WriteIR writes the IR module of the program to output.
+func WriteIR(prog *ast.Prog, info *semantic.Info, output string) error {
+	return os.WriteFile(output, []byte(Generate(prog, info)), 0644)
+}
+
+func (g *generator) beginFunction(returnType semantic.Type) {
+	g.allocas = &strings.Builder{}
+	g.body = &strings.Builder{}
+	g.slots = make(map[*semantic.Symbol]string)
+	g.names = make(map[string]int)
+	g.returnType = returnType
+	g.terminated = false
+	g.block = "entry"
+}
+
+// endFunction writes the function. The allocas are gathered in the entry
+// block so that loops do not grow the stack.
+func (g *generator) endFunction(out *strings.Builder, header string) {
+	if !g.terminated {
+		if g.returnType == semantic.Void {
+			g.emit("ret void")
+		} else {
+			// the checker guarantees that every path returned
+			g.emit("unreachable")
+		}
+	}
+	fmt.Fprintf(out, "%s {\nentry:\n%s%s}\n\n", header, g.allocas.String(), g.body.String())
+}
+
+// newName returns a local name that is unique in the function.
+func (g *generator) newName(prefix string) string {
+	g.names[prefix]++
+	return fmt.Sprintf("%s%d", prefix, g.names[prefix])
+}
+
+func (g *generator) emit(format string, args ...interface{}) {
+	if g.terminated {
+		// code after a return still needs a block, even if it never runs
+		g.label(g.newName("dead"))
+	}
+	g.body.WriteString("  ")
+	fmt.Fprintf(g.body, format, args...)
+	g.body.WriteByte('\n')
+}
+
+// emitValue emits an instruction that defines a new temporary.
+func (g *generator) emitValue(t semantic.Type, format string, args ...interface{}) value {
+	name := "%" + g.newName("t")
+	g.emit("%s = "+format, append([]interface{}{name}, args...)...)
+	return value{operand: name, t: t}
+}
+
+func (g *generator) terminate(format string, args ...interface{}) {
+	g.emit(format, args...)
+	g.terminated = true
+}
+
+// branch ends the current block with a jump, unless it already ended.
+func (g *generator) branch(target string) {
+	if !g.terminated {
+		g.terminate("br label %%%s", target)
+	}
+}
+
+// label starts a new block, falling through from the current one.
+func (g *generator) label(name string) {
+	g.branch(name)
+	fmt.Fprintf(g.body, "%s:\n", name)
+	g.terminated = false
+	g.block = name
+}
+
+func (g *generator) allocate(sym *semantic.Symbol) string {
+	slot := "%" + g.newName(sym.Name+".addr")
+	g.slots[sym] = slot
+	fmt.Fprintf(g.allocas, "  %s = alloca %s\n", slot, llvmTypes[semantic.TypeOf(sym.Type)])
+	return slot
+}
+
+func (g *generator) address(sym *semantic.Symbol) string {
+	if slot, ok := g.slots[sym]; ok {
+		return slot
+	}
+	return "@" + globalPrefix + sym.Name
+}
+
+func (g *generator) genFunction(out *strings.Builder, fn *ast.FuncDecl) {
+	returnType := semantic.TypeOf(fn.ReturnType)
+	g.beginFunction(returnType)
+	params := make([]string, len(fn.Parameters))
+	for i, param := range fn.Parameters {
+		t := llvmTypes[semantic.TypeOf(param.Type)]
+		params[i] = fmt.Sprintf("%s %%arg.%s", t, param.ID.Value)
+		slot := g.allocate(g.info.Defs[param.ID])
+		g.emit("store %s %%arg.%s, %s* %s", t, param.ID.Value, t, slot)
+	}
+	g.genStatements(fn.Body.Statements)
+	header := fmt.Sprintf("define internal %s @%s%s(%s)",
+		llvmTypes[returnType], functionPrefix, fn.Name.Value, strings.Join(params, ", "))
+	g.endFunction(out, header)
+}
+
+func (g *generator) genStatements(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		g.genStatement(stmt)
+	}
+}
+
+func (g *generator) genStatement(stmt ast.Stmt) {
+	switch node := stmt.(type) {
+	case *ast.DeclStatement:
+		v := g.genExpression(node.Value)
+		sym := g.info.Defs[node.ID]
+		if !sym.Scope.IsGlobal() {
+			g.allocate(sym)
+		}
+		g.store(v, g.address(sym))
+	case *ast.AssignStatement:
+		g.store(g.genExpression(node.Value), g.address(g.info.Uses[node.Target]))
+	case *ast.ReturnStatement:
+		if node.Expr == nil {
+			g.terminate("ret void")
+			return
+		}
+		v := g.genExpression(node.Expr)
+		g.terminate("ret %s %s", llvmTypes[v.t], v.operand)
+	case *ast.ExprStatement:
+		g.genExpression(node.Expr)
+	case *ast.WhileStatement:
+		cond, body, end := g.newName("while.cond"), g.newName("while.body"), g.newName("while.end")
+		g.label(cond)
+		c := g.genExpression(node.Condition)
+		g.terminate("br i1 %s, label %%%s, label %%%s", c.operand, body, end)
+		g.label(body)
+		g.genStatements(node.Body.Statements)
+		g.branch(cond)
+		g.label(end)
+	case *ast.BlockStatement:
+		g.genStatements(node.Statements)
+	}
+}
+
+func (g *generator) store(v value, address string) {
+	t := llvmTypes[v.t]
+	g.emit("store %s %s, %s* %s", t, v.operand, t, address)
+}
+
+func (g *generator) genIf(node *ast.IfExpression) {
+	then, end := g.newName("if.then"), g.newName("if.end")
+	alternative := end
+	if node.FalseBlockCondition != nil {
+		alternative = g.newName("if.else")
+	}
+	c := g.genExpression(node.Condition)
+	g.terminate("br i1 %s, label %%%s, label %%%s", c.operand, then, alternative)
+	g.label(then)
+	g.genStatements(node.TrueBlockCondition.Statements)
+	if node.FalseBlockCondition != nil {
+		g.branch(end)
+		g.label(alternative)
+		g.genStatements(node.FalseBlockCondition.Statements)
+	}
+	g.label(end)
+}
+
+func (g *generator) genExpression(expr ast.Expr) value {
+	switch node := expr.(type) {
+	case *ast.IntegerLiteral:
+		return value{operand: fmt.Sprint(node.Value), t: semantic.Integer}
+	case *ast.DecimalLiteral:
+		return value{operand: fmt.Sprintf("0x%016X", math.Float64bits(node.Value)), t: semantic.Decimal}
+	case *ast.Boolean:
+		return value{operand: fmt.Sprint(node.Value), t: semantic.Boolean}
+	case *ast.StringLiteral:
+		return value{operand: g.literal(node.Value), t: semantic.String}
+	case *ast.CharLiteral:
+		return value{operand: fmt.Sprint(int8(node.Value)), t: semantic.Char}
+	case *ast.InterpolatedString:
+		var str value
+		for i, part := range node.Parts {
+			v := g.genExpression(part)
+			if v.t != semantic.String {
+				v = g.emitValue(semantic.String, "call i8* @%s(%s %s)", stringRoutines[v.t], llvmTypes[v.t], v.operand)
+			}
+			if i == 0 {
+				str = v
+				continue
+			}
+			str = g.emitValue(semantic.String, "call i8* @wbrt_concat(i8* %s, i8* %s)", str.operand, v.operand)
+		}
+		return str
+	case *ast.Identifier:
+		sym := g.info.Uses[node]
+		t := semantic.TypeOf(sym.Type)
+		return g.emitValue(t, "load %s, %s* %s", llvmTypes[t], llvmTypes[t], g.address(sym))
+	case *ast.PrefixExpression:
+		right := g.genExpression(node.Right)
+		switch {
+		case node.Token.Kind == token.NOT:
+			return g.emitValue(semantic.Boolean, "xor i1 %s, true", right.operand)
+		case right.t == semantic.Decimal:
+			return g.emitValue(semantic.Decimal, "fneg double %s", right.operand)
+		}
+		return g.emitValue(semantic.Integer, "sub i64 0, %s", right.operand)
+	case *ast.InfixExpression:
+		if node.Token.Kind == token.AND || node.Token.Kind == token.OR {
+			return g.genLogical(node)
+		}
+		left := g.genExpression(node.Left)
+		right := g.genExpression(node.Right)
+		return g.genInfix(node, left, right)
+	case *ast.CallExpression:
+		return g.genCall(node)
+	case *ast.IfExpression:
+		g.genIf(node)
+	}
+	return value{t: semantic.Void}
+}
+
+// literal returns a pointer to the first byte of a string constant.
+func (g *generator) literal(str string) string {
+	index := len(g.strs)
+	for i, s := range g.strs {
+		if s == str {
+			index = i
+		}
+	}
+	if index == len(g.strs) {
+		g.strs = append(g.strs, str)
+	}
+	array := fmt.Sprintf("[%d x i8]", len(str)+1)
+	return fmt.Sprintf("getelementptr inbounds (%s, %s* @.str.%d, i64 0, i64 0)", array, array, index)
+}
+
+// escape returns the bytes of a string in the syntax of LLVM, with quotes,
+// backslashes and the bytes that are not printable in hexadecimal.
+func escape(str string) string {
+	var b strings.Builder
+	for i := 0; i < len(str); i++ {
+		if c := str[i]; c < ' ' || c > '~' || c == '"' || c == '\\' {
+			fmt.Fprintf(&b, "\\%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+var (
+	integerInstructions = map[token.Kind]string{
+		token.OP_PLUS:  "add",
+		token.OP_MINUS: "sub",
+		token.OP_MULTI: "mul",
+	}
+
+	decimalInstructions = map[token.Kind]string{
+		token.OP_PLUS:   "fadd",
+		token.OP_MINUS:  "fsub",
+		token.OP_MULTI:  "fmul",
+		token.OP_DIVIDE: "fdiv",
+	}
+
+	// chars are ordered by their unsigned code
+	signedPredicates = map[token.Kind]string{
+		token.OP_EQ: "eq", token.OP_NOTEQ: "ne",
+		token.OP_LT: "slt", token.OP_LTE: "sle", token.OP_GT: "sgt", token.OP_GTE: "sge",
+	}
+	unsignedPredicates = map[token.Kind]string{
+		token.OP_EQ: "eq", token.OP_NOTEQ: "ne",
+		token.OP_LT: "ult", token.OP_LTE: "ule", token.OP_GT: "ugt", token.OP_GTE: "uge",
+	}
+	// ordered predicates are false when an operand is NaN, != is true
+	decimalPredicates = map[token.Kind]string{
+		token.OP_EQ: "oeq", token.OP_NOTEQ: "une",
+		token.OP_LT: "olt", token.OP_LTE: "ole", token.OP_GT: "ogt", token.OP_GTE: "oge",
+	}
+)
+
+func (g *generator) genInfix(node *ast.InfixExpression, left, right value) value {
+	kind := node.Token.Kind
+	t := llvmTypes[left.t]
+	switch left.t {
+	case semantic.Decimal:
+		if op, ok := decimalInstructions[kind]; ok {
+			return g.emitValue(semantic.Decimal, "%s double %s, %s", op, left.operand, right.operand)
+		}
+		return g.emitValue(semantic.Boolean, "fcmp %s double %s, %s", decimalPredicates[kind], left.operand, right.operand)
+	case semantic.Integer:
+		if op, ok := integerInstructions[kind]; ok {
+			return g.emitValue(semantic.Integer, "%s i64 %s, %s", op, left.operand, right.operand)
+		}
+		if kind == token.OP_DIVIDE {
+			return g.genDivision(node, left, right)
+		}
+	case semantic.Char:
+		return g.emitValue(semantic.Boolean, "icmp %s i8 %s, %s", unsignedPredicates[kind], left.operand, right.operand)
+	case semantic.String:
+		order := g.emitValue(semantic.Integer, "call i32 @strcmp(i8* %s, i8* %s)", left.operand, right.operand)
+		return g.emitValue(semantic.Boolean, "icmp %s i32 %s, 0", signedPredicates[kind], order.operand)
+	}
+	return g.emitValue(semantic.Boolean, "icmp %s %s %s, %s", signedPredicates[kind], t, left.operand, right.operand)
+}
+
+// genLogical branches over the right operand of && and || when the left
+// one decides the result, which a phi then takes from the left block.
+func (g *generator) genLogical(node *ast.InfixExpression) value {
+	rhs, end := g.newName("logical.rhs"), g.newName("logical.end")
+	left := g.genExpression(node.Left)
+	from := g.block
+	if node.Token.Kind == token.AND {
+		g.terminate("br i1 %s, label %%%s, label %%%s", left.operand, rhs, end)
+	} else {
+		g.terminate("br i1 %s, label %%%s, label %%%s", left.operand, end, rhs)
+	}
+	g.label(rhs)
+	right := g.genExpression(node.Right)
+	to := g.block
+	g.label(end)
+	return g.emitValue(semantic.Boolean, "phi i1 [ %s, %%%s ], [ %s, %%%s ]", left.operand, from, right.operand, to)
+}
+
+// genDivision checks the divisor, since division by zero is a runtime error,
+// and makes the division of the smallest integer by -1 wrap around instead
+// of being undefined.
+func (g *generator) genDivision(node *ast.InfixExpression, left, right value) value {
+	fail, ok := g.newName("div.zero"), g.newName("div.ok")
+	zero := g.emitValue(semantic.Boolean, "icmp eq i64 %s, 0", right.operand)
+	g.terminate("br i1 %s, label %%%s, label %%%s", zero.operand, fail, ok)
+	g.label(fail)
+	pos := node.Token.Position
+	g.emit("call void @wbrt_division_by_zero(i32 %d, i32 %d)", pos.Line, pos.Column)
+	g.terminate("unreachable")
+	g.label(ok)
+	minusOne := g.emitValue(semantic.Boolean, "icmp eq i64 %s, -1", right.operand)
+	divisor := g.emitValue(semantic.Integer, "select i1 %s, i64 1, i64 %s", minusOne.operand, right.operand)
+	quotient := g.emitValue(semantic.Integer, "sdiv i64 %s, %s", left.operand, divisor.operand)
+	negated := g.emitValue(semantic.Integer, "sub i64 0, %s", left.operand)
+	return g.emitValue(semantic.Integer, "select i1 %s, i64 %s, i64 %s", minusOne.operand, negated.operand, quotient.operand)
+}
+
+func (g *generator) genCall(call *ast.CallExpression) value {
+	id := call.Function.(*ast.Identifier)
+	args := make([]string, len(call.Arguments))
+	for i, arg := range call.Arguments {
+		v := g.genExpression(arg)
+		args[i] = llvmTypes[v.t] + " " + v.operand
+	}
+	sym := g.info.Uses[id]
+	if sym.Kind == semantic.BUILTIN {
+		g.emit("call void @%s(%s)", printRoutines[g.info.TypeOf(call.Arguments[0])], args[0])
+		return value{t: semantic.Void}
+	}
+	returnType := semantic.TypeOf(sym.Type)
+	if returnType == semantic.Void {
+		g.emit("call void @%s%s(%s)", functionPrefix, id.Value, strings.Join(args, ", "))
+		return value{t: semantic.Void}
+	}
+	return g.emitValue(returnType, "call %s @%s%s(%s)",
+		llvmTypes[returnType], functionPrefix, id.Value, strings.Join(args, ", "))
+}
+
+// runtime implements print and the conversions to String on top of the C
+// library. Decimals are printed like %g, except that every NaN prints as
+// nan. Strings are NUL-terminated, and those built at run time are
+// allocated with malloc and never freed.
+const runtime = `@.fmt.integer = private unnamed_addr constant [6 x i8] c"%lld\0A\00"
+@.fmt.decimal = private unnamed_addr constant [4 x i8] c"%g\0A\00"
+@.fmt.char = private unnamed_addr constant [4 x i8] c"%c\0A\00"
+@.fmt.integer.string = private unnamed_addr constant [5 x i8] c"%lld\00"
+@.fmt.decimal.string = private unnamed_addr constant [3 x i8] c"%g\00"
+@.fmt.concat = private unnamed_addr constant [5 x i8] c"%s%s\00"
+@.str.true = private unnamed_addr constant [5 x i8] c"true\00"
+@.str.false = private unnamed_addr constant [6 x i8] c"false\00"
+@.str.nan = private unnamed_addr constant [4 x i8] c"nan\00"
+@.fmt.division_by_zero = private unnamed_addr constant [48 x i8] c"%d:%d: runtime error. integer division by zero\0A\00"
+
+declare i32 @printf(i8*, ...)
+declare i32 @puts(i8*)
+declare i32 @strcmp(i8*, i8*)
+declare i32 @snprintf(i8*, i64, i8*, ...)
+declare i8* @malloc(i64)
+declare i32 @dprintf(i32, i8*, ...)
+declare void @exit(i32)
+
+define internal void @wbrt_print_integer(i64 %v) {
+  %fmt = getelementptr inbounds [6 x i8], [6 x i8]* @.fmt.integer, i64 0, i64 0
+  call i32 (i8*, ...) @printf(i8* %fmt, i64 %v)
+  ret void
+}
+
+define internal void @wbrt_print_decimal(double %v) {
+  %nan = fcmp uno double %v, %v
+  br i1 %nan, label %print.nan, label %print.number
+print.nan:
+  %str = getelementptr inbounds [4 x i8], [4 x i8]* @.str.nan, i64 0, i64 0
+  call i32 @puts(i8* %str)
+  ret void
+print.number:
+  %fmt = getelementptr inbounds [4 x i8], [4 x i8]* @.fmt.decimal, i64 0, i64 0
+  call i32 (i8*, ...) @printf(i8* %fmt, double %v)
+  ret void
+}
+
+define internal void @wbrt_print_char(i8 %v) {
+  %fmt = getelementptr inbounds [4 x i8], [4 x i8]* @.fmt.char, i64 0, i64 0
+  %c = zext i8 %v to i32
+  call i32 (i8*, ...) @printf(i8* %fmt, i32 %c)
+  ret void
+}
+
+define internal void @wbrt_print_boolean(i1 %v) {
+  %true = getelementptr inbounds [5 x i8], [5 x i8]* @.str.true, i64 0, i64 0
+  %false = getelementptr inbounds [6 x i8], [6 x i8]* @.str.false, i64 0, i64 0
+  %str = select i1 %v, i8* %true, i8* %false
+  call i32 @puts(i8* %str)
+  ret void
+}
+
+define internal void @wbrt_print_string(i8* %v) {
+  call i32 @puts(i8* %v)
+  ret void
+}
+
+define internal i8* @wbrt_string_integer(i64 %v) {
+  %fmt = getelementptr inbounds [5 x i8], [5 x i8]* @.fmt.integer.string, i64 0, i64 0
+  %n = call i32 (i8*, i64, i8*, ...) @snprintf(i8* null, i64 0, i8* %fmt, i64 %v)
+  %size = call i64 @wbrt_size(i32 %n)
+  %str = call i8* @malloc(i64 %size)
+  call i32 (i8*, i64, i8*, ...) @snprintf(i8* %str, i64 %size, i8* %fmt, i64 %v)
+  ret i8* %str
+}
+
+define internal i8* @wbrt_string_decimal(double %v) {
+  %nan = fcmp uno double %v, %v
+  br i1 %nan, label %string.nan, label %string.number
+string.nan:
+  %str.nan = getelementptr inbounds [4 x i8], [4 x i8]* @.str.nan, i64 0, i64 0
+  ret i8* %str.nan
+string.number:
+  %fmt = getelementptr inbounds [3 x i8], [3 x i8]* @.fmt.decimal.string, i64 0, i64 0
+  %n = call i32 (i8*, i64, i8*, ...) @snprintf(i8* null, i64 0, i8* %fmt, double %v)
+  %size = call i64 @wbrt_size(i32 %n)
+  %str = call i8* @malloc(i64 %size)
+  call i32 (i8*, i64, i8*, ...) @snprintf(i8* %str, i64 %size, i8* %fmt, double %v)
+  ret i8* %str
+}
+
+define internal i8* @wbrt_string_char(i8 %v) {
+  %str = call i8* @malloc(i64 2)
+  store i8 %v, i8* %str
+  %end = getelementptr inbounds i8, i8* %str, i64 1
+  store i8 0, i8* %end
+  ret i8* %str
+}
+
+define internal i8* @wbrt_string_boolean(i1 %v) {
+  %true = getelementptr inbounds [5 x i8], [5 x i8]* @.str.true, i64 0, i64 0
+  %false = getelementptr inbounds [6 x i8], [6 x i8]* @.str.false, i64 0, i64 0
+  %str = select i1 %v, i8* %true, i8* %false
+  ret i8* %str
+}
+
+define internal i8* @wbrt_concat(i8* %a, i8* %b) {
+  %fmt = getelementptr inbounds [5 x i8], [5 x i8]* @.fmt.concat, i64 0, i64 0
+  %n = call i32 (i8*, i64, i8*, ...) @snprintf(i8* null, i64 0, i8* %fmt, i8* %a, i8* %b)
+  %size = call i64 @wbrt_size(i32 %n)
+  %str = call i8* @malloc(i64 %size)
+  call i32 (i8*, i64, i8*, ...) @snprintf(i8* %str, i64 %size, i8* %fmt, i8* %a, i8* %b)
+  ret i8* %str
+}
+
+; returns the size of a string of n bytes with its terminating NUL
+define internal i64 @wbrt_size(i32 %n) {
+  %length = sext i32 %n to i64
+  %size = add i64 %length, 1
+  ret i64 %size
+}
+
+; reports the error and exits with the status of runtime errors
+define internal void @wbrt_division_by_zero(i32 %line, i32 %column) noreturn {
+  %fmt = getelementptr inbounds [48 x i8], [48 x i8]* @.fmt.division_by_zero, i64 0, i64 0
+  call i32 (i32, i8*, ...) @dprintf(i32 2, i8* %fmt, i32 %line, i32 %column)
+  call void @exit(i32 3)
+  unreachable
+}
+`
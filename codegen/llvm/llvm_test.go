@@ -0,0 +1,95 @@
+package llvm
+
+import (
+	"flag"
+	"github.com/wevertonbruno/wb-compiler/wbtest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestGolden(t *testing.T) {
+	sources, err := filepath.Glob(filepath.Join("testdata", "*.wb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, source := range sources {
+		input, err := os.ReadFile(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prog, info := wbtest.Check(t, string(input))
+		ir := Generate(prog, info)
+
+		golden := strings.TrimSuffix(source, ".wb") + ".ll"
+		if *update {
+			if err := os.WriteFile(golden, []byte(ir), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		expected, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ir != string(expected) {
+			t.Errorf("%s does not match %s. got=\n%s", source, golden, ir)
+		}
+	}
+}
+
+// TestExecute runs the generated modules with lli, when LLVM is installed.
+func TestExecute(t *testing.T) {
+	for _, tool := range []string{"llvm-as", "lli"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s is not installed", tool)
+		}
+	}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{readFile(t, "testdata/fib.wb"), "55\n"},
+		{readFile(t, "testdata/control.wb"), "1\n"},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		stdout, err := execute(t, dir, tt.input)
+		if err != nil {
+			t.Errorf("program %q failed: %v", tt.input, err)
+			continue
+		}
+		if stdout != tt.expected {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", tt.input, tt.expected, stdout)
+		}
+	}
+	wbtest.RunPrograms(t, func(t *testing.T, source string) (string, error) {
+		return execute(t, dir, source)
+	})
+}
+
+func execute(t *testing.T, dir, input string) (string, error) {
+	t.Helper()
+	prog, info := wbtest.Check(t, input)
+	ir := filepath.Join(dir, "prog.ll")
+	bitcode := filepath.Join(dir, "prog.bc")
+	if err := WriteIR(prog, info, ir); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("llvm-as", "-o", bitcode, ir).CombinedOutput(); err != nil {
+		t.Fatalf("invalid module for %q: %v\n%s", input, err, out)
+	}
+	return wbtest.Exec(exec.Command("lli", bitcode))
+}
+
+func readFile(t *testing.T, name string) string {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
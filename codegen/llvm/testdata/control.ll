@@ -0,0 +1,175 @@
+@wbg_limit = internal global i64 0
+
+define i32 @main() {
+entry:
+  store i64 3, i64* @wbg_limit
+  call void @wb_count(double 0x3FE0000000000000)
+  ret i32 0
+}
+
+define internal void @wb_count(double %arg.step) {
+entry:
+  %step.addr1 = alloca double
+  %i.addr1 = alloca i64
+  %total.addr1 = alloca double
+  %i.addr2 = alloca i64
+  store double %arg.step, double* %step.addr1
+  store i64 0, i64* %i.addr1
+  store double 0x0000000000000000, double* %total.addr1
+  br label %while.cond1
+while.cond1:
+  %t1 = load i64, i64* %i.addr1
+  %t2 = load i64, i64* @wbg_limit
+  %t3 = icmp slt i64 %t1, %t2
+  br i1 %t3, label %while.body1, label %while.end1
+while.body1:
+  %t4 = load i64, i64* @wbg_limit
+  store i64 %t4, i64* %i.addr2
+  %t5 = load double, double* %total.addr1
+  %t6 = load double, double* %step.addr1
+  %t7 = fcmp oge double %t5, %t6
+  br i1 %t7, label %if.then1, label %if.else1
+if.then1:
+  call void @wbrt_print_boolean(i1 true)
+  br label %if.end1
+if.else1:
+  %t8 = load i64, i64* %i.addr2
+  %t9 = icmp eq i64 2, 0
+  br i1 %t9, label %div.zero1, label %div.ok1
+div.zero1:
+  call void @wbrt_division_by_zero(i32 11, i32 21)
+  unreachable
+div.ok1:
+  %t10 = icmp eq i64 2, -1
+  %t11 = select i1 %t10, i64 1, i64 2
+  %t12 = sdiv i64 %t8, %t11
+  %t13 = sub i64 0, %t8
+  %t14 = select i1 %t10, i64 %t13, i64 %t12
+  call void @wbrt_print_integer(i64 %t14)
+  br label %if.end1
+if.end1:
+  ret void
+while.end1:
+  ret void
+}
+
+@.fmt.integer = private unnamed_addr constant [6 x i8] c"%lld\0A\00"
+@.fmt.decimal = private unnamed_addr constant [4 x i8] c"%g\0A\00"
+@.fmt.char = private unnamed_addr constant [4 x i8] c"%c\0A\00"
+@.fmt.integer.string = private unnamed_addr constant [5 x i8] c"%lld\00"
+@.fmt.decimal.string = private unnamed_addr constant [3 x i8] c"%g\00"
+@.fmt.concat = private unnamed_addr constant [5 x i8] c"%s%s\00"
+@.str.true = private unnamed_addr constant [5 x i8] c"true\00"
+@.str.false = private unnamed_addr constant [6 x i8] c"false\00"
+@.str.nan = private unnamed_addr constant [4 x i8] c"nan\00"
+@.fmt.division_by_zero = private unnamed_addr constant [48 x i8] c"%d:%d: runtime error. integer division by zero\0A\00"
+
+declare i32 @printf(i8*, ...)
+declare i32 @puts(i8*)
+declare i32 @strcmp(i8*, i8*)
+declare i32 @snprintf(i8*, i64, i8*, ...)
+declare i8* @malloc(i64)
+declare i32 @dprintf(i32, i8*, ...)
+declare void @exit(i32)
+
+define internal void @wbrt_print_integer(i64 %v) {
+  %fmt = getelementptr inbounds [6 x i8], [6 x i8]* @.fmt.integer, i64 0, i64 0
+  call i32 (i8*, ...) @printf(i8* %fmt, i64 %v)
+  ret void
+}
+
+define internal void @wbrt_print_decimal(double %v) {
+  %nan = fcmp uno double %v, %v
+  br i1 %nan, label %print.nan, label %print.number
+print.nan:
+  %str = getelementptr inbounds [4 x i8], [4 x i8]* @.str.nan, i64 0, i64 0
+  call i32 @puts(i8* %str)
+  ret void
+print.number:
+  %fmt = getelementptr inbounds [4 x i8], [4 x i8]* @.fmt.decimal, i64 0, i64 0
+  call i32 (i8*, ...) @printf(i8* %fmt, double %v)
+  ret void
+}
+
+define internal void @wbrt_print_char(i8 %v) {
+  %fmt = getelementptr inbounds [4 x i8], [4 x i8]* @.fmt.char, i64 0, i64 0
+  %c = zext i8 %v to i32
+  call i32 (i8*, ...) @printf(i8* %fmt, i32 %c)
+  ret void
+}
+
+define internal void @wbrt_print_boolean(i1 %v) {
+  %true = getelementptr inbounds [5 x i8], [5 x i8]* @.str.true, i64 0, i64 0
+  %false = getelementptr inbounds [6 x i8], [6 x i8]* @.str.false, i64 0, i64 0
+  %str = select i1 %v, i8* %true, i8* %false
+  call i32 @puts(i8* %str)
+  ret void
+}
+
+define internal void @wbrt_print_string(i8* %v) {
+  call i32 @puts(i8* %v)
+  ret void
+}
+
+define internal i8* @wbrt_string_integer(i64 %v) {
+  %fmt = getelementptr inbounds [5 x i8], [5 x i8]* @.fmt.integer.string, i64 0, i64 0
+  %n = call i32 (i8*, i64, i8*, ...) @snprintf(i8* null, i64 0, i8* %fmt, i64 %v)
+  %size = call i64 @wbrt_size(i32 %n)
+  %str = call i8* @malloc(i64 %size)
+  call i32 (i8*, i64, i8*, ...) @snprintf(i8* %str, i64 %size, i8* %fmt, i64 %v)
+  ret i8* %str
+}
+
+define internal i8* @wbrt_string_decimal(double %v) {
+  %nan = fcmp uno double %v, %v
+  br i1 %nan, label %string.nan, label %string.number
+string.nan:
+  %str.nan = getelementptr inbounds [4 x i8], [4 x i8]* @.str.nan, i64 0, i64 0
+  ret i8* %str.nan
+string.number:
+  %fmt = getelementptr inbounds [3 x i8], [3 x i8]* @.fmt.decimal.string, i64 0, i64 0
+  %n = call i32 (i8*, i64, i8*, ...) @snprintf(i8* null, i64 0, i8* %fmt, double %v)
+  %size = call i64 @wbrt_size(i32 %n)
+  %str = call i8* @malloc(i64 %size)
+  call i32 (i8*, i64, i8*, ...) @snprintf(i8* %str, i64 %size, i8* %fmt, double %v)
+  ret i8* %str
+}
+
+define internal i8* @wbrt_string_char(i8 %v) {
+  %str = call i8* @malloc(i64 2)
+  store i8 %v, i8* %str
+  %end = getelementptr inbounds i8, i8* %str, i64 1
+  store i8 0, i8* %end
+  ret i8* %str
+}
+
+define internal i8* @wbrt_string_boolean(i1 %v) {
+  %true = getelementptr inbounds [5 x i8], [5 x i8]* @.str.true, i64 0, i64 0
+  %false = getelementptr inbounds [6 x i8], [6 x i8]* @.str.false, i64 0, i64 0
+  %str = select i1 %v, i8* %true, i8* %false
+  ret i8* %str
+}
+
+define internal i8* @wbrt_concat(i8* %a, i8* %b) {
+  %fmt = getelementptr inbounds [5 x i8], [5 x i8]* @.fmt.concat, i64 0, i64 0
+  %n = call i32 (i8*, i64, i8*, ...) @snprintf(i8* null, i64 0, i8* %fmt, i8* %a, i8* %b)
+  %size = call i64 @wbrt_size(i32 %n)
+  %str = call i8* @malloc(i64 %size)
+  call i32 (i8*, i64, i8*, ...) @snprintf(i8* %str, i64 %size, i8* %fmt, i8* %a, i8* %b)
+  ret i8* %str
+}
+
+; returns the size of a string of n bytes with its terminating NUL
+define internal i64 @wbrt_size(i32 %n) {
+  %length = sext i32 %n to i64
+  %size = add i64 %length, 1
+  ret i64 %size
+}
+
+; reports the error and exits with the status of runtime errors
+define internal void @wbrt_division_by_zero(i32 %line, i32 %column) noreturn {
+  %fmt = getelementptr inbounds [48 x i8], [48 x i8]* @.fmt.division_by_zero, i64 0, i64 0
+  call i32 (i32, i8*, ...) @dprintf(i32 2, i8* %fmt, i32 %line, i32 %column)
+  call void @exit(i32 3)
+  unreachable
+}
@@ -0,0 +1,154 @@
+define i32 @main() {
+entry:
+  call void @wb_main()
+  ret i32 0
+}
+
+define internal i64 @wb_fib(i64 %arg.n) {
+entry:
+  %n.addr1 = alloca i64
+  store i64 %arg.n, i64* %n.addr1
+  %t1 = load i64, i64* %n.addr1
+  %t2 = icmp slt i64 %t1, 2
+  br i1 %t2, label %if.then1, label %if.end1
+if.then1:
+  %t3 = load i64, i64* %n.addr1
+  ret i64 %t3
+if.end1:
+  %t4 = load i64, i64* %n.addr1
+  %t5 = sub i64 %t4, 1
+  %t6 = call i64 @wb_fib(i64 %t5)
+  %t7 = load i64, i64* %n.addr1
+  %t8 = sub i64 %t7, 2
+  %t9 = call i64 @wb_fib(i64 %t8)
+  %t10 = add i64 %t6, %t9
+  ret i64 %t10
+}
+
+define internal void @wb_main() {
+entry:
+  %t1 = call i64 @wb_fib(i64 10)
+  call void @wbrt_print_integer(i64 %t1)
+  ret void
+}
+
+@.fmt.integer = private unnamed_addr constant [6 x i8] c"%lld\0A\00"
+@.fmt.decimal = private unnamed_addr constant [4 x i8] c"%g\0A\00"
+@.fmt.char = private unnamed_addr constant [4 x i8] c"%c\0A\00"
+@.fmt.integer.string = private unnamed_addr constant [5 x i8] c"%lld\00"
+@.fmt.decimal.string = private unnamed_addr constant [3 x i8] c"%g\00"
+@.fmt.concat = private unnamed_addr constant [5 x i8] c"%s%s\00"
+@.str.true = private unnamed_addr constant [5 x i8] c"true\00"
+@.str.false = private unnamed_addr constant [6 x i8] c"false\00"
+@.str.nan = private unnamed_addr constant [4 x i8] c"nan\00"
+@.fmt.division_by_zero = private unnamed_addr constant [48 x i8] c"%d:%d: runtime error. integer division by zero\0A\00"
+
+declare i32 @printf(i8*, ...)
+declare i32 @puts(i8*)
+declare i32 @strcmp(i8*, i8*)
+declare i32 @snprintf(i8*, i64, i8*, ...)
+declare i8* @malloc(i64)
+declare i32 @dprintf(i32, i8*, ...)
+declare void @exit(i32)
+
+define internal void @wbrt_print_integer(i64 %v) {
+  %fmt = getelementptr inbounds [6 x i8], [6 x i8]* @.fmt.integer, i64 0, i64 0
+  call i32 (i8*, ...) @printf(i8* %fmt, i64 %v)
+  ret void
+}
+
+define internal void @wbrt_print_decimal(double %v) {
+  %nan = fcmp uno double %v, %v
+  br i1 %nan, label %print.nan, label %print.number
+print.nan:
+  %str = getelementptr inbounds [4 x i8], [4 x i8]* @.str.nan, i64 0, i64 0
+  call i32 @puts(i8* %str)
+  ret void
+print.number:
+  %fmt = getelementptr inbounds [4 x i8], [4 x i8]* @.fmt.decimal, i64 0, i64 0
+  call i32 (i8*, ...) @printf(i8* %fmt, double %v)
+  ret void
+}
+
+define internal void @wbrt_print_char(i8 %v) {
+  %fmt = getelementptr inbounds [4 x i8], [4 x i8]* @.fmt.char, i64 0, i64 0
+  %c = zext i8 %v to i32
+  call i32 (i8*, ...) @printf(i8* %fmt, i32 %c)
+  ret void
+}
+
+define internal void @wbrt_print_boolean(i1 %v) {
+  %true = getelementptr inbounds [5 x i8], [5 x i8]* @.str.true, i64 0, i64 0
+  %false = getelementptr inbounds [6 x i8], [6 x i8]* @.str.false, i64 0, i64 0
+  %str = select i1 %v, i8* %true, i8* %false
+  call i32 @puts(i8* %str)
+  ret void
+}
+
+define internal void @wbrt_print_string(i8* %v) {
+  call i32 @puts(i8* %v)
+  ret void
+}
+
+define internal i8* @wbrt_string_integer(i64 %v) {
+  %fmt = getelementptr inbounds [5 x i8], [5 x i8]* @.fmt.integer.string, i64 0, i64 0
+  %n = call i32 (i8*, i64, i8*, ...) @snprintf(i8* null, i64 0, i8* %fmt, i64 %v)
+  %size = call i64 @wbrt_size(i32 %n)
+  %str = call i8* @malloc(i64 %size)
+  call i32 (i8*, i64, i8*, ...) @snprintf(i8* %str, i64 %size, i8* %fmt, i64 %v)
+  ret i8* %str
+}
+
+define internal i8* @wbrt_string_decimal(double %v) {
+  %nan = fcmp uno double %v, %v
+  br i1 %nan, label %string.nan, label %string.number
+string.nan:
+  %str.nan = getelementptr inbounds [4 x i8], [4 x i8]* @.str.nan, i64 0, i64 0
+  ret i8* %str.nan
+string.number:
+  %fmt = getelementptr inbounds [3 x i8], [3 x i8]* @.fmt.decimal.string, i64 0, i64 0
+  %n = call i32 (i8*, i64, i8*, ...) @snprintf(i8* null, i64 0, i8* %fmt, double %v)
+  %size = call i64 @wbrt_size(i32 %n)
+  %str = call i8* @malloc(i64 %size)
+  call i32 (i8*, i64, i8*, ...) @snprintf(i8* %str, i64 %size, i8* %fmt, double %v)
+  ret i8* %str
+}
+
+define internal i8* @wbrt_string_char(i8 %v) {
+  %str = call i8* @malloc(i64 2)
+  store i8 %v, i8* %str
+  %end = getelementptr inbounds i8, i8* %str, i64 1
+  store i8 0, i8* %end
+  ret i8* %str
+}
+
+define internal i8* @wbrt_string_boolean(i1 %v) {
+  %true = getelementptr inbounds [5 x i8], [5 x i8]* @.str.true, i64 0, i64 0
+  %false = getelementptr inbounds [6 x i8], [6 x i8]* @.str.false, i64 0, i64 0
+  %str = select i1 %v, i8* %true, i8* %false
+  ret i8* %str
+}
+
+define internal i8* @wbrt_concat(i8* %a, i8* %b) {
+  %fmt = getelementptr inbounds [5 x i8], [5 x i8]* @.fmt.concat, i64 0, i64 0
+  %n = call i32 (i8*, i64, i8*, ...) @snprintf(i8* null, i64 0, i8* %fmt, i8* %a, i8* %b)
+  %size = call i64 @wbrt_size(i32 %n)
+  %str = call i8* @malloc(i64 %size)
+  call i32 (i8*, i64, i8*, ...) @snprintf(i8* %str, i64 %size, i8* %fmt, i8* %a, i8* %b)
+  ret i8* %str
+}
+
+; returns the size of a string of n bytes with its terminating NUL
+define internal i64 @wbrt_size(i32 %n) {
+  %length = sext i32 %n to i64
+  %size = add i64 %length, 1
+  ret i64 %size
+}
+
+; reports the error and exits with the status of runtime errors
+define internal void @wbrt_division_by_zero(i32 %line, i32 %column) noreturn {
+  %fmt = getelementptr inbounds [48 x i8], [48 x i8]* @.fmt.division_by_zero, i64 0, i64 0
+  call i32 (i32, i8*, ...) @dprintf(i32 2, i8* %fmt, i32 %line, i32 %column)
+  call void @exit(i32 3)
+  unreachable
+}
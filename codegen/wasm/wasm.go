@@ -0,0 +1,636 @@
+// Package wasm encodes checked programs as WebAssembly binary modules.
+//
+// The module imports its output routines from the host, under the module
+// name "env", and exports "_start", which runs the top level statements and
+// then calls main. See wbrt.js for a host that runs modules with Node.js.
+//
+// Strings are addresses of NUL-terminated bytes in the linear memory, which
+// the module exports as "memory" when the program has string literals or
+// interpolates strings. The host allocates the strings built at run time
+// past the initial memory.
+package wasm
+
+import (
+	"encoding/binary"
+	"github.com/wevertonbruno/wb-compiler/analyzers/semantic"
+	"github.com/wevertonbruno/wb-compiler/analyzers/token"
+	"github.com/wevertonbruno/wb-compiler/ast"
+	"math"
+	"os"
+)
+
+const (
+	entryPoint   = "main"
+	startExport  = "_start"
+	memoryExport = "memory"
+	hostModule   = "env"
+
+	pageSize = 65536
+)
+
+// value types
+const (
+	i32 byte = 0x7F
+	i64 byte = 0x7E
+	f64 byte = 0x7C
+
+	emptyBlock byte = 0x40
+)
+
+// section ids
+const (
+	typeSection     byte = 1
+	importSection   byte = 2
+	functionSection byte = 3
+	memorySection   byte = 5
+	globalSection   byte = 6
+	exportSection   byte = 7
+	codeSection     byte = 10
+	dataSection     byte = 11
+)
+
+// export kinds
+const (
+	functionKind byte = 0x00
+	memoryKind   byte = 0x02
+)
+
+// instructions
+const (
+	opUnreachable byte = 0x00
+	opBlock       byte = 0x02
+	opLoop        byte = 0x03
+	opIf          byte = 0x04
+	opElse        byte = 0x05
+	opEnd         byte = 0x0B
+	opBr          byte = 0x0C
+	opBrIf        byte = 0x0D
+	opReturn      byte = 0x0F
+	opCall        byte = 0x10
+	opDrop        byte = 0x1A
+
+	opLocalGet  byte = 0x20
+	opLocalSet  byte = 0x21
+	opLocalTee  byte = 0x22
+	opGlobalGet byte = 0x23
+	opGlobalSet byte = 0x24
+
+	opI32Const byte = 0x41
+	opI64Const byte = 0x42
+	opF64Const byte = 0x44
+
+	opI32Eqz byte = 0x45
+	opI64Eqz byte = 0x50
+	opI64Eq  byte = 0x51
+
+	opI64Sub  byte = 0x7D
+	opI64DivS byte = 0x7F
+	opF64Neg  byte = 0x9A
+)
+
+var (
+	valueTypes = map[semantic.Type]byte{
+		semantic.Integer: i64,
+		semantic.Decimal: f64,
+		semantic.Char:    i32,
+		semantic.Boolean: i32,
+		semantic.String:  i32,
+	}
+
+	// imports lists the host functions, their index is their position
+	imports = []struct {
+		name    string
+		params  []byte
+		results []byte
+	}{
+		{"print_integer", []byte{i64}, nil},
+		{"print_decimal", []byte{f64}, nil},
+		{"print_char", []byte{i32}, nil},
+		{"print_boolean", []byte{i32}, nil},
+		{"division_by_zero", []byte{i32, i32}, nil},
+		{"print_string", []byte{i32}, nil},
+		{"equal_strings", []byte{i32, i32}, []byte{i32}},
+		{"string_integer", []byte{i64}, []byte{i32}},
+		{"string_decimal", []byte{f64}, []byte{i32}},
+		{"string_char", []byte{i32}, []byte{i32}},
+		{"string_boolean", []byte{i32}, []byte{i32}},
+		{"concat", []byte{i32, i32}, []byte{i32}},
+	}
+
+	printImports = map[semantic.Type]uint32{
+		semantic.Integer: 0,
+		semantic.Decimal: 1,
+		semantic.Char:    2,
+		semantic.Boolean: 3,
+		semantic.String:  5,
+	}
+	stringImports = map[semantic.Type]uint32{
+		semantic.Integer: 7,
+		semantic.Decimal: 8,
+		semantic.Char:    9,
+		semantic.Boolean: 10,
+	}
+	divisionByZeroImport uint32 = 4
+	equalStringsImport   uint32 = 6
+	concatImport         uint32 = 11
+
+	integerInstructions = map[token.Kind]byte{
+		token.OP_PLUS: 0x7C, token.OP_MINUS: 0x7D, token.OP_MULTI: 0x7E,
+		token.OP_EQ: 0x51, token.OP_NOTEQ: 0x52,
+		token.OP_LT: 0x53, token.OP_GT: 0x55, token.OP_LTE: 0x57, token.OP_GTE: 0x59,
+	}
+	decimalInstructions = map[token.Kind]byte{
+		token.OP_PLUS: 0xA0, token.OP_MINUS: 0xA1, token.OP_MULTI: 0xA2, token.OP_DIVIDE: 0xA3,
+		token.OP_EQ: 0x61, token.OP_NOTEQ: 0x62,
+		token.OP_LT: 0x63, token.OP_GT: 0x64, token.OP_LTE: 0x65, token.OP_GTE: 0x66,
+	}
+	// chars are ordered by their unsigned code
+	smallInstructions = map[token.Kind]byte{
+		token.OP_EQ: 0x46, token.OP_NOTEQ: 0x47,
+		token.OP_LT: 0x49, token.OP_GT: 0x4B, token.OP_LTE: 0x4D, token.OP_GTE: 0x4F,
+	}
+)
+
+type (
+	generator struct {
+		info      *semantic.Info
+		types     [][]byte // encoded function types
+		functions map[*semantic.Symbol]uint32
+		globals   map[*semantic.Symbol]uint32
+		// data holds the string literals, after a NUL byte at address
+		// zero that makes the zero value of strings the empty string
+		data      []byte
+		addresses map[string]uint32
+		// interpolates is set when the program builds strings, which
+		// needs the memory even without literals
+		interpolates bool
+
+		// state of the function being generated
+		code   []byte
+		locals map[*semantic.Symbol]uint32
+		// types of the locals, after the parameters
+		localTypes []byte
+		params     int
+	}
+)
+
+// Generate returns the binary module of a checked program.
+func Generate(prog *ast.Prog, info *semantic.Info) []byte {
+	g := &generator{
+		info:      info,
+		functions: make(map[*semantic.Symbol]uint32),
+		globals:   make(map[*semantic.Symbol]uint32),
+		data:      []byte{0},
+		addresses: map[string]uint32{"": 0},
+	}
+
+	var imported, declared, globals []byte
+	for _, imp := range imports {
+		imported = appendName(imported, hostModule)
+		imported = appendName(imported, imp.name)
+		imported = append(imported, 0x00)
+		imported = appendU32(imported, g.typeIndex(imp.params, imp.results))
+	}
+
+	var funcs []*ast.FuncDecl
+	var main *ast.FuncDecl
+	for _, stmt := range prog.Statements {
+		switch node := stmt.(type) {
+		case *ast.FuncDecl:
+			g.functions[info.Defs[node.Name]] = uint32(len(imports) + len(funcs))
+			funcs = append(funcs, node)
+			if node.Name.Value == entryPoint && len(node.Parameters) == 0 {
+				main = node
+			}
+		case *ast.DeclStatement:
+			g.globals[info.Defs[node.ID]] = uint32(len(g.globals))
+			globals = append(globals, g.zeroGlobal(semantic.TypeOf(node.Type))...)
+		}
+	}
+
+	var bodies []byte
+	for _, fn := range funcs {
+		params := make([]byte, len(fn.Parameters))
+		for i, param := range fn.Parameters {
+			params[i] = valueTypes[semantic.TypeOf(param.Type)]
+		}
+		var results []byte
+		if t, ok := valueTypes[semantic.TypeOf(fn.ReturnType)]; ok {
+			results = []byte{t}
+		}
+		declared = appendU32(declared, g.typeIndex(params, results))
+		bodies = appendBytes(bodies, g.genFunction(fn))
+	}
+
+	start := uint32(len(imports) + len(funcs))
+	declared = appendU32(declared, g.typeIndex(nil, nil))
+	g.beginFunction()
+	for _, stmt := range prog.Statements {
+		if _, ok := stmt.(*ast.FuncDecl); !ok {
+			g.genStatement(stmt)
+		}
+	}
+	if main != nil {
+		g.emit(opCall)
+		g.emitU32(g.functions[info.Defs[main.Name]])
+		// _start returns nothing, so the result of main is dropped
+		if semantic.TypeOf(main.ReturnType) != semantic.Void {
+			g.emit(opDrop)
+		}
+	}
+	bodies = appendBytes(bodies, g.endFunction())
+
+	var exported []byte
+	exported = appendName(exported, startExport)
+	exported = append(exported, functionKind)
+	exported = appendU32(exported, start)
+	exports := 1
+	strs := len(g.data) > 1 || g.interpolates
+	if strs {
+		exported = appendName(exported, memoryExport)
+		exported = append(exported, memoryKind)
+		exported = appendU32(exported, 0)
+		exports++
+	}
+
+	module := []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+	module = appendSection(module, typeSection, len(g.types), concat(g.types))
+	module = appendSection(module, importSection, len(imports), imported)
+	module = appendSection(module, functionSection, len(funcs)+1, declared)
+	if strs {
+		// a minimum number of pages and no maximum
+		pages := (len(g.data) + pageSize - 1) / pageSize
+		module = appendSection(module, memorySection, 1, appendU32([]byte{0x00}, uint32(pages)))
+	}
+	if len(g.globals) > 0 {
+		module = appendSection(module, globalSection, len(g.globals), globals)
+	}
+	module = appendSection(module, exportSection, exports, exported)
+	module = appendSection(module, codeSection, len(funcs)+1, bodies)
+	if strs {
+		// an active segment of memory 0 at address 0
+		segment := []byte{0x00, opI32Const, 0x00, opEnd}
+		module = appendSection(module, dataSection, 1, appendBytes(segment, g.data))
+	}
+	return module
+}
+
+// WriteModule writes the binary module of the program to output.
+func WriteModule(prog *ast.Prog, info *semantic.Info, output string) error {
+	return os.WriteFile(output, Generate(prog, info), 0644)
+}
+
+// typeIndex returns the index of a function type, adding it if needed.
+func (g *generator) typeIndex(params, results []byte) uint32 {
+	t := []byte{0x60}
+	t = appendBytes(t, params)
+	t = appendBytes(t, results)
+	for i, other := range g.types {
+		if string(other) == string(t) {
+			return uint32(i)
+		}
+	}
+	g.types = append(g.types, t)
+	return uint32(len(g.types) - 1)
+}
+
+// address returns the address of a string literal in the memory, adding
+// it to the data if needed.
+func (g *generator) address(str string) uint32 {
+	address, ok := g.addresses[str]
+	if !ok {
+		address = uint32(len(g.data))
+		g.addresses[str] = address
+		g.data = append(append(g.data, str...), 0)
+	}
+	return address
+}
+
+// zeroGlobal encodes a mutable global initialized with zero.
+func (g *generator) zeroGlobal(t semantic.Type) []byte {
+	global := []byte{valueTypes[t], 0x01}
+	switch valueTypes[t] {
+	case i64:
+		global = append(global, opI64Const, 0)
+	case f64:
+		global = append(global, opF64Const, 0, 0, 0, 0, 0, 0, 0, 0)
+	default:
+		global = append(global, opI32Const, 0)
+	}
+	return append(global, opEnd)
+}
+
+func (g *generator) beginFunction() {
+	g.code = nil
+	g.locals = make(map[*semantic.Symbol]uint32)
+	g.localTypes = nil
+	g.params = 0
+}
+
+// endFunction returns the encoded body, whose local declarations can only
+// be written once the code has been generated.
+func (g *generator) endFunction() []byte {
+	var body []byte
+	body = appendU32(body, uint32(len(g.localTypes)))
+	for _, t := range g.localTypes {
+		body = appendU32(body, 1)
+		body = append(body, t)
+	}
+	body = append(body, g.code...)
+	return append(body, opEnd)
+}
+
+func (g *generator) emit(bytes ...byte) {
+	g.code = append(g.code, bytes...)
+}
+
+func (g *generator) emitU32(v uint32) {
+	g.code = appendU32(g.code, v)
+}
+
+func (g *generator) emitI64(v int64) {
+	g.code = appendI64(g.code, v)
+}
+
+func (g *generator) newLocal(t byte) uint32 {
+	index := uint32(g.params + len(g.localTypes))
+	g.localTypes = append(g.localTypes, t)
+	return index
+}
+
+func (g *generator) genFunction(fn *ast.FuncDecl) []byte {
+	g.beginFunction()
+	for i, param := range fn.Parameters {
+		g.locals[g.info.Defs[param.ID]] = uint32(i)
+	}
+	g.params = len(fn.Parameters)
+	g.genStatements(fn.Body.Statements)
+	if semantic.TypeOf(fn.ReturnType) != semantic.Void {
+		// the checker guarantees that every path returned
+		g.emit(opUnreachable)
+	}
+	return g.endFunction()
+}
+
+func (g *generator) genStatements(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		g.genStatement(stmt)
+	}
+}
+
+func (g *generator) genStatement(stmt ast.Stmt) {
+	switch node := stmt.(type) {
+	case *ast.DeclStatement:
+		g.genExpression(node.Value)
+		sym := g.info.Defs[node.ID]
+		if index, ok := g.globals[sym]; ok {
+			g.emit(opGlobalSet)
+			g.emitU32(index)
+			return
+		}
+		index := g.newLocal(valueTypes[semantic.TypeOf(node.Type)])
+		g.locals[sym] = index
+		g.emit(opLocalSet)
+		g.emitU32(index)
+	case *ast.AssignStatement:
+		g.genExpression(node.Value)
+		sym := g.info.Uses[node.Target]
+		if index, ok := g.locals[sym]; ok {
+			g.emit(opLocalSet)
+			g.emitU32(index)
+		} else {
+			g.emit(opGlobalSet)
+			g.emitU32(g.globals[sym])
+		}
+	case *ast.ReturnStatement:
+		if node.Expr != nil {
+			g.genExpression(node.Expr)
+		}
+		g.emit(opReturn)
+	case *ast.ExprStatement:
+		g.genExpression(node.Expr)
+		if _, ok := valueTypes[g.info.TypeOf(node.Expr)]; ok {
+			g.emit(opDrop)
+		}
+	case *ast.WhileStatement:
+		g.emit(opBlock, emptyBlock, opLoop, emptyBlock)
+		g.genExpression(node.Condition)
+		g.emit(opI32Eqz, opBrIf, 1)
+		g.genStatements(node.Body.Statements)
+		g.emit(opBr, 0, opEnd, opEnd)
+	case *ast.BlockStatement:
+		g.genStatements(node.Statements)
+	}
+}
+
+func (g *generator) genIf(node *ast.IfExpression) {
+	g.genExpression(node.Condition)
+	g.emit(opIf, emptyBlock)
+	g.genStatements(node.TrueBlockCondition.Statements)
+	if node.FalseBlockCondition != nil {
+		g.emit(opElse)
+		g.genStatements(node.FalseBlockCondition.Statements)
+	}
+	g.emit(opEnd)
+}
+
+func (g *generator) genExpression(expr ast.Expr) {
+	switch node := expr.(type) {
+	case *ast.IntegerLiteral:
+		g.emit(opI64Const)
+		g.emitI64(node.Value)
+	case *ast.DecimalLiteral:
+		var bits [8]byte
+		binary.LittleEndian.PutUint64(bits[:], math.Float64bits(node.Value))
+		g.emit(opF64Const)
+		g.emit(bits[:]...)
+	case *ast.Boolean:
+		if node.Value {
+			g.emit(opI32Const, 1)
+		} else {
+			g.emit(opI32Const, 0)
+		}
+	case *ast.StringLiteral:
+		g.emit(opI32Const)
+		g.code = appendI32(g.code, int32(g.address(node.Value)))
+	case *ast.CharLiteral:
+		g.emit(opI32Const)
+		g.code = appendI32(g.code, int32(node.Value))
+	case *ast.InterpolatedString:
+		g.interpolates = true
+		for i, part := range node.Parts {
+			g.genExpression(part)
+			if t := g.info.TypeOf(part); t != semantic.String {
+				g.emit(opCall)
+				g.emitU32(stringImports[t])
+			}
+			if i > 0 {
+				g.emit(opCall)
+				g.emitU32(concatImport)
+			}
+		}
+	case *ast.Identifier:
+		sym := g.info.Uses[node]
+		if index, ok := g.locals[sym]; ok {
+			g.emit(opLocalGet)
+			g.emitU32(index)
+		} else {
+			g.emit(opGlobalGet)
+			g.emitU32(g.globals[sym])
+		}
+	case *ast.PrefixExpression:
+		switch {
+		case node.Token.Kind == token.NOT:
+			g.genExpression(node.Right)
+			g.emit(opI32Eqz)
+		case g.info.TypeOf(node.Right) == semantic.Decimal:
+			g.genExpression(node.Right)
+			g.emit(opF64Neg)
+		default:
+			g.emit(opI64Const, 0)
+			g.genExpression(node.Right)
+			g.emit(opI64Sub)
+		}
+	case *ast.InfixExpression:
+		if node.Token.Kind == token.AND || node.Token.Kind == token.OR {
+			g.genLogical(node)
+			return
+		}
+		t := g.info.TypeOf(node.Left)
+		if t == semantic.Integer && node.Token.Kind == token.OP_DIVIDE {
+			g.genDivision(node)
+			return
+		}
+		g.genExpression(node.Left)
+		g.genExpression(node.Right)
+		switch t {
+		case semantic.Integer:
+			g.emit(integerInstructions[node.Token.Kind])
+		case semantic.Decimal:
+			g.emit(decimalInstructions[node.Token.Kind])
+		case semantic.String:
+			g.emit(opCall)
+			g.emitU32(equalStringsImport)
+			if node.Token.Kind == token.OP_NOTEQ {
+				g.emit(opI32Eqz)
+			}
+		default:
+			g.emit(smallInstructions[node.Token.Kind])
+		}
+	case *ast.CallExpression:
+		g.genCall(node)
+	case *ast.IfExpression:
+		g.genIf(node)
+	}
+}
+
+// genLogical evaluates the right operand of && and || in an if on the left
+// one, whose other arm yields the result the left operand decides.
+func (g *generator) genLogical(node *ast.InfixExpression) {
+	g.genExpression(node.Left)
+	g.emit(opIf, i32)
+	if node.Token.Kind == token.AND {
+		g.genExpression(node.Right)
+		g.emit(opElse, opI32Const, 0)
+	} else {
+		g.emit(opI32Const, 1, opElse)
+		g.genExpression(node.Right)
+	}
+	g.emit(opEnd)
+}
+
+// genDivision reports division by zero to the host before trapping, and
+// makes the division of the smallest integer by -1 wrap around.
+func (g *generator) genDivision(node *ast.InfixExpression) {
+	left, right := g.newLocal(i64), g.newLocal(i64)
+	g.genExpression(node.Left)
+	g.emit(opLocalSet)
+	g.emitU32(left)
+	g.genExpression(node.Right)
+	g.emit(opLocalTee)
+	g.emitU32(right)
+
+	pos := node.Token.Position
+	g.emit(opI64Eqz, opIf, emptyBlock, opI32Const)
+	g.code = appendI32(g.code, int32(pos.Line))
+	g.emit(opI32Const)
+	g.code = appendI32(g.code, int32(pos.Column))
+	g.emit(opCall)
+	g.emitU32(divisionByZeroImport)
+	g.emit(opUnreachable, opEnd)
+
+	g.emit(opLocalGet)
+	g.emitU32(right)
+	g.emit(opI64Const, 0x7F, opI64Eq, opIf, i64, opI64Const, 0, opLocalGet)
+	g.emitU32(left)
+	g.emit(opI64Sub, opElse, opLocalGet)
+	g.emitU32(left)
+	g.emit(opLocalGet)
+	g.emitU32(right)
+	g.emit(opI64DivS, opEnd)
+}
+
+func (g *generator) genCall(call *ast.CallExpression) {
+	for _, arg := range call.Arguments {
+		g.genExpression(arg)
+	}
+	sym := g.info.Uses[call.Function.(*ast.Identifier)]
+	g.emit(opCall)
+	if sym.Kind == semantic.BUILTIN {
+		g.emitU32(printImports[g.info.TypeOf(call.Arguments[0])])
+		return
+	}
+	g.emitU32(g.functions[sym])
+}
+
+func appendU32(b []byte, v uint32) []byte {
+	for {
+		c := byte(v & 0x7F)
+		v >>= 7
+		if v == 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func appendI64(b []byte, v int64) []byte {
+	for {
+		c := byte(v & 0x7F)
+		v >>= 7
+		if v == 0 && c&0x40 == 0 || v == -1 && c&0x40 != 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func appendI32(b []byte, v int32) []byte {
+	return appendI64(b, int64(v))
+}
+
+// appendBytes appends a vector of bytes prefixed by its length.
+func appendBytes(b, v []byte) []byte {
+	b = appendU32(b, uint32(len(v)))
+	return append(b, v...)
+}
+
+func appendName(b []byte, name string) []byte {
+	return appendBytes(b, []byte(name))
+}
+
+// appendSection appends a section holding a vector of count entries.
+func appendSection(b []byte, id byte, count int, entries []byte) []byte {
+	contents := appendU32(nil, uint32(count))
+	contents = append(contents, entries...)
+	b = append(b, id)
+	return appendBytes(b, contents)
+}
+
+func concat(chunks [][]byte) []byte {
+	var b []byte
+	for _, chunk := range chunks {
+		b = append(b, chunk...)
+	}
+	return b
+}
@@ -0,0 +1,76 @@
+package wasm
+
+import (
+	"bytes"
+	"github.com/wevertonbruno/wb-compiler/wbtest"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestLEB128(t *testing.T) {
+	tests := []struct {
+		value    int64
+		expected []byte
+	}{
+		{0, []byte{0x00}},
+		{63, []byte{0x3F}},
+		{64, []byte{0xC0, 0x00}},
+		{-1, []byte{0x7F}},
+		{-64, []byte{0x40}},
+		{-65, []byte{0xBF, 0x7F}},
+		{624485, []byte{0xE5, 0x8E, 0x26}},
+	}
+	for _, tt := range tests {
+		if got := appendI64(nil, tt.value); !bytes.Equal(got, tt.expected) {
+			t.Errorf("wrong encoding of %d. expected=% x, got=% x", tt.value, tt.expected, got)
+		}
+	}
+	if got := appendU32(nil, 624485); !bytes.Equal(got, []byte{0xE5, 0x8E, 0x26}) {
+		t.Errorf("wrong unsigned encoding. got=% x", got)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	prog, info := wbtest.Check(t, "print(1)")
+	module := Generate(prog, info)
+
+	if !bytes.HasPrefix(module, []byte("\x00asm\x01\x00\x00\x00")) {
+		t.Fatalf("wrong header. got=% x", module[:8])
+	}
+	expected := []byte{
+		codeSection, 0x08, 0x01, // one body
+		0x06, 0x00, // six bytes, no locals
+		opI64Const, 0x01, opCall, 0x00, opEnd,
+	}
+	if !bytes.HasSuffix(module, expected) {
+		t.Errorf("wrong code section. expected suffix=% x, got=% x", expected, module)
+	}
+}
+
+// TestExecute runs the generated modules with the Node.js host.
+func TestExecute(t *testing.T) {
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node is not installed")
+	}
+	dir := t.TempDir()
+	wbtest.RunPrograms(t, func(t *testing.T, source string) (string, error) {
+		return execute(t, dir, source)
+	})
+
+	// the host runs out of stack before the program does
+	_, err := execute(t, dir, "func f(n : Integer) : Integer {\n\treturn f(n + 1)\n}\nprint(f(0))")
+	if expected := "runtime error. stack overflow"; err == nil || err.Error() != expected {
+		t.Errorf("wrong error. expected=%q, got=%v", expected, err)
+	}
+}
+
+func execute(t *testing.T, dir, input string) (string, error) {
+	t.Helper()
+	prog, info := wbtest.Check(t, input)
+	module := filepath.Join(dir, "prog.wasm")
+	if err := WriteModule(prog, info, module); err != nil {
+		t.Fatal(err)
+	}
+	return wbtest.Exec(exec.Command("node", "wbrt.js", module))
+}
@@ -0,0 +1,112 @@
+// wbrt.js is the WBlang runtime for WebAssembly modules built by wbc. It
+// provides the imports of the module and runs it with Node.js:
+//
+//	node wbrt.js program.wasm
+//
+// Other hosts, such as browsers, can reuse run with their own output.
+'use strict';
+
+const fs = require('fs');
+
+class RuntimeError extends Error {}
+
+// formatDecimal formats a decimal like the %g conversion of C.
+function formatDecimal(x) {
+  if (Number.isNaN(x)) {
+    return 'nan';
+  }
+  if (!Number.isFinite(x)) {
+    return x < 0 ? '-inf' : 'inf';
+  }
+  if (x === 0) {
+    return Object.is(x, -0) ? '-0' : '0';
+  }
+  const [mantissa, exponent] = x.toExponential(5).split('e');
+  const e = Number(exponent);
+  if (e < -4 || e >= 6) {
+    const digits = String(Math.abs(e)).padStart(2, '0');
+    return trimZeros(mantissa) + 'e' + (e < 0 ? '-' : '+') + digits;
+  }
+  return trimZeros(x.toFixed(5 - e));
+}
+
+function trimZeros(s) {
+  return s.includes('.') ? s.replace(/\.?0+$/, '') : s;
+}
+
+// run instantiates the module and calls its entry point. write receives
+// the output of the program as buffers.
+function run(bytes, write) {
+  const line = (s) => write(Buffer.from(s + '\n'));
+  // string returns the NUL-terminated bytes at an address of the memory
+  const string = (address) => {
+    const memory = new Uint8Array(instance.exports.memory.buffer);
+    const end = memory.indexOf(0, address);
+    return Buffer.from(memory.subarray(address, end));
+  };
+  // allocate copies bytes to the memory, NUL-terminated, and returns their
+  // address. The strings are never freed: they are laid out one after the
+  // other past the initial memory, which grows as needed.
+  let heap = 0;
+  const allocate = (b) => {
+    const memory = instance.exports.memory;
+    if (heap === 0) {
+      heap = memory.buffer.byteLength;
+    }
+    const end = heap + b.length + 1;
+    if (end > memory.buffer.byteLength) {
+      memory.grow(Math.ceil((end - memory.buffer.byteLength) / 65536));
+    }
+    const address = heap;
+    new Uint8Array(memory.buffer).set(b, address);
+    new Uint8Array(memory.buffer)[address + b.length] = 0;
+    heap = end;
+    return address;
+  };
+  const env = {
+    print_integer: (v) => line(v.toString()),
+    print_decimal: (v) => line(formatDecimal(v)),
+    print_char: (v) => write(Buffer.from([v & 0xff, 0x0a])),
+    print_boolean: (v) => line(v ? 'true' : 'false'),
+    print_string: (v) => write(Buffer.concat([string(v), Buffer.from('\n')])),
+    equal_strings: (a, b) => (string(a).equals(string(b)) ? 1 : 0),
+    string_integer: (v) => allocate(Buffer.from(v.toString())),
+    string_decimal: (v) => allocate(Buffer.from(formatDecimal(v))),
+    string_char: (v) => allocate(Buffer.from([v & 0xff])),
+    string_boolean: (v) => allocate(Buffer.from(v ? 'true' : 'false')),
+    concat: (a, b) => allocate(Buffer.concat([string(a), string(b)])),
+    division_by_zero: (lineNumber, column) => {
+      throw new RuntimeError(`${lineNumber}:${column}: runtime error. integer division by zero`);
+    },
+  };
+  const module = new WebAssembly.Module(bytes);
+  const instance = new WebAssembly.Instance(module, { env });
+  try {
+    instance.exports._start();
+  } catch (e) {
+    // the engine reports running out of stack as a RangeError, which has
+    // no position in the program
+    if (e instanceof RangeError) {
+      throw new RuntimeError('runtime error. stack overflow');
+    }
+    throw e;
+  }
+}
+
+module.exports = { run, formatDecimal, RuntimeError };
+
+if (require.main === module) {
+  if (process.argv.length !== 3) {
+    process.stderr.write('usage: node wbrt.js program.wasm\n');
+    process.exit(2);
+  }
+  try {
+    run(fs.readFileSync(process.argv[2]), (b) => fs.writeSync(1, b));
+  } catch (e) {
+    if (!(e instanceof RuntimeError)) {
+      throw e;
+    }
+    fs.writeSync(2, e.message + '\n');
+    process.exit(3);
+  }
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"fmt"
+	"github.com/wevertonbruno/wb-compiler/analyzers/diagnostic"
+	"github.com/wevertonbruno/wb-compiler/analyzers/lexer"
+	"github.com/wevertonbruno/wb-compiler/analyzers/parser"
+	"github.com/wevertonbruno/wb-compiler/analyzers/reader"
+	"github.com/wevertonbruno/wb-compiler/analyzers/semantic"
+	"github.com/wevertonbruno/wb-compiler/analyzers/token"
+	"github.com/wevertonbruno/wb-compiler/ast"
+	"github.com/wevertonbruno/wb-compiler/codegen/amd64"
+	"github.com/wevertonbruno/wb-compiler/codegen/cgen"
+	"github.com/wevertonbruno/wb-compiler/codegen/llvm"
+	"github.com/wevertonbruno/wb-compiler/codegen/wasm"
+	"github.com/wevertonbruno/wb-compiler/compiler"
+	"github.com/wevertonbruno/wb-compiler/evaluator"
+	"github.com/wevertonbruno/wb-compiler/ir"
+	"github.com/wevertonbruno/wb-compiler/ir/opt"
+	"github.com/wevertonbruno/wb-compiler/vm"
+	"path/filepath"
+	"strings"
+)
+
+// defaultOutput names the output of a program read from the standard input.
+const defaultOutput = "out"
+
+type target struct {
+	description string
+	// extension is appended to the source name to make the default output.
+	extension string
+	build     func(prog *ast.Prog, info *semantic.Info, output string) error
+	// buildIR, when set instead of build, generates the program from the
+	// intermediate representation after the optimizations.
+	buildIR func(p *ir.Program, output string) error
+}
+
+// targets lists the code generators available to the build command.
+var targets = map[string]target{
+	"c":          {"C99 source, to be compiled with -fwrapv", ".c", cgen.WriteSource, nil},
+	"llvm":       {"LLVM IR text module", ".ll", llvm.WriteIR, nil},
+	"wasm":       {"WebAssembly binary module, run with codegen/wasm/wbrt.js", ".wasm", wasm.WriteModule, nil},
+	"x86-64":     {"Linux x86-64 executable, linked with $CC", "", nil, amd64.Build},
+	"x86-64-asm": {"x86-64 GNU assembly", ".s", nil, amd64.WriteAssembly},
+}
+
+func lexCommand(c *context, args []string) int {
+	fs := c.flagSet("lex")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	name, src, code := c.source(fs)
+	if code != exitOK {
+		return code
+	}
+
+	code = exitOK
+	l := lexer.NewLexer(reader.NewInput(src))
+	for tok := l.GetToken(); !tok.Match(token.EOF); tok = l.GetToken() {
+		if tok.Match(token.ERROR) {
+			c.report(name, []diagnostic.Diagnostic{diagnostic.New(tok.Spelling, tok)})
+			code = exitCompile
+			continue
+		}
+		fmt.Fprintln(c.stdout, tok)
+	}
+	return code
+}
+
+func parseCommand(c *context, args []string) int {
+	fs := c.flagSet("parse")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	name, src, code := c.source(fs)
+	if code != exitOK {
+		return code
+	}
+
+	prog, errs := parser.NewParser(lexer.NewLexer(reader.NewInput(src))).Parse()
+	if len(errs) > 0 {
+		c.report(name, errs)
+		return exitCompile
+	}
+	for _, stmt := range prog.Statements {
+		fmt.Fprintln(c.stdout, strings.TrimRight(stmt.String(), "\n"))
+	}
+	return exitOK
+}
+
+func checkCommand(c *context, args []string) int {
+	fs := c.flagSet("check")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	name, src, code := c.source(fs)
+	if code != exitOK {
+		return code
+	}
+
+	prog, info, code := c.frontend(name, src)
+	if code != exitOK {
+		return code
+	}
+	c.warn(name, prog, info)
+	return exitOK
+}
+
+func runCommand(c *context, args []string) int {
+	fs := c.flagSet("run")
+	engine := fs.String("engine", "eval", "execute with the tree-walking evaluator (eval) or the bytecode virtual machine (vm)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *engine != "eval" && *engine != "vm" {
+		fmt.Fprintf(c.stderr, "wbc: unknown engine %q\n", *engine)
+		return exitUsage
+	}
+	name, src, code := c.source(fs)
+	if code != exitOK {
+		return code
+	}
+
+	prog, info, code := c.frontend(name, src)
+	if code != exitOK {
+		return code
+	}
+	var err error
+	if *engine == "vm" {
+		var bytecode *compiler.Bytecode
+		if bytecode, code = c.compileBytecode(prog, info); code != exitOK {
+			return code
+		}
+		err = vm.New(bytecode, c.stdout).Run()
+	} else {
+		err = evaluator.New(c.stdout).Run(prog)
+	}
+	if err != nil {
+		fmt.Fprintf(c.stderr, "%s:%v\n", displayName(name), err)
+		return exitRuntime
+	}
+	return exitOK
+}
+
+func disasmCommand(c *context, args []string) int {
+	fs := c.flagSet("disasm")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	name, src, code := c.source(fs)
+	if code != exitOK {
+		return code
+	}
+
+	prog, info, code := c.frontend(name, src)
+	if code != exitOK {
+		return code
+	}
+	bytecode, code := c.compileBytecode(prog, info)
+	if code != exitOK {
+		return code
+	}
+	fmt.Fprint(c.stdout, bytecode)
+	return exitOK
+}
+
+func irCommand(c *context, args []string) int {
+	fs := c.flagSet("ir")
+	ssa := fs.Bool("ssa", false, "print the functions in static single assignment form")
+	level := fs.Int("O", opt.None, "optimize at `level` (0 none, 1 basic, 2 also inlining, tail calls and loops) and print the functions out of SSA form")
+	intervals := fs.Bool("intervals", false, "print the live intervals and registers of the x86-64 target at the -O level")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if !c.validLevel(*level) {
+		return exitUsage
+	}
+	name, src, code := c.source(fs)
+	if code != exitOK {
+		return code
+	}
+
+	prog, info, code := c.frontend(name, src)
+	if code != exitOK {
+		return code
+	}
+	p := ir.Lower(prog, info)
+	if *intervals {
+		c.report(name, opt.Optimize(p, *level))
+		fmt.Fprint(c.stdout, amd64.DumpIntervals(p))
+		return exitOK
+	}
+	if *level > opt.None {
+		c.report(name, opt.Optimize(p, *level))
+	} else if *ssa {
+		for _, fn := range p.Funcs {
+			fn.BuildSSA()
+		}
+	}
+	fmt.Fprint(c.stdout, p)
+	return exitOK
+}
+
+func (c *context) compileBytecode(prog *ast.Prog, info *semantic.Info) (*compiler.Bytecode, int) {
+	comp := compiler.New(info)
+	if err := comp.Compile(prog); err != nil {
+		fmt.Fprintf(c.stderr, "wbc: %v\n", err)
+		return nil, exitCompile
+	}
+	return comp.Bytecode(), exitOK
+}
+
+func buildCommand(c *context, args []string) int {
+	fs := c.flagSet("build")
+	output := fs.String("o", "", "write the output to `file`")
+	targetName := fs.String("target", "", "the code generator to use, see wbc help")
+	level := fs.Int("O", opt.Full, "optimize at `level` (0 none, 1 basic, 2 also inlining, tail calls and loops); only the x86-64 targets are optimized, the others ignore it")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if !c.validLevel(*level) {
+		return exitUsage
+	}
+	t, ok := targets[*targetName]
+	if !ok {
+		fmt.Fprintf(c.stderr, "wbc: unknown target %q\n", *targetName)
+		return exitUsage
+	}
+	name, src, code := c.source(fs)
+	if code != exitOK {
+		return code
+	}
+
+	out := *output
+	if out == "" {
+		out = defaultOutput
+		if name != "-" {
+			out = strings.TrimSuffix(name, ".wb")
+		}
+		out += t.extension
+	}
+	// a source not named *.wb would be replaced by an executable
+	if filepath.Clean(out) == filepath.Clean(name) {
+		fmt.Fprintf(c.stderr, "wbc: the output would replace the source %s, name it with -o\n", name)
+		return exitUsage
+	}
+
+	prog, info, code := c.frontend(name, src)
+	if code != exitOK {
+		return code
+	}
+	var err error
+	if t.buildIR != nil {
+		p := ir.Lower(prog, info)
+		c.report(name, opt.Optimize(p, *level))
+		err = t.buildIR(p, out)
+	} else {
+		c.warn(name, prog, info)
+		err = t.build(prog, info, out)
+	}
+	if err != nil {
+		fmt.Fprintf(c.stderr, "wbc: %v\n", err)
+		return exitIO
+	}
+	return exitOK
+}
+
+// validLevel reports whether level names an optimization level, printing an
+// error if it does not.
+func (c *context) validLevel(level int) bool {
+	if level < opt.None || level > opt.Full {
+		fmt.Fprintf(c.stderr, "wbc: unknown optimization level %d\n", level)
+		return false
+	}
+	return true
+}
+
+// warn reports the warnings of the optimizations, for the commands that
+// do not run them.
+func (c *context) warn(name string, prog *ast.Prog, info *semantic.Info) {
+	c.report(name, opt.Optimize(ir.Lower(prog, info), opt.Basic))
+}
+
+// frontend parses and checks a program, reporting any errors found.
+func (c *context) frontend(name, src string) (*ast.Prog, *semantic.Info, int) {
+	prog, errs := parser.NewParser(lexer.NewLexer(reader.NewInput(src))).Parse()
+	if len(errs) > 0 {
+		c.report(name, errs)
+		return nil, nil, exitCompile
+	}
+	info, errs := semantic.Check(prog)
+	if len(errs) > 0 {
+		c.report(name, errs)
+		return nil, nil, exitCompile
+	}
+	return prog, info, exitOK
+}
+
+func (c *context) report(name string, errs []diagnostic.Diagnostic) {
+	for _, err := range errs {
+		fmt.Fprintf(c.stderr, "%s:%v\n", displayName(name), err)
+	}
+}
+
+func displayName(name string) string {
+	if name == "-" {
+		return "<stdin>"
+	}
+	return name
+}
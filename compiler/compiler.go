@@ -0,0 +1,415 @@
+package compiler
+
+import (
+	"fmt"
+	"github.com/wevertonbruno/wb-compiler/analyzers/semantic"
+	"github.com/wevertonbruno/wb-compiler/analyzers/token"
+	"github.com/wevertonbruno/wb-compiler/ast"
+	"github.com/wevertonbruno/wb-compiler/code"
+	"github.com/wevertonbruno/wb-compiler/object"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	compilerError = "compiler error. %v"
+
+	tooManyConstantsError = "too many constants, the limit is %d"
+	tooManyGlobalsError   = "too many global variables, the limit is %d"
+	tooManyLocalsError    = "too many local variables in %s, the limit is %d"
+	tooManyArgumentsError = "too many arguments in call to %s, the limit is %d"
+	tooLongError          = "%s is too long, jumps reach the first %d bytes of its code only"
+
+	// placeholder operand of jumps that are patched later
+	placeholder = 9999
+
+	mainProgram = "<main>"
+	entryPoint  = "main"
+)
+
+type (
+	Bytecode struct {
+		// Main holds the instructions of the top level statements.
+		Main      *object.CompiledFunction
+		Constants []object.Object
+	}
+
+	CompilationScope struct {
+		fn     *object.CompiledFunction
+		locals map[*semantic.Symbol]int
+	}
+
+	Compiler struct {
+		info      *semantic.Info
+		constants []object.Object
+		globals   map[*semantic.Symbol]int
+		scope     *CompilationScope
+
+		// literals deduplicates constants with the same value
+		literals map[interface{}]int
+	}
+
+	// bailout unwinds the compiler when the program exceeds a limit
+	bailout struct {
+		err error
+	}
+)
+
+func New(info *semantic.Info) *Compiler {
+	return &Compiler{
+		info:     info,
+		globals:  make(map[*semantic.Symbol]int),
+		literals: make(map[interface{}]int),
+	}
+}
+
+// Compile translates a checked program. The top level statements run in
+// order and are followed by a call to main, if the program declares one.
+func (c *Compiler) Compile(prog *ast.Prog) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			b, ok := r.(bailout)
+			if !ok {
+				panic(r)
+			}
+			err = b.err
+		}
+	}()
+
+	c.scope = newCompilationScope(mainProgram)
+	var main *ast.FuncDecl
+	// functions are stored in globals before anything runs, so they can be
+	// called before their declaration
+	for _, stmt := range prog.Statements {
+		if fn, ok := stmt.(*ast.FuncDecl); ok {
+			c.global(c.info.Defs[fn.Name])
+		}
+	}
+	for _, stmt := range prog.Statements {
+		if fn, ok := stmt.(*ast.FuncDecl); ok {
+			c.emit(code.OpConstant, c.addConstant(c.compileFunction(fn)))
+			c.emit(code.OpSetGlobal, c.global(c.info.Defs[fn.Name]))
+			if fn.Name.Value == entryPoint && len(fn.Parameters) == 0 {
+				main = fn
+			}
+		}
+	}
+	for _, stmt := range prog.Statements {
+		if _, ok := stmt.(*ast.FuncDecl); !ok {
+			c.compileStatement(stmt)
+		}
+	}
+	if main != nil {
+		c.emit(code.OpGetGlobal, c.global(c.info.Defs[main.Name]))
+		c.emitAt(main.Name.Token, code.OpCall, 0)
+		c.emit(code.OpPop)
+	}
+	return nil
+}
+
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Main:      c.scope.fn,
+		Constants: c.constants,
+	}
+}
+
+func newCompilationScope(name string) *CompilationScope {
+	return &CompilationScope{
+		fn: &object.CompiledFunction{
+			Name:         name,
+			Instructions: code.Instructions{},
+			Positions:    make(map[int]token.Token),
+		},
+		locals: make(map[*semantic.Symbol]int),
+	}
+}
+
+func (c *Compiler) abort(message string) {
+	panic(bailout{err: fmt.Errorf(compilerError, message)})
+}
+
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	ins := code.Make(op, operands...)
+	pos := len(c.scope.fn.Instructions)
+	c.scope.fn.Instructions = append(c.scope.fn.Instructions, ins...)
+	return pos
+}
+
+// emitAt emits an instruction that may fail at runtime, remembering the
+// token it comes from so that the error can be located in the source.
+func (c *Compiler) emitAt(tok token.Token, op code.Opcode, operands ...int) int {
+	pos := c.emit(op, operands...)
+	c.scope.fn.Positions[pos] = tok
+	return pos
+}
+
+// changeOperand patches the target of a forward jump. A backward jump is
+// followed by the patch of the jump out of its loop, which covers its
+// target as well.
+func (c *Compiler) changeOperand(pos int, operand int) {
+	if operand > math.MaxUint16 {
+		c.abort(fmt.Sprintf(tooLongError, c.scope.fn.Name, math.MaxUint16+1))
+	}
+	op := code.Opcode(c.scope.fn.Instructions[pos])
+	copy(c.scope.fn.Instructions[pos:], code.Make(op, operand))
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	if len(c.constants) > math.MaxUint16 {
+		c.abort(fmt.Sprintf(tooManyConstantsError, math.MaxUint16+1))
+	}
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+func (c *Compiler) addLiteral(key interface{}, obj object.Object) int {
+	if index, ok := c.literals[key]; ok {
+		return index
+	}
+	index := c.addConstant(obj)
+	c.literals[key] = index
+	return index
+}
+
+// global returns the slot of a global symbol, allocating it on first use
+// since functions may refer to globals that are declared after them.
+func (c *Compiler) global(sym *semantic.Symbol) int {
+	if index, ok := c.globals[sym]; ok {
+		return index
+	}
+	if len(c.globals) > math.MaxUint16 {
+		c.abort(fmt.Sprintf(tooManyGlobalsError, math.MaxUint16+1))
+	}
+	index := len(c.globals)
+	c.globals[sym] = index
+	return index
+}
+
+func (c *Compiler) defineLocal(sym *semantic.Symbol) int {
+	if c.scope.fn.NumLocals > math.MaxUint8 {
+		c.abort(fmt.Sprintf(tooManyLocalsError, c.scope.fn.Name, math.MaxUint8+1))
+	}
+	index := c.scope.fn.NumLocals
+	c.scope.locals[sym] = index
+	c.scope.fn.NumLocals++
+	return index
+}
+
+func (c *Compiler) inFunction() bool {
+	return c.scope.fn.Name != mainProgram
+}
+
+func (c *Compiler) compileFunction(fn *ast.FuncDecl) *object.CompiledFunction {
+	outer := c.scope
+	c.scope = newCompilationScope(fn.Name.Value)
+	for _, param := range fn.Parameters {
+		c.defineLocal(c.info.Defs[param.ID])
+	}
+	c.scope.fn.NumParameters = len(fn.Parameters)
+	c.compileStatements(fn.Body.Statements)
+	if !semantic.Terminates(fn.Body.Statements) {
+		c.emit(code.OpReturn)
+	}
+	compiled := c.scope.fn
+	c.scope = outer
+	return compiled
+}
+
+func (c *Compiler) compileStatements(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		c.compileStatement(stmt)
+	}
+}
+
+func (c *Compiler) compileStatement(stmt ast.Stmt) {
+	switch node := stmt.(type) {
+	case *ast.DeclStatement:
+		c.compileExpression(node.Value)
+		sym := c.info.Defs[node.ID]
+		if c.inFunction() {
+			c.emit(code.OpSetLocal, c.defineLocal(sym))
+		} else {
+			c.emit(code.OpSetGlobal, c.global(sym))
+		}
+	case *ast.AssignStatement:
+		c.compileExpression(node.Value)
+		sym := c.info.Uses[node.Target]
+		if index, ok := c.scope.locals[sym]; ok {
+			c.emit(code.OpSetLocal, index)
+		} else {
+			c.emit(code.OpSetGlobal, c.global(sym))
+		}
+	case *ast.ReturnStatement:
+		if node.Expr == nil {
+			c.emit(code.OpReturn)
+			return
+		}
+		c.compileExpression(node.Expr)
+		c.emit(code.OpReturnValue)
+	case *ast.ExprStatement:
+		if ifExpr, ok := node.Expr.(*ast.IfExpression); ok {
+			c.compileIf(ifExpr)
+			return
+		}
+		c.compileExpression(node.Expr)
+		c.emit(code.OpPop)
+	case *ast.WhileStatement:
+		start := len(c.scope.fn.Instructions)
+		c.compileExpression(node.Condition)
+		exit := c.emit(code.OpJumpNotTruthy, placeholder)
+		c.compileStatements(node.Body.Statements)
+		c.emit(code.OpJump, start)
+		c.changeOperand(exit, len(c.scope.fn.Instructions))
+	case *ast.BlockStatement:
+		c.compileStatements(node.Statements)
+	}
+}
+
+func (c *Compiler) compileIf(node *ast.IfExpression) {
+	c.compileExpression(node.Condition)
+	jumpNotTruthy := c.emit(code.OpJumpNotTruthy, placeholder)
+	c.compileStatements(node.TrueBlockCondition.Statements)
+	if node.FalseBlockCondition == nil {
+		c.changeOperand(jumpNotTruthy, len(c.scope.fn.Instructions))
+		return
+	}
+	jump := c.emit(code.OpJump, placeholder)
+	c.changeOperand(jumpNotTruthy, len(c.scope.fn.Instructions))
+	c.compileStatements(node.FalseBlockCondition.Statements)
+	c.changeOperand(jump, len(c.scope.fn.Instructions))
+}
+
+var infixOpcodes = map[token.Kind]code.Opcode{
+	token.OP_PLUS:   code.OpAdd,
+	token.OP_MINUS:  code.OpSub,
+	token.OP_MULTI:  code.OpMul,
+	token.OP_DIVIDE: code.OpDiv,
+	token.OP_EQ:     code.OpEqual,
+	token.OP_NOTEQ:  code.OpNotEqual,
+	token.OP_LT:     code.OpLessThan,
+	token.OP_LTE:    code.OpLessEqual,
+	token.OP_GT:     code.OpGreaterThan,
+	token.OP_GTE:    code.OpGreaterEqual,
+}
+
+func (c *Compiler) compileExpression(expr ast.Expr) {
+	switch node := expr.(type) {
+	case *ast.IntegerLiteral:
+		c.emit(code.OpConstant, c.addLiteral(node.Value, &object.Integer{Value: node.Value}))
+	case *ast.DecimalLiteral:
+		c.emit(code.OpConstant, c.addLiteral(node.Value, &object.Decimal{Value: node.Value}))
+	case *ast.StringLiteral:
+		c.emit(code.OpConstant, c.addLiteral(node.Value, &object.String{Value: node.Value}))
+	case *ast.CharLiteral:
+		c.emit(code.OpConstant, c.addLiteral(node.Value, &object.Char{Value: node.Value}))
+	case *ast.InterpolatedString:
+		// OpConcat joins up to 255 values, so the parts beyond are joined
+		// to the string of those before
+		count := 0
+		for _, part := range node.Parts {
+			if count == 255 {
+				c.emit(code.OpConcat, count)
+				count = 1
+			}
+			c.compileExpression(part)
+			count++
+		}
+		c.emit(code.OpConcat, count)
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+	case *ast.Identifier:
+		sym := c.info.Uses[node]
+		if index, ok := c.scope.locals[sym]; ok {
+			c.emit(code.OpGetLocal, index)
+		} else {
+			c.emit(code.OpGetGlobal, c.global(sym))
+		}
+	case *ast.PrefixExpression:
+		c.compileExpression(node.Right)
+		switch node.Token.Kind {
+		case token.OP_MINUS:
+			c.emit(code.OpMinus)
+		case token.NOT:
+			c.emit(code.OpNot)
+		}
+	case *ast.InfixExpression:
+		if node.Token.Kind == token.AND || node.Token.Kind == token.OR {
+			c.compileLogical(node)
+			return
+		}
+		c.compileExpression(node.Left)
+		c.compileExpression(node.Right)
+		c.emitAt(node.Token, infixOpcodes[node.Token.Kind])
+	case *ast.CallExpression:
+		c.compileCall(node)
+	}
+}
+
+// compileLogical jumps over the right operand of && and || when the left
+// one decides the result, which is then pushed instead.
+func (c *Compiler) compileLogical(node *ast.InfixExpression) {
+	c.compileExpression(node.Left)
+	jumpNotTruthy := c.emit(code.OpJumpNotTruthy, placeholder)
+	if node.Token.Kind == token.AND {
+		c.compileExpression(node.Right)
+	} else {
+		c.emit(code.OpTrue)
+	}
+	jump := c.emit(code.OpJump, placeholder)
+	c.changeOperand(jumpNotTruthy, len(c.scope.fn.Instructions))
+	if node.Token.Kind == token.AND {
+		c.emit(code.OpFalse)
+	} else {
+		c.compileExpression(node.Right)
+	}
+	c.changeOperand(jump, len(c.scope.fn.Instructions))
+}
+
+func (c *Compiler) compileCall(call *ast.CallExpression) {
+	id := call.Function.(*ast.Identifier)
+	if sym := c.info.Uses[id]; sym.Kind == semantic.BUILTIN {
+		c.compileExpression(call.Arguments[0])
+		c.emit(code.OpPrint)
+		return
+	}
+	if len(call.Arguments) > math.MaxUint8 {
+		c.abort(fmt.Sprintf(tooManyArgumentsError, id.Value, math.MaxUint8))
+	}
+	c.compileExpression(id)
+	for _, arg := range call.Arguments {
+		c.compileExpression(arg)
+	}
+	c.emitAt(call.Token, code.OpCall, len(call.Arguments))
+}
+
+// String disassembles the bytecode: the constants pool, the body of every
+// compiled function and then the top level instructions.
+func (b *Bytecode) String() string {
+	out := &strings.Builder{}
+	out.WriteString("constants:\n")
+	for i, constant := range b.Constants {
+		value := constant.Inspect()
+		switch constant := constant.(type) {
+		case *object.String:
+			value = strconv.Quote(constant.Value)
+		case *object.Char:
+			value = strconv.QuoteRune(rune(constant.Value))
+		}
+		fmt.Fprintf(out, "%04d %s %s\n", i, constant.Type(), value)
+	}
+	for _, constant := range b.Constants {
+		if fn, ok := constant.(*object.CompiledFunction); ok {
+			fmt.Fprintf(out, "\nfunc %s (params=%d, locals=%d):\n", fn.Name, fn.NumParameters, fn.NumLocals)
+			out.WriteString(fn.Instructions.String())
+		}
+	}
+	fmt.Fprintf(out, "\n%s:\n", b.Main.Name)
+	out.WriteString(b.Main.Instructions.String())
+	return out.String()
+}
@@ -0,0 +1,348 @@
+package compiler
+
+import (
+	"github.com/wevertonbruno/wb-compiler/code"
+	"github.com/wevertonbruno/wb-compiler/object"
+	"github.com/wevertonbruno/wb-compiler/wbtest"
+	"strings"
+	"testing"
+)
+
+type compilerTestCase struct {
+	input                string
+	expectedConstants    []interface{}
+	expectedInstructions []code.Instructions
+}
+
+func TestExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "1 + 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "-2.5 * 2.5 < 1.0",
+			expectedConstants: []interface{}{2.5, 1.0},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpMinus),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpMul),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpLessThan),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "!true != false",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpNot),
+				code.Make(code.OpFalse),
+				code.Make(code.OpNotEqual),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `"wb" == "wb" != ('a' < 'b')`,
+			expectedConstants: []interface{}{"wb", byte('a'), byte('b')},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpEqual),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpLessThan),
+				code.Make(code.OpNotEqual),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "true && false || true",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthy, 8),
+				code.Make(code.OpFalse),
+				code.Make(code.OpJump, 9),
+				// 0008
+				code.Make(code.OpFalse),
+				// 0009
+				code.Make(code.OpJumpNotTruthy, 16),
+				code.Make(code.OpTrue),
+				code.Make(code.OpJump, 17),
+				// 0016
+				code.Make(code.OpTrue),
+				// 0017
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestInterpolatedString(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `"a${1}b${'c'}"`,
+			expectedConstants: []interface{}{"a", 1, "b", byte('c')},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpConcat, 4),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+
+	// the values are joined 255 at most at a time
+	input := `"` + strings.Repeat("${1}", 300) + `"`
+	disassembly := compile(t, input).String()
+	if !strings.Contains(disassembly, "OpConcat 255\n") || !strings.Contains(disassembly, "OpConcat 46\n") {
+		t.Errorf("wrong concatenations. got=\n%s", disassembly)
+	}
+}
+
+func TestStatements(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `var a : Integer = 1
+			if (a > 0) {
+				print(a)
+			} else {
+				print(0)
+			}`,
+			expectedConstants: []interface{}{1, 0},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				// 0006
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpGreaterThan),
+				code.Make(code.OpJumpNotTruthy, 24),
+				// 0016
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPrint),
+				code.Make(code.OpPop),
+				code.Make(code.OpJump, 29),
+				// 0024
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPrint),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `while true {
+				print(1)
+			}`,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthy, 12),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPrint),
+				code.Make(code.OpPop),
+				code.Make(code.OpJump, 0),
+			},
+		},
+		{
+			input: `var a : Integer = 1
+			func f(n : Integer) {
+				n = a
+			}
+			a = a + 2`,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetGlobal, 1),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpReturn),
+				},
+				1, 2,
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpAdd),
+				code.Make(code.OpSetGlobal, 1),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestJumpLimit(t *testing.T) {
+	// print(1) takes 5 bytes, so the jumps out of the loops are out of reach
+	body := strings.Repeat("print(1)\n", 17000)
+	for _, tt := range []struct {
+		input    string
+		expected string
+	}{
+		{"while true {\n" + body + "}", "compiler error. <main> is too long, jumps reach the first 65536 bytes of its code only"},
+		{"func f() {\nwhile true {\n" + body + "}\n}", "compiler error. f is too long, jumps reach the first 65536 bytes of its code only"},
+	} {
+		prog, info := wbtest.Check(t, tt.input)
+		err := New(info).Compile(prog)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%v", tt.expected, err)
+		}
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `func main() {
+				print(twice(2))
+			}
+			func twice(x : Integer) : Integer {
+				var y : Integer = x * 2
+				return y
+			}`,
+			expectedConstants: []interface{}{
+				2,
+				[]code.Instructions{
+					code.Make(code.OpGetGlobal, 1),
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpPrint),
+					code.Make(code.OpPop),
+					code.Make(code.OpReturn),
+				},
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpMul),
+					code.Make(code.OpSetLocal, 1),
+					code.Make(code.OpGetLocal, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpCall, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestBytecodeString(t *testing.T) {
+	bytecode := compile(t, `func one() : Integer {
+		return 1
+	}
+	print(one())
+	print("one\n")
+	print('1')`)
+	expected := `constants:
+0000 Integer 1
+0001 CompiledFunction compiled func one
+0002 String "one\n"
+0003 Char '1'
+
+func one (params=0, locals=0):
+0000 OpConstant 0
+0003 OpReturnValue
+
+<main>:
+0000 OpConstant 1
+0003 OpSetGlobal 0
+0006 OpGetGlobal 0
+0009 OpCall 0
+0011 OpPrint
+0012 OpPop
+0013 OpConstant 2
+0016 OpPrint
+0017 OpPop
+0018 OpConstant 3
+0021 OpPrint
+0022 OpPop
+`
+	if actual := bytecode.String(); actual != expected {
+		t.Errorf("wrong disassembly.\nwant=%q\ngot=%q", expected, actual)
+	}
+}
+
+func runCompilerTests(t *testing.T, tests []compilerTestCase) {
+	t.Helper()
+	for _, tt := range tests {
+		bytecode := compile(t, tt.input)
+		testInstructions(t, tt.expectedInstructions, bytecode.Main.Instructions)
+		if len(bytecode.Constants) != len(tt.expectedConstants) {
+			t.Fatalf("wrong number of constants. want=%d, got=%d",
+				len(tt.expectedConstants), len(bytecode.Constants))
+		}
+		for i, constant := range tt.expectedConstants {
+			testConstant(t, constant, bytecode.Constants[i])
+		}
+	}
+}
+
+func compile(t *testing.T, input string) *Bytecode {
+	t.Helper()
+	prog, info := wbtest.Check(t, input)
+	c := New(info)
+	if err := c.Compile(prog); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	return c.Bytecode()
+}
+
+func testInstructions(t *testing.T, expected []code.Instructions, actual code.Instructions) {
+	t.Helper()
+	concatted := code.Instructions{}
+	for _, ins := range expected {
+		concatted = append(concatted, ins...)
+	}
+	if actual.String() != concatted.String() {
+		t.Errorf("wrong instructions.\nwant=\n%s\ngot=\n%s", concatted, actual)
+	}
+}
+
+func testConstant(t *testing.T, expected interface{}, actual object.Object) {
+	t.Helper()
+	switch expected := expected.(type) {
+	case int:
+		if integer, ok := actual.(*object.Integer); !ok || integer.Value != int64(expected) {
+			t.Errorf("constant is not Integer %d. got=%s", expected, actual.Inspect())
+		}
+	case float64:
+		if decimal, ok := actual.(*object.Decimal); !ok || decimal.Value != expected {
+			t.Errorf("constant is not Decimal %f. got=%s", expected, actual.Inspect())
+		}
+	case string:
+		if str, ok := actual.(*object.String); !ok || str.Value != expected {
+			t.Errorf("constant is not String %q. got=%s", expected, actual.Inspect())
+		}
+	case byte:
+		if char, ok := actual.(*object.Char); !ok || char.Value != expected {
+			t.Errorf("constant is not Char %q. got=%s", expected, actual.Inspect())
+		}
+	case []code.Instructions:
+		fn, ok := actual.(*object.CompiledFunction)
+		if !ok {
+			t.Errorf("constant is not a function. got=%T", actual)
+			return
+		}
+		testInstructions(t, expected, fn.Instructions)
+	}
+}
@@ -0,0 +1,315 @@
+package evaluator
+
+import (
+	"fmt"
+	"github.com/wevertonbruno/wb-compiler/analyzers/diagnostic"
+	"github.com/wevertonbruno/wb-compiler/analyzers/semantic"
+	"github.com/wevertonbruno/wb-compiler/analyzers/token"
+	"github.com/wevertonbruno/wb-compiler/ast"
+	"github.com/wevertonbruno/wb-compiler/object"
+	"io"
+	"strings"
+)
+
+const (
+	runtimeError = "runtime error. %v"
+
+	divisionByZeroError  = "integer division by zero"
+	unknownOperatorError = "unknown operator: %s %v %s"
+	notCallableError     = "%s is not a function"
+	stackOverflowError   = "stack overflow"
+
+	entryPoint = "main"
+
+	// maxFrames matches vm.MaxFrames: the top level takes a frame and each
+	// running call another
+	maxFrames = 1024
+)
+
+type (
+	Evaluator struct {
+		out    io.Writer
+		global *object.Environment
+		// frames counts the top level and the calls running
+		frames int
+	}
+
+	// abort unwinds the evaluator when the program fails at runtime
+	abort struct {
+		err diagnostic.Diagnostic
+	}
+)
+
+func New(out io.Writer) *Evaluator {
+	e := &Evaluator{out: out, global: object.NewEnvironment(), frames: 1}
+	e.global.Set(semantic.PrintBuiltin, &object.Builtin{Name: semantic.PrintBuiltin, Fn: e.print})
+	return e
+}
+
+// Run executes the top level statements of a checked program in order and
+// then calls its main function, if there is one.
+func (e *Evaluator) Run(prog *ast.Prog) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			a, ok := r.(abort)
+			if !ok {
+				panic(r)
+			}
+			err = a.err
+		}
+	}()
+
+	for _, stmt := range prog.Statements {
+		if fn, ok := stmt.(*ast.FuncDecl); ok {
+			e.global.Set(fn.Name.Value, &object.Function{Decl: fn, Env: e.global})
+		}
+	}
+	for _, stmt := range prog.Statements {
+		e.exec(stmt, e.global)
+	}
+	if main, ok := e.global.Get(entryPoint); ok {
+		if fn, ok := main.(*object.Function); ok && len(fn.Decl.Parameters) == 0 {
+			e.callFunction(fn.Decl.Name.Token, fn, nil)
+		}
+	}
+	return nil
+}
+
+func (e *Evaluator) error(tok token.Token, message string) {
+	panic(abort{err: diagnostic.New(fmt.Sprintf(runtimeError, message), tok)})
+}
+
+func (e *Evaluator) print(args ...object.Object) object.Object {
+	for _, arg := range args {
+		fmt.Fprintln(e.out, arg.Inspect())
+	}
+	return object.VOID
+}
+
+// exec runs a statement and returns a *object.ReturnValue when the statement
+// returned from the enclosing function, or nil otherwise.
+func (e *Evaluator) exec(stmt ast.Stmt, env *object.Environment) object.Object {
+	switch node := stmt.(type) {
+	case *ast.DeclStatement:
+		env.Set(node.ID.Value, e.eval(node.Value, env))
+	case *ast.AssignStatement:
+		env.Assign(node.Target.Value, e.eval(node.Value, env))
+	case *ast.ReturnStatement:
+		if node.Expr == nil {
+			return &object.ReturnValue{Value: object.VOID}
+		}
+		return &object.ReturnValue{Value: e.eval(node.Expr, env)}
+	case *ast.ExprStatement:
+		if ifExpr, ok := node.Expr.(*ast.IfExpression); ok {
+			return e.execIf(ifExpr, env)
+		}
+		e.eval(node.Expr, env)
+	case *ast.WhileStatement:
+		for e.isTrue(e.eval(node.Condition, env)) {
+			if ret := e.execBlock(node.Body, env); ret != nil {
+				return ret
+			}
+		}
+	case *ast.BlockStatement:
+		return e.execBlock(node, env)
+	}
+	return nil
+}
+
+func (e *Evaluator) execBlock(block *ast.BlockStatement, outer *object.Environment) object.Object {
+	return e.execStatements(block.Statements, object.NewEnclosedEnvironment(outer))
+}
+
+func (e *Evaluator) execStatements(stmts []ast.Stmt, env *object.Environment) object.Object {
+	for _, stmt := range stmts {
+		if ret := e.exec(stmt, env); ret != nil {
+			return ret
+		}
+	}
+	return nil
+}
+
+func (e *Evaluator) execIf(ifExpr *ast.IfExpression, env *object.Environment) object.Object {
+	if e.isTrue(e.eval(ifExpr.Condition, env)) {
+		return e.execBlock(ifExpr.TrueBlockCondition, env)
+	} else if ifExpr.FalseBlockCondition != nil {
+		return e.execBlock(ifExpr.FalseBlockCondition, env)
+	}
+	return nil
+}
+
+func (e *Evaluator) isTrue(obj object.Object) bool {
+	return obj == object.TRUE
+}
+
+func (e *Evaluator) eval(expr ast.Expr, env *object.Environment) object.Object {
+	switch node := expr.(type) {
+	case *ast.IntegerLiteral:
+		return &object.Integer{Value: node.Value}
+	case *ast.DecimalLiteral:
+		return &object.Decimal{Value: node.Value}
+	case *ast.Boolean:
+		return object.NativeBoolean(node.Value)
+	case *ast.StringLiteral:
+		return &object.String{Value: node.Value}
+	case *ast.CharLiteral:
+		return &object.Char{Value: node.Value}
+	case *ast.InterpolatedString:
+		var out strings.Builder
+		for _, part := range node.Parts {
+			out.WriteString(e.eval(part, env).Inspect())
+		}
+		return &object.String{Value: out.String()}
+	case *ast.Identifier:
+		val, _ := env.Get(node.Value)
+		return val
+	case *ast.PrefixExpression:
+		return e.evalPrefix(node, e.eval(node.Right, env))
+	case *ast.InfixExpression:
+		if node.Token.Kind == token.AND || node.Token.Kind == token.OR {
+			return e.evalLogical(node, env)
+		}
+		return e.evalInfix(node, e.eval(node.Left, env), e.eval(node.Right, env))
+	case *ast.CallExpression:
+		return e.evalCall(node, env)
+	case *ast.IfExpression:
+		e.execIf(node, env)
+	}
+	return object.VOID
+}
+
+func (e *Evaluator) evalPrefix(node *ast.PrefixExpression, right object.Object) object.Object {
+	switch node.Token.Kind {
+	case token.NOT:
+		return object.NativeBoolean(!e.isTrue(right))
+	case token.OP_MINUS:
+		switch right := right.(type) {
+		case *object.Integer:
+			return &object.Integer{Value: -right.Value}
+		case *object.Decimal:
+			return &object.Decimal{Value: -right.Value}
+		}
+	}
+	e.error(node.Token, fmt.Sprintf(unknownOperatorError, "", node.Token.Spelling, right.Type()))
+	return nil
+}
+
+// evalLogical evaluates the right operand of && and || only when the left
+// one does not decide the result.
+func (e *Evaluator) evalLogical(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := e.isTrue(e.eval(node.Left, env))
+	if left == (node.Token.Kind == token.OR) {
+		return object.NativeBoolean(left)
+	}
+	return e.eval(node.Right, env)
+}
+
+func (e *Evaluator) evalInfix(node *ast.InfixExpression, left, right object.Object) object.Object {
+	switch {
+	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
+		return e.evalIntegerInfix(node, left.(*object.Integer).Value, right.(*object.Integer).Value)
+	case left.Type() == object.DECIMAL_OBJ && right.Type() == object.DECIMAL_OBJ:
+		return e.evalDecimalInfix(node, left.(*object.Decimal).Value, right.(*object.Decimal).Value)
+	case left.Type() == object.CHAR_OBJ && right.Type() == object.CHAR_OBJ:
+		// chars are ordered by their code
+		l, r := int64(left.(*object.Char).Value), int64(right.(*object.Char).Value)
+		return e.evalIntegerInfix(node, l, r)
+	case node.Token.Kind == token.OP_EQ && left.Type() == right.Type():
+		return object.NativeBoolean(left.Inspect() == right.Inspect())
+	case node.Token.Kind == token.OP_NOTEQ && left.Type() == right.Type():
+		return object.NativeBoolean(left.Inspect() != right.Inspect())
+	}
+	e.error(node.Token, fmt.Sprintf(unknownOperatorError, left.Type(), node.Token.Spelling, right.Type()))
+	return nil
+}
+
+func (e *Evaluator) evalIntegerInfix(node *ast.InfixExpression, left, right int64) object.Object {
+	switch node.Token.Kind {
+	case token.OP_PLUS:
+		return &object.Integer{Value: left + right}
+	case token.OP_MINUS:
+		return &object.Integer{Value: left - right}
+	case token.OP_MULTI:
+		return &object.Integer{Value: left * right}
+	case token.OP_DIVIDE:
+		if right == 0 {
+			e.error(node.Token, divisionByZeroError)
+		}
+		return &object.Integer{Value: left / right}
+	case token.OP_EQ:
+		return object.NativeBoolean(left == right)
+	case token.OP_NOTEQ:
+		return object.NativeBoolean(left != right)
+	case token.OP_LT:
+		return object.NativeBoolean(left < right)
+	case token.OP_LTE:
+		return object.NativeBoolean(left <= right)
+	case token.OP_GT:
+		return object.NativeBoolean(left > right)
+	case token.OP_GTE:
+		return object.NativeBoolean(left >= right)
+	}
+	e.error(node.Token, fmt.Sprintf(unknownOperatorError, object.INTEGER_OBJ, node.Token.Spelling, object.INTEGER_OBJ))
+	return nil
+}
+
+func (e *Evaluator) evalDecimalInfix(node *ast.InfixExpression, left, right float64) object.Object {
+	switch node.Token.Kind {
+	case token.OP_PLUS:
+		return &object.Decimal{Value: left + right}
+	case token.OP_MINUS:
+		return &object.Decimal{Value: left - right}
+	case token.OP_MULTI:
+		return &object.Decimal{Value: left * right}
+	case token.OP_DIVIDE:
+		return &object.Decimal{Value: left / right}
+	case token.OP_EQ:
+		return object.NativeBoolean(left == right)
+	case token.OP_NOTEQ:
+		return object.NativeBoolean(left != right)
+	case token.OP_LT:
+		return object.NativeBoolean(left < right)
+	case token.OP_LTE:
+		return object.NativeBoolean(left <= right)
+	case token.OP_GT:
+		return object.NativeBoolean(left > right)
+	case token.OP_GTE:
+		return object.NativeBoolean(left >= right)
+	}
+	e.error(node.Token, fmt.Sprintf(unknownOperatorError, object.DECIMAL_OBJ, node.Token.Spelling, object.DECIMAL_OBJ))
+	return nil
+}
+
+func (e *Evaluator) evalCall(node *ast.CallExpression, env *object.Environment) object.Object {
+	callee := e.eval(node.Function, env)
+	args := make([]object.Object, len(node.Arguments))
+	for i, arg := range node.Arguments {
+		args[i] = e.eval(arg, env)
+	}
+	switch fn := callee.(type) {
+	case *object.Function:
+		return e.callFunction(node.Token, fn, args)
+	case *object.Builtin:
+		return fn.Fn(args...)
+	}
+	e.error(node.Token, fmt.Sprintf(notCallableError, node.Function.String()))
+	return nil
+}
+
+// callFunction calls fn with the arguments, failing at tok when too many
+// calls are running.
+func (e *Evaluator) callFunction(tok token.Token, fn *object.Function, args []object.Object) object.Object {
+	if e.frames == maxFrames {
+		e.error(tok, stackOverflowError)
+	}
+	e.frames++
+	defer func() { e.frames-- }()
+	env := object.NewEnclosedEnvironment(fn.Env)
+	for i, param := range fn.Decl.Parameters {
+		env.Set(param.ID.Value, args[i])
+	}
+	if ret, ok := e.execStatements(fn.Decl.Body.Statements, env).(*object.ReturnValue); ok {
+		return ret.Value
+	}
+	return object.VOID
+}
@@ -0,0 +1,43 @@
+package evaluator
+
+import (
+	"bytes"
+	"github.com/wevertonbruno/wb-compiler/ast"
+	"github.com/wevertonbruno/wb-compiler/wbtest"
+	"testing"
+)
+
+func TestPrograms(t *testing.T) {
+	wbtest.RunPrograms(t, func(t *testing.T, source string) (string, error) {
+		out := &bytes.Buffer{}
+		err := New(out).Run(parse(t, source))
+		return out.String(), err
+	})
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`func rec(n : Integer) : Integer {
+			return rec(n + 1) + 1
+		}
+		print(rec(0))`, "2:14: runtime error. stack overflow"},
+	}
+	for _, tt := range tests {
+		err := New(&bytes.Buffer{}).Run(parse(t, tt.input))
+		if err == nil {
+			t.Errorf("expected a runtime error for %q", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Prog {
+	prog, _ := wbtest.Check(t, input)
+	return prog
+}
@@ -0,0 +1,132 @@
+package ir
+
+// ComputeCFG rebuilds the predecessors and successors of every block from
+// the terminators. Passes that change the terminators must call it again.
+func (f *Func) ComputeCFG() {
+	for _, b := range f.Blocks {
+		b.Preds = nil
+		b.Succs = nil
+	}
+	for _, b := range f.Blocks {
+		term := b.Terminator()
+		if term == nil {
+			continue
+		}
+		for _, succ := range term.Blocks {
+			if !contains(b.Succs, succ) {
+				b.Succs = append(b.Succs, succ)
+				succ.Preds = append(succ.Preds, b)
+			}
+		}
+	}
+}
+
+// ReversePostorder returns the blocks reachable from the entry, each one
+// before its successors except along back edges.
+func (f *Func) ReversePostorder() []*Block {
+	visited := make(map[*Block]bool)
+	var postorder []*Block
+	var visit func(b *Block)
+	visit = func(b *Block) {
+		visited[b] = true
+		for _, succ := range b.Succs {
+			if !visited[succ] {
+				visit(succ)
+			}
+		}
+		postorder = append(postorder, b)
+	}
+	visit(f.Entry())
+
+	order := make([]*Block, len(postorder))
+	for i, b := range postorder {
+		order[len(postorder)-1-i] = b
+	}
+	return order
+}
+
+// Reachable returns the set of blocks reachable from the entry.
+func (f *Func) Reachable() map[*Block]bool {
+	reachable := make(map[*Block]bool)
+	for _, b := range f.ReversePostorder() {
+		reachable[b] = true
+	}
+	return reachable
+}
+
+// PredIndex returns the position of pred among the predecessors of b, or -1.
+func (b *Block) PredIndex(pred *Block) int {
+	for i, p := range b.Preds {
+		if p == pred {
+			return i
+		}
+	}
+	return -1
+}
+
+func contains(blocks []*Block, b *Block) bool {
+	for _, other := range blocks {
+		if other == b {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveUnreachable deletes the blocks that cannot be reached from the
+// entry and updates the control-flow graph.
+func (f *Func) RemoveUnreachable() {
+	reachable := f.Reachable()
+	blocks := f.Blocks[:0]
+	for _, b := range f.Blocks {
+		if reachable[b] {
+			blocks = append(blocks, b)
+		}
+	}
+	f.Blocks = blocks
+	f.ComputeCFG()
+}
+
+// NewBlockAfter returns a new empty block laid out right after prev, or
+// first when prev is nil.
+func (f *Func) NewBlockAfter(prev *Block) *Block {
+	b := f.NewBlock()
+	f.Blocks = f.Blocks[:len(f.Blocks)-1]
+	at := 0
+	for i, other := range f.Blocks {
+		if other == prev {
+			at = i + 1
+		}
+	}
+	f.Blocks = append(f.Blocks, nil)
+	copy(f.Blocks[at+1:], f.Blocks[at:])
+	f.Blocks[at] = b
+	return b
+}
+
+// SplitBlock moves the instructions of b from index i on to a new block
+// laid out after it, which b then jumps to, and returns the new block.
+func (f *Func) SplitBlock(b *Block, i int) *Block {
+	rest := f.NewBlockAfter(b)
+	rest.Instrs = append(rest.Instrs, b.Instrs[i:]...)
+	b.Instrs = append(b.Instrs[:i:i], &Instr{Op: OpJump, Blocks: []*Block{rest}})
+	// the successors are now reached from rest
+	var succs []*Block
+	if term := rest.Terminator(); term != nil {
+		succs = term.Blocks
+	}
+	for _, succ := range succs {
+		for _, instr := range succ.Instrs {
+			if instr.Op != OpPhi {
+				break
+			}
+			for j, pred := range instr.Blocks {
+				if pred == b {
+					instr.Blocks[j] = rest
+				}
+			}
+		}
+	}
+	f.ComputeCFG()
+	return rest
+}
@@ -0,0 +1,104 @@
+package ir
+
+// DomTree is the dominator tree of the blocks reachable from the entry of
+// a function, computed with the algorithm of Cooper, Harvey and Kennedy.
+type DomTree struct {
+	idom     map[*Block]*Block
+	children map[*Block][]*Block
+	order    []*Block // reverse postorder
+}
+
+// Dominators computes the dominator tree of the function. The control-flow
+// graph must be up to date.
+func (f *Func) Dominators() *DomTree {
+	order := f.ReversePostorder()
+	index := make(map[*Block]int, len(order))
+	for i, b := range order {
+		index[b] = i
+	}
+
+	entry := f.Entry()
+	idom := map[*Block]*Block{entry: entry}
+	intersect := func(a, b *Block) *Block {
+		for a != b {
+			for index[a] > index[b] {
+				a = idom[a]
+			}
+			for index[b] > index[a] {
+				b = idom[b]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, b := range order[1:] {
+			var dom *Block
+			for _, pred := range b.Preds {
+				if idom[pred] == nil {
+					// not processed yet, or unreachable
+					continue
+				}
+				if dom == nil {
+					dom = pred
+				} else {
+					dom = intersect(pred, dom)
+				}
+			}
+			if idom[b] != dom {
+				idom[b] = dom
+				changed = true
+			}
+		}
+	}
+
+	d := &DomTree{idom: idom, children: make(map[*Block][]*Block), order: order}
+	for _, b := range order[1:] {
+		d.children[idom[b]] = append(d.children[idom[b]], b)
+	}
+	delete(idom, entry)
+	return d
+}
+
+// Idom returns the immediate dominator of b, or nil for the entry block.
+func (d *DomTree) Idom(b *Block) *Block {
+	return d.idom[b]
+}
+
+// Children returns the blocks immediately dominated by b.
+func (d *DomTree) Children(b *Block) []*Block {
+	return d.children[b]
+}
+
+// Dominates reports whether every path from the entry to b goes through a.
+// Every block dominates itself.
+func (d *DomTree) Dominates(a, b *Block) bool {
+	for ; b != nil; b = d.idom[b] {
+		if a == b {
+			return true
+		}
+	}
+	return false
+}
+
+// Frontiers returns the dominance frontier of every block: the blocks
+// where its dominance ends, reached through one of their predecessors.
+func (d *DomTree) Frontiers() map[*Block][]*Block {
+	frontiers := make(map[*Block][]*Block)
+	for _, b := range d.order {
+		if len(b.Preds) < 2 {
+			continue
+		}
+		for _, pred := range b.Preds {
+			if _, reachable := d.idom[pred]; !reachable && pred != d.order[0] {
+				continue
+			}
+			for runner := pred; runner != nil && runner != d.idom[b]; runner = d.idom[runner] {
+				if !contains(frontiers[runner], b) {
+					frontiers[runner] = append(frontiers[runner], b)
+				}
+			}
+		}
+	}
+	return frontiers
+}
@@ -0,0 +1,89 @@
+package ir
+
+import (
+	"errors"
+	"github.com/wevertonbruno/wb-compiler/analyzers/semantic"
+)
+
+// ErrDivisionByZero is returned by Eval for an integer division by zero.
+var ErrDivisionByZero = errors.New("integer division by zero")
+
+// Eval computes an operation on constants the way the program would at
+// run time. It returns nil for the operations that cannot be evaluated
+// ahead of time, such as calls and loads.
+func Eval(op Op, args ...*Const) (*Const, error) {
+	switch {
+	case op == OpCopy:
+		return args[0], nil
+	case op == OpNeg && args[0].Type == semantic.Decimal:
+		return DecimalConst(-args[0].Decimal), nil
+	case op == OpNeg:
+		return IntConst(-args[0].Int), nil
+	case op == OpNot:
+		return BoolConst(args[0].Int == 0), nil
+	case op == OpStr:
+		return StringConst(args[0].Text()), nil
+	case op == OpConcat:
+		return StringConst(args[0].Str + args[1].Str), nil
+	case !op.IsBinary():
+		return nil, nil
+	case args[0].Type == semantic.Decimal:
+		return evalDecimal(op, args[0].Decimal, args[1].Decimal), nil
+	case args[0].Type == semantic.String:
+		// strings are only compared for equality
+		return BoolConst((args[0].Str == args[1].Str) == (op == OpEq)), nil
+	}
+	return evalInteger(op, args[0].Int, args[1].Int)
+}
+
+func evalInteger(op Op, a, b int64) (*Const, error) {
+	switch op {
+	case OpAdd:
+		return IntConst(a + b), nil
+	case OpSub:
+		return IntConst(a - b), nil
+	case OpMul:
+		return IntConst(a * b), nil
+	case OpDiv:
+		if b == 0 {
+			return nil, ErrDivisionByZero
+		}
+		// the most negative integer divided by -1 wraps around in Go too
+		return IntConst(a / b), nil
+	case OpEq:
+		return BoolConst(a == b), nil
+	case OpNe:
+		return BoolConst(a != b), nil
+	case OpLt:
+		return BoolConst(a < b), nil
+	case OpLe:
+		return BoolConst(a <= b), nil
+	case OpGt:
+		return BoolConst(a > b), nil
+	}
+	return BoolConst(a >= b), nil
+}
+
+func evalDecimal(op Op, a, b float64) *Const {
+	switch op {
+	case OpAdd:
+		return DecimalConst(a + b)
+	case OpSub:
+		return DecimalConst(a - b)
+	case OpMul:
+		return DecimalConst(a * b)
+	case OpDiv:
+		return DecimalConst(a / b)
+	case OpEq:
+		return BoolConst(a == b)
+	case OpNe:
+		return BoolConst(a != b)
+	case OpLt:
+		return BoolConst(a < b)
+	case OpLe:
+		return BoolConst(a <= b)
+	case OpGt:
+		return BoolConst(a > b)
+	}
+	return BoolConst(a >= b)
+}
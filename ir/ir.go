@@ -0,0 +1,395 @@
+// Package ir defines a three-address intermediate representation. Every
+// function is a control-flow graph of basic blocks, each a list of
+// instructions that ends with a single jump, branch or return.
+package ir
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/wevertonbruno/wb-compiler/analyzers/reader"
+	"github.com/wevertonbruno/wb-compiler/analyzers/semantic"
+	"github.com/wevertonbruno/wb-compiler/object"
+	"strconv"
+	"strings"
+)
+
+// EntryName names the function that runs the top level statements.
+const EntryName = "<main>"
+
+type Op byte
+
+const (
+	OpCopy Op = iota
+	OpNeg
+	OpNot
+	// OpStr converts a value to the String print would write for it
+	OpStr
+
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpEq
+	OpNe
+	OpLt
+	OpLe
+	OpGt
+	OpGe
+	OpConcat
+
+	OpLoad
+	OpStore
+	OpCall
+	OpPrint
+	OpPhi
+
+	// terminators
+	OpJump
+	OpBranch
+	OpReturn
+	OpUnreachable
+)
+
+var opNames = map[Op]string{
+	OpCopy:        "copy",
+	OpNeg:         "neg",
+	OpNot:         "not",
+	OpStr:         "str",
+	OpAdd:         "add",
+	OpSub:         "sub",
+	OpMul:         "mul",
+	OpDiv:         "div",
+	OpEq:          "eq",
+	OpNe:          "ne",
+	OpLt:          "lt",
+	OpLe:          "le",
+	OpGt:          "gt",
+	OpGe:          "ge",
+	OpConcat:      "concat",
+	OpLoad:        "load",
+	OpStore:       "store",
+	OpCall:        "call",
+	OpPrint:       "print",
+	OpPhi:         "phi",
+	OpJump:        "jmp",
+	OpBranch:      "br",
+	OpReturn:      "ret",
+	OpUnreachable: "unreachable",
+}
+
+func (op Op) String() string {
+	return opNames[op]
+}
+
+func (op Op) IsTerminator() bool {
+	return op >= OpJump
+}
+
+// IsBinary reports whether the operation takes two operands, the
+// arithmetic operations and the comparisons.
+func (op Op) IsBinary() bool {
+	return op >= OpAdd && op <= OpGe
+}
+
+func (op Op) IsComparison() bool {
+	return op >= OpEq && op <= OpGe
+}
+
+type (
+	// Operand is a *Var or a *Const.
+	Operand interface {
+		String() string
+		operand()
+	}
+
+	// Var is a local variable, a parameter or a temporary of a function.
+	// Names are unique within the function.
+	Var struct {
+		Name string
+		Type semantic.Type
+	}
+
+	// Const is a constant of Type. Integers, chars and booleans, as 0 or 1,
+	// are held in Int, and strings in Str.
+	Const struct {
+		Type    semantic.Type
+		Int     int64
+		Decimal float64
+		Str     string
+	}
+
+	Instr struct {
+		Op   Op
+		Dst  *Var
+		Args []Operand
+		// Name is the callee of a call, or the global of a load or store.
+		Name string
+		// Blocks are the targets of a jump or branch, the true target
+		// first, or the predecessors the arguments of a phi come from.
+		Blocks []*Block
+		// Pos locates the operator of a division, which may fail, the
+		// right operand of && and ||, which may be skipped, or else the
+		// statement the instruction was lowered from. The
+		// instructions the lowering adds on its own, such as the return
+		// at the end of a function, have none.
+		Pos reader.Position
+	}
+
+	Block struct {
+		ID     int
+		Instrs []*Instr
+		Preds  []*Block
+		Succs  []*Block
+	}
+
+	Func struct {
+		Name   string
+		Params []*Var
+		Result semantic.Type
+		// Blocks are in layout order, the entry block first.
+		Blocks []*Block
+
+		used     map[string]bool
+		versions map[string]int
+		temps    int
+		blocks   int
+	}
+
+	Global struct {
+		Name string
+		Type semantic.Type
+	}
+
+	Program struct {
+		Globals []*Global
+		// Funcs lists the functions in declaration order, followed by
+		// Entry, which runs the top level statements and then calls main.
+		Funcs []*Func
+		Entry *Func
+	}
+)
+
+func (v *Var) operand()   {}
+func (c *Const) operand() {}
+
+func (v *Var) String() string {
+	return v.Name
+}
+
+func (c *Const) String() string {
+	switch c.Type {
+	case semantic.Decimal:
+		s := strconv.FormatFloat(c.Decimal, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eIN") {
+			s += ".0"
+		}
+		return s
+	case semantic.Boolean:
+		return strconv.FormatBool(c.Int != 0)
+	case semantic.Char:
+		return strconv.QuoteRuneToASCII(rune(byte(c.Int)))
+	case semantic.String:
+		return strconv.QuoteToASCII(c.Str)
+	}
+	return strconv.FormatInt(c.Int, 10)
+}
+
+// Text returns what print writes for the constant.
+func (c *Const) Text() string {
+	switch c.Type {
+	case semantic.Decimal:
+		return object.FormatDecimal(c.Decimal)
+	case semantic.Boolean:
+		return strconv.FormatBool(c.Int != 0)
+	case semantic.Char:
+		return string([]byte{byte(c.Int)})
+	case semantic.String:
+		return c.Str
+	}
+	return strconv.FormatInt(c.Int, 10)
+}
+
+func IntConst(v int64) *Const {
+	return &Const{Type: semantic.Integer, Int: v}
+}
+
+func DecimalConst(v float64) *Const {
+	return &Const{Type: semantic.Decimal, Decimal: v}
+}
+
+func BoolConst(v bool) *Const {
+	if v {
+		return &Const{Type: semantic.Boolean, Int: 1}
+	}
+	return &Const{Type: semantic.Boolean}
+}
+
+func CharConst(v byte) *Const {
+	return &Const{Type: semantic.Char, Int: int64(v)}
+}
+
+func StringConst(v string) *Const {
+	return &Const{Type: semantic.String, Str: v}
+}
+
+// TypeOf returns the type of an operand.
+func TypeOf(op Operand) semantic.Type {
+	switch op := op.(type) {
+	case *Var:
+		return op.Type
+	case *Const:
+		return op.Type
+	}
+	return semantic.Invalid
+}
+
+func NewFunc(name string, result semantic.Type) *Func {
+	return &Func{
+		Name:     name,
+		Result:   result,
+		used:     make(map[string]bool),
+		versions: make(map[string]int),
+	}
+}
+
+// NewVar returns a variable named after name, renamed if the function
+// already has a variable with that name.
+func (f *Func) NewVar(name string, t semantic.Type) *Var {
+	unique := name
+	for f.used[unique] {
+		// dots cannot appear in identifiers
+		f.versions[name]++
+		unique = fmt.Sprintf("%s.%d", name, f.versions[name])
+	}
+	f.used[unique] = true
+	return &Var{Name: unique, Type: t}
+}
+
+// NewTemp returns a new temporary.
+func (f *Func) NewTemp(t semantic.Type) *Var {
+	f.temps++
+	return f.NewVar(fmt.Sprintf("%%%d", f.temps), t)
+}
+
+// NewBlock appends a new empty block to the function.
+func (f *Func) NewBlock() *Block {
+	b := &Block{}
+	f.AddBlock(b)
+	return b
+}
+
+// AddBlock numbers a block and appends it to the function.
+func (f *Func) AddBlock(b *Block) {
+	b.ID = f.blocks
+	f.blocks++
+	f.Blocks = append(f.Blocks, b)
+}
+
+func (f *Func) Entry() *Block {
+	return f.Blocks[0]
+}
+
+// Func returns the function with the given name, or nil.
+func (p *Program) Func(name string) *Func {
+	for _, fn := range p.Funcs {
+		if fn.Name == name {
+			return fn
+		}
+	}
+	return nil
+}
+
+func (b *Block) String() string {
+	return fmt.Sprintf("b%d", b.ID)
+}
+
+// Terminator returns the last instruction of the block, or nil if the
+// block does not end with a terminator yet.
+func (b *Block) Terminator() *Instr {
+	if len(b.Instrs) == 0 {
+		return nil
+	}
+	if last := b.Instrs[len(b.Instrs)-1]; last.Op.IsTerminator() {
+		return last
+	}
+	return nil
+}
+
+func (i *Instr) String() string {
+	var out bytes.Buffer
+	if i.Dst != nil {
+		fmt.Fprintf(&out, "%s = ", i.Dst)
+	}
+	out.WriteString(i.Op.String())
+	args := make([]string, len(i.Args))
+	for j, arg := range i.Args {
+		args[j] = arg.String()
+	}
+	switch i.Op {
+	case OpCall:
+		fmt.Fprintf(&out, " %s(%s)", i.Name, strings.Join(args, ", "))
+	case OpLoad:
+		fmt.Fprintf(&out, " @%s", i.Name)
+	case OpStore:
+		fmt.Fprintf(&out, " @%s, %s", i.Name, args[0])
+	case OpPhi:
+		for j, arg := range args {
+			if j > 0 {
+				out.WriteString(",")
+			}
+			fmt.Fprintf(&out, " [%s, %s]", arg, i.Blocks[j])
+		}
+	case OpJump:
+		fmt.Fprintf(&out, " %s", i.Blocks[0])
+	case OpBranch:
+		fmt.Fprintf(&out, " %s, %s, %s", args[0], i.Blocks[0], i.Blocks[1])
+	default:
+		if len(args) > 0 {
+			fmt.Fprintf(&out, " %s", strings.Join(args, ", "))
+		}
+	}
+	return out.String()
+}
+
+func (f *Func) String() string {
+	var out bytes.Buffer
+	params := make([]string, len(f.Params))
+	for i, param := range f.Params {
+		params[i] = fmt.Sprintf("%s : %s", param, param.Type)
+	}
+	fmt.Fprintf(&out, "func %s(%s)", f.Name, strings.Join(params, ", "))
+	if f.Result != semantic.Void {
+		fmt.Fprintf(&out, " : %s", f.Result)
+	}
+	out.WriteString(" {\n")
+	for _, b := range f.Blocks {
+		fmt.Fprintf(&out, "%s:", b)
+		if len(b.Preds) > 0 {
+			preds := make([]string, len(b.Preds))
+			for i, pred := range b.Preds {
+				preds[i] = pred.String()
+			}
+			fmt.Fprintf(&out, " ; preds %s", strings.Join(preds, ", "))
+		}
+		out.WriteString("\n")
+		for _, instr := range b.Instrs {
+			fmt.Fprintf(&out, "    %s\n", instr)
+		}
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+func (p *Program) String() string {
+	var out bytes.Buffer
+	for _, global := range p.Globals {
+		fmt.Fprintf(&out, "global @%s : %s\n", global.Name, global.Type)
+	}
+	for i, fn := range p.Funcs {
+		if i > 0 || len(p.Globals) > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString(fn.String())
+	}
+	return out.String()
+}
@@ -0,0 +1,214 @@
+package ir_test
+
+import (
+	"flag"
+	"github.com/wevertonbruno/wb-compiler/ir"
+	"github.com/wevertonbruno/wb-compiler/ir/irtest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestGolden(t *testing.T) {
+	sources, err := filepath.Glob(filepath.Join("testdata", "*.wb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, source := range sources {
+		input, err := os.ReadFile(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dump := irtest.Lower(t, string(input)).String()
+
+		golden := strings.TrimSuffix(source, ".wb") + ".ir"
+		if *update {
+			if err := os.WriteFile(golden, []byte(dump), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		expected, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dump != string(expected) {
+			t.Errorf("%s does not match %s. got=\n%s", source, golden, dump)
+		}
+	}
+}
+
+func TestLower(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"print(-1 + 2 * 3)", `func <main>() {
+b0:
+    %1 = neg 1
+    %2 = mul 2, 3
+    %3 = add %1, %2
+    print %3
+    ret
+}
+`},
+		{`func f(a : Decimal) : Boolean {
+			var b : Decimal = a
+			return b < 1.5
+		}`, `func f(a : Decimal) : Boolean {
+b0:
+    b = copy a
+    %1 = lt b, 1.5
+    ret %1
+}
+
+func <main>() {
+b0:
+    ret
+}
+`},
+		{`var g : Integer = 1
+		func f(a : Integer) {
+			var b : Integer = a
+			b = b * 2
+			a = b
+			g = a
+		}`, `global @g : Integer
+
+func f(a : Integer) {
+b0:
+    b = copy a
+    %1 = mul b, 2
+    b = copy %1
+    a = copy b
+    store @g, a
+    ret
+}
+
+func <main>() {
+b0:
+    store @g, 1
+    ret
+}
+`},
+		{`func f(n : Integer, s : String) {
+			print("${n}: ${s}")
+		}`, `func f(n : Integer, s : String) {
+b0:
+    %1 = str n
+    %2 = concat %1, ": "
+    %3 = concat %2, s
+    print %3
+    ret
+}
+
+func <main>() {
+b0:
+    ret
+}
+`},
+		{`func f(a : Boolean, b : Boolean) : Boolean {
+			return a || b && 1 < 2
+		}`, `func f(a : Boolean, b : Boolean) : Boolean {
+b0:
+    %1 = copy a
+    br a, b4, b1
+b1: ; preds b0
+    %2 = copy b
+    br b, b2, b3
+b2: ; preds b1
+    %3 = lt 1, 2
+    %2 = copy %3
+    jmp b3
+b3: ; preds b1, b2
+    %1 = copy %2
+    jmp b4
+b4: ; preds b0, b3
+    ret %1
+}
+
+func <main>() {
+b0:
+    ret
+}
+`},
+		{`func f() {
+			return
+			print(1)
+		}`, `func f() {
+b0:
+    ret
+b1:
+    print 1
+    ret
+}
+
+func <main>() {
+b0:
+    ret
+}
+`},
+	}
+	for _, tt := range tests {
+		if got := irtest.Lower(t, tt.input).String(); got != tt.expected {
+			t.Errorf("wrong IR for %q. expected=\n%s\ngot=\n%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestCFG(t *testing.T) {
+	p := irtest.Lower(t, `func f(n : Integer) : Integer {
+		while n > 0 {
+			if (n == 3) {
+				return n
+			}
+			print(n)
+		}
+		return 0
+	}`)
+	fn := p.Func("f")
+	if fn == nil {
+		t.Fatal("function f not lowered")
+	}
+
+	succs := make(map[string][]string)
+	for _, b := range fn.Blocks {
+		for _, succ := range b.Succs {
+			succs[b.String()] = append(succs[b.String()], succ.String())
+		}
+		for _, pred := range b.Preds {
+			if b.PredIndex(pred) < 0 || !strings.Contains(" "+blocksString(pred.Succs)+" ", " "+b.String()+" ") {
+				t.Errorf("%s is a predecessor of %s without the matching edge", pred, b)
+			}
+		}
+	}
+	expected := map[string][]string{
+		"b0": {"b1"},
+		"b1": {"b2", "b5"},
+		"b2": {"b3", "b4"},
+		"b4": {"b1"},
+	}
+	for name, want := range expected {
+		if strings.Join(succs[name], " ") != strings.Join(want, " ") {
+			t.Errorf("wrong successors of %s. expected=%v, got=%v", name, want, succs[name])
+		}
+	}
+
+	order := fn.ReversePostorder()
+	if order[0] != fn.Entry() {
+		t.Errorf("reverse postorder does not start at the entry. got=%s", order[0])
+	}
+	position := make(map[*ir.Block]int)
+	for i, b := range order {
+		position[b] = i
+	}
+	if len(order) != len(fn.Blocks) {
+		t.Errorf("expected every block to be reachable. got=%v", order)
+	}
+	if position[fn.Blocks[1]] > position[fn.Blocks[2]] {
+		t.Errorf("loop header after loop body in %v", order)
+	}
+}
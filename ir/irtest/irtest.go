@@ -0,0 +1,132 @@
+// Package irtest helps testing the intermediate representation and the
+// passes over it. It lowers sources and interprets the result, so that the
+// tests can check that a pass keeps the output of a program.
+package irtest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/wevertonbruno/wb-compiler/ir"
+	"github.com/wevertonbruno/wb-compiler/wbtest"
+	"testing"
+)
+
+// maxSteps stops programs that loop forever after a broken pass.
+const maxSteps = 1000000
+
+// Lower parses, checks and lowers a source, failing the test on errors.
+func Lower(t *testing.T, input string) *ir.Program {
+	t.Helper()
+	return ir.Lower(wbtest.Check(t, input))
+}
+
+// Execute interprets a program and returns what it printed. Phis are
+// supported, so a program may be executed in SSA form.
+func Execute(p *ir.Program) (string, error) {
+	m := &machine{prog: p, globals: make(map[string]*ir.Const)}
+	_, err := m.call(p.Entry, nil)
+	return m.out.String(), err
+}
+
+type machine struct {
+	prog    *ir.Program
+	globals map[string]*ir.Const
+	out     bytes.Buffer
+	steps   int
+}
+
+func (m *machine) call(fn *ir.Func, args []*ir.Const) (*ir.Const, error) {
+	frame := make(map[*ir.Var]*ir.Const)
+	for i, param := range fn.Params {
+		frame[param] = args[i]
+	}
+	value := func(op ir.Operand) *ir.Const {
+		if c, ok := op.(*ir.Const); ok {
+			return c
+		}
+		if c, ok := frame[op.(*ir.Var)]; ok {
+			return c
+		}
+		return &ir.Const{Type: op.(*ir.Var).Type}
+	}
+
+	var prev *ir.Block
+	b := fn.Entry()
+	for {
+		// the phis of a block read their arguments at the same time
+		merged := make(map[*ir.Var]*ir.Const)
+		for _, instr := range b.Instrs {
+			if instr.Op != ir.OpPhi {
+				break
+			}
+			for i, pred := range instr.Blocks {
+				if pred == prev {
+					merged[instr.Dst] = value(instr.Args[i])
+				}
+			}
+		}
+		for v, c := range merged {
+			frame[v] = c
+		}
+
+		var next *ir.Block
+		for _, instr := range b.Instrs {
+			if m.steps++; m.steps > maxSteps {
+				return nil, errors.New("too many steps")
+			}
+			args := make([]*ir.Const, len(instr.Args))
+			for i, arg := range instr.Args {
+				args[i] = value(arg)
+			}
+			var result *ir.Const
+			switch instr.Op {
+			case ir.OpPhi:
+				continue
+			case ir.OpLoad:
+				result = m.globals[instr.Name]
+			case ir.OpStore:
+				m.globals[instr.Name] = args[0]
+			case ir.OpPrint:
+				for _, arg := range args {
+					fmt.Fprintln(&m.out, arg.Text())
+				}
+			case ir.OpCall:
+				callee := m.prog.Func(instr.Name)
+				if callee == nil {
+					return nil, fmt.Errorf("call to unknown function %s", instr.Name)
+				}
+				var err error
+				if result, err = m.call(callee, args); err != nil {
+					return nil, err
+				}
+			case ir.OpJump:
+				next = instr.Blocks[0]
+			case ir.OpBranch:
+				next = instr.Blocks[1]
+				if args[0].Int != 0 {
+					next = instr.Blocks[0]
+				}
+			case ir.OpReturn:
+				if len(args) == 0 {
+					return nil, nil
+				}
+				return args[0], nil
+			case ir.OpUnreachable:
+				return nil, errors.New("reached unreachable")
+			default:
+				var err error
+				if result, err = ir.Eval(instr.Op, args...); err != nil {
+					return nil, fmt.Errorf("%d:%d: %v", instr.Pos.Line, instr.Pos.Column, err)
+				}
+			}
+			if instr.Dst != nil {
+				frame[instr.Dst] = result
+			}
+		}
+		if next == nil {
+			return nil, fmt.Errorf("%s of %s has no terminator", b, fn.Name)
+		}
+		prev, b = b, next
+	}
+}
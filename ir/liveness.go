@@ -0,0 +1,100 @@
+package ir
+
+// Liveness holds the variables live on entry to and on exit from every
+// block of a function.
+type Liveness struct {
+	in  map[*Block]map[*Var]bool
+	out map[*Block]map[*Var]bool
+}
+
+// Liveness computes the live variables of the function with the usual
+// backward dataflow analysis. The arguments of a phi are live on exit
+// from the predecessor they come from, not on entry to the phi's block.
+// The control-flow graph must be up to date.
+func (f *Func) Liveness() *Liveness {
+	l := &Liveness{in: make(map[*Block]map[*Var]bool), out: make(map[*Block]map[*Var]bool)}
+	uses := make(map[*Block]map[*Var]bool)
+	defs := make(map[*Block]map[*Var]bool)
+	for _, b := range f.Blocks {
+		uses[b], defs[b] = make(map[*Var]bool), make(map[*Var]bool)
+		for _, instr := range b.Instrs {
+			if instr.Op != OpPhi {
+				for _, arg := range instr.Args {
+					if v, ok := arg.(*Var); ok && !defs[b][v] {
+						uses[b][v] = true
+					}
+				}
+			}
+			if instr.Dst != nil {
+				defs[b][instr.Dst] = true
+			}
+		}
+		l.in[b], l.out[b] = make(map[*Var]bool), make(map[*Var]bool)
+	}
+
+	order := f.ReversePostorder()
+	for changed := true; changed; {
+		changed = false
+		for i := len(order) - 1; i >= 0; i-- {
+			b := order[i]
+			out := l.out[b]
+			for _, succ := range b.Succs {
+				for v := range l.in[succ] {
+					if !out[v] {
+						out[v], changed = true, true
+					}
+				}
+				for _, instr := range succ.Instrs {
+					if instr.Op != OpPhi {
+						break
+					}
+					for j, pred := range instr.Blocks {
+						if v, ok := instr.Args[j].(*Var); ok && pred == b && !out[v] {
+							out[v], changed = true, true
+						}
+					}
+				}
+			}
+			in := l.in[b]
+			for v := range uses[b] {
+				if !in[v] {
+					in[v], changed = true, true
+				}
+			}
+			for v := range out {
+				if !defs[b][v] && !in[v] {
+					in[v], changed = true, true
+				}
+			}
+		}
+	}
+	return l
+}
+
+// LiveIn reports whether v is live on entry to b.
+func (l *Liveness) LiveIn(b *Block, v *Var) bool {
+	return l.in[b][v]
+}
+
+// LiveOut reports whether v is live on exit from b.
+func (l *Liveness) LiveOut(b *Block, v *Var) bool {
+	return l.out[b][v]
+}
+
+// In returns the variables live on entry to b, in no particular order.
+func (l *Liveness) In(b *Block) []*Var {
+	return keys(l.in[b])
+}
+
+// Out returns the variables live on exit from b, in no particular order.
+func (l *Liveness) Out(b *Block) []*Var {
+	return keys(l.out[b])
+}
+
+func keys(set map[*Var]bool) []*Var {
+	vars := make([]*Var, 0, len(set))
+	for v := range set {
+		vars = append(vars, v)
+	}
+	return vars
+}
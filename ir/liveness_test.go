@@ -0,0 +1,59 @@
+package ir_test
+
+import (
+	"github.com/wevertonbruno/wb-compiler/ir"
+	"github.com/wevertonbruno/wb-compiler/ir/irtest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestLiveness(t *testing.T) {
+	fn := irtest.Lower(t, `func f(n : Integer, d : Decimal) {
+		var k : Integer = n * 2
+		while n > k {
+			print(d)
+		}
+		print(k)
+	}`).Func("f")
+	// b0 defines k, b1 is the loop header, b2 the body and b3 the exit
+	b := fn.Blocks
+	live := fn.Liveness()
+	tests := []struct {
+		block   *ir.Block
+		in, out string
+	}{
+		{b[0], "d n", "d k n"},
+		{b[1], "d k n", "d k n"},
+		{b[2], "d k n", "d k n"},
+		{b[3], "k", ""},
+	}
+	for _, tt := range tests {
+		if got := names(live.In(tt.block)); got != tt.in {
+			t.Errorf("wrong live variables on entry to %s. expected=%q, got=%q", tt.block, tt.in, got)
+		}
+		if got := names(live.Out(tt.block)); got != tt.out {
+			t.Errorf("wrong live variables on exit from %s. expected=%q, got=%q", tt.block, tt.out, got)
+		}
+	}
+
+	swap := swapLoop().Entry
+	swap.BuildSSA()
+	live = swap.Liveness()
+	// the phis of the loop header read x and y on exit from the entry
+	if got := names(live.Out(swap.Blocks[0])); got != "i x y" {
+		t.Errorf("wrong live variables on exit from the entry. got=%q", got)
+	}
+	if got := names(live.In(swap.Blocks[1])); got != "" {
+		t.Errorf("phi results live on entry to their block. got=%q", got)
+	}
+}
+
+func names(vars []*ir.Var) string {
+	s := make([]string, len(vars))
+	for i, v := range vars {
+		s[i] = v.Name
+	}
+	sort.Strings(s)
+	return strings.Join(s, " ")
+}
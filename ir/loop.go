@@ -0,0 +1,124 @@
+package ir
+
+import "sort"
+
+// Loop is a natural loop: a header and the blocks that reach one of its
+// back edges without going through the header.
+type Loop struct {
+	Header *Block
+	// Blocks are the blocks of the loop, the header first and those of
+	// the inner loops included.
+	Blocks []*Block
+	// Latches are the blocks with a back edge to the header.
+	Latches []*Block
+	// Parent is the innermost loop around this one, or nil.
+	Parent *Loop
+}
+
+// Contains reports whether b belongs to the loop.
+func (l *Loop) Contains(b *Block) bool {
+	return contains(l.Blocks, b)
+}
+
+// Loops finds the natural loops of a function from the back edges, the
+// edges to a block that dominates their source. The back edges to the
+// same header make up a single loop. Inner loops come before the loops
+// around them.
+func (f *Func) Loops(dom *DomTree) []*Loop {
+	var loops []*Loop
+	byHeader := make(map[*Block]*Loop)
+	for _, b := range f.ReversePostorder() {
+		for _, succ := range b.Succs {
+			if !dom.Dominates(succ, b) {
+				continue
+			}
+			l := byHeader[succ]
+			if l == nil {
+				l = &Loop{Header: succ, Blocks: []*Block{succ}}
+				byHeader[succ] = l
+				loops = append(loops, l)
+			}
+			l.Latches = append(l.Latches, b)
+			// walk back from the latch up to the header
+			work := []*Block{b}
+			for len(work) > 0 {
+				block := work[len(work)-1]
+				work = work[:len(work)-1]
+				if l.Contains(block) {
+					continue
+				}
+				l.Blocks = append(l.Blocks, block)
+				work = append(work, block.Preds...)
+			}
+		}
+	}
+
+	sort.SliceStable(loops, func(i, j int) bool {
+		return len(loops[i].Blocks) < len(loops[j].Blocks)
+	})
+	for i, l := range loops {
+		for _, outer := range loops[i+1:] {
+			if outer.Contains(l.Header) {
+				l.Parent = outer
+				break
+			}
+		}
+	}
+	return loops
+}
+
+// Preheader returns the block that runs right before the loop is entered,
+// the only predecessor of the header from outside the loop, whose only
+// successor is the header. The block is added when the loop has none,
+// taking the arguments of the phis of the header that came from outside,
+// and becomes part of the loops around. The header must not be the entry
+// of the function.
+func (f *Func) Preheader(l *Loop) *Block {
+	var outside []*Block
+	for _, pred := range l.Header.Preds {
+		if !l.Contains(pred) {
+			outside = append(outside, pred)
+		}
+	}
+	if len(outside) == 1 && len(outside[0].Succs) == 1 {
+		return outside[0]
+	}
+
+	var prev *Block
+	for i, b := range f.Blocks {
+		if b == l.Header && i > 0 {
+			prev = f.Blocks[i-1]
+		}
+	}
+	pre := f.NewBlockAfter(prev)
+	for _, pred := range outside {
+		for i, target := range pred.Terminator().Blocks {
+			if target == l.Header {
+				pred.Terminator().Blocks[i] = pre
+			}
+		}
+	}
+	for _, phi := range l.Header.Instrs[:phiCount(l.Header)] {
+		merged := &Instr{Op: OpPhi, Dst: f.NewVar(phi.Dst.Name, phi.Dst.Type), Pos: phi.Pos}
+		var args []Operand
+		var blocks []*Block
+		for i, from := range phi.Blocks {
+			if contains(outside, from) {
+				merged.Args = append(merged.Args, phi.Args[i])
+				merged.Blocks = append(merged.Blocks, from)
+			} else {
+				args = append(args, phi.Args[i])
+				blocks = append(blocks, from)
+			}
+		}
+		phi.Args = append(args, merged.Dst)
+		phi.Blocks = append(blocks, pre)
+		pre.Instrs = append(pre.Instrs, merged)
+	}
+	pre.Instrs = append(pre.Instrs, &Instr{Op: OpJump, Blocks: []*Block{l.Header}})
+	for outer := l.Parent; outer != nil; outer = outer.Parent {
+		outer.Blocks = append(outer.Blocks, pre)
+	}
+	f.ComputeCFG()
+	return pre
+}
@@ -0,0 +1,97 @@
+package ir_test
+
+import (
+	"github.com/wevertonbruno/wb-compiler/analyzers/semantic"
+	"github.com/wevertonbruno/wb-compiler/ir"
+	"github.com/wevertonbruno/wb-compiler/ir/irtest"
+	"testing"
+)
+
+func TestLoops(t *testing.T) {
+	// b1 is the header of the outer loop, b2 of the inner one, whose body
+	// is b3. b4 is the latch of the outer loop and b5 the exit.
+	fn := ir.NewFunc(ir.EntryName, semantic.Void)
+	c := fn.NewVar("c", semantic.Boolean)
+	b := []*ir.Block{fn.NewBlock(), fn.NewBlock(), fn.NewBlock(), fn.NewBlock(), fn.NewBlock(), fn.NewBlock()}
+	jump := func(to *ir.Block) *ir.Instr {
+		return &ir.Instr{Op: ir.OpJump, Blocks: []*ir.Block{to}}
+	}
+	branch := func(yes, no *ir.Block) *ir.Instr {
+		return &ir.Instr{Op: ir.OpBranch, Args: []ir.Operand{c}, Blocks: []*ir.Block{yes, no}}
+	}
+	b[0].Instrs = []*ir.Instr{jump(b[1])}
+	b[1].Instrs = []*ir.Instr{branch(b[2], b[5])}
+	b[2].Instrs = []*ir.Instr{branch(b[3], b[4])}
+	b[3].Instrs = []*ir.Instr{jump(b[2])}
+	b[4].Instrs = []*ir.Instr{jump(b[1])}
+	b[5].Instrs = []*ir.Instr{{Op: ir.OpReturn}}
+	fn.ComputeCFG()
+
+	loops := fn.Loops(fn.Dominators())
+	if len(loops) != 2 {
+		t.Fatalf("wrong number of loops. expected=2, got=%d", len(loops))
+	}
+	inner, outer := loops[0], loops[1]
+	if inner.Header != b[2] || blocksString(inner.Latches) != "b3" || inner.Parent != outer {
+		t.Errorf("wrong inner loop. header=%s, latches=%v", inner.Header, inner.Latches)
+	}
+	if outer.Header != b[1] || blocksString(outer.Latches) != "b4" || outer.Parent != nil {
+		t.Errorf("wrong outer loop. header=%s, latches=%v", outer.Header, outer.Latches)
+	}
+	for i, block := range b {
+		if in := i == 2 || i == 3; inner.Contains(block) != in {
+			t.Errorf("wrong membership of %s in the inner loop. expected=%v", block, in)
+		}
+		if in := i >= 1 && i <= 4; outer.Contains(block) != in {
+			t.Errorf("wrong membership of %s in the outer loop. expected=%v", block, in)
+		}
+	}
+
+	// b1 also branches to the exit
+	if pre := fn.Preheader(inner); pre == b[1] || blocksString(pre.Succs) != "b2" || !outer.Contains(pre) {
+		t.Errorf("wrong preheader %s\n%s", pre, fn)
+	}
+	if pre := fn.Preheader(outer); pre != b[0] {
+		t.Errorf("wrong preheader. expected=b0, got=%s", pre)
+	}
+}
+
+func TestPreheader(t *testing.T) {
+	// the loop is entered from two blocks, with a different value each
+	fn := ir.NewFunc(ir.EntryName, semantic.Void)
+	x := fn.NewVar("x", semantic.Integer)
+	next := fn.NewVar("x", semantic.Integer)
+	c := fn.NewVar("c", semantic.Boolean)
+	entry, a, b, header, body, exit := fn.NewBlock(), fn.NewBlock(), fn.NewBlock(), fn.NewBlock(), fn.NewBlock(), fn.NewBlock()
+	entry.Instrs = []*ir.Instr{{Op: ir.OpBranch, Args: []ir.Operand{ir.BoolConst(false)}, Blocks: []*ir.Block{a, b}}}
+	a.Instrs = []*ir.Instr{{Op: ir.OpJump, Blocks: []*ir.Block{header}}}
+	b.Instrs = []*ir.Instr{{Op: ir.OpJump, Blocks: []*ir.Block{header}}}
+	header.Instrs = []*ir.Instr{
+		{Op: ir.OpPhi, Dst: x, Args: []ir.Operand{ir.IntConst(1), ir.IntConst(2), next}, Blocks: []*ir.Block{a, b, body}},
+		{Op: ir.OpLt, Dst: c, Args: []ir.Operand{x, ir.IntConst(5)}},
+		{Op: ir.OpBranch, Args: []ir.Operand{c}, Blocks: []*ir.Block{body, exit}},
+	}
+	body.Instrs = []*ir.Instr{
+		{Op: ir.OpPrint, Args: []ir.Operand{x}},
+		{Op: ir.OpAdd, Dst: next, Args: []ir.Operand{x, ir.IntConst(1)}},
+		{Op: ir.OpJump, Blocks: []*ir.Block{header}},
+	}
+	exit.Instrs = []*ir.Instr{{Op: ir.OpReturn}}
+	fn.ComputeCFG()
+	p := &ir.Program{Funcs: []*ir.Func{fn}, Entry: fn}
+
+	loops := fn.Loops(fn.Dominators())
+	pre := fn.Preheader(loops[0])
+	if pre == a || pre == b || blocksString(pre.Succs) != header.String() {
+		t.Fatalf("wrong preheader %s\n%s", pre, fn)
+	}
+	if preds := blocksString(header.Preds); preds != pre.String()+" "+body.String() && preds != body.String()+" "+pre.String() {
+		t.Errorf("wrong predecessors of the header. got=%s", preds)
+	}
+	if got := header.Instrs[0].String(); got != "x = phi [x.1, b4], [x.2, b6]" {
+		t.Errorf("wrong phi in the header. got=%q", got)
+	}
+	if out, err := irtest.Execute(p); err != nil || out != "2\n3\n4\n" {
+		t.Errorf("wrong output. got=%q, %v\n%s", out, err, fn)
+	}
+}
@@ -0,0 +1,337 @@
+package ir
+
+import (
+	"github.com/wevertonbruno/wb-compiler/analyzers/reader"
+	"github.com/wevertonbruno/wb-compiler/analyzers/semantic"
+	"github.com/wevertonbruno/wb-compiler/analyzers/token"
+	"github.com/wevertonbruno/wb-compiler/ast"
+)
+
+const entryPoint = "main"
+
+var (
+	prefixOps = map[token.Kind]Op{
+		token.OP_MINUS: OpNeg,
+		token.NOT:      OpNot,
+	}
+
+	infixOps = map[token.Kind]Op{
+		token.OP_PLUS:   OpAdd,
+		token.OP_MINUS:  OpSub,
+		token.OP_MULTI:  OpMul,
+		token.OP_DIVIDE: OpDiv,
+		token.OP_EQ:     OpEq,
+		token.OP_NOTEQ:  OpNe,
+		token.OP_LT:     OpLt,
+		token.OP_LTE:    OpLe,
+		token.OP_GT:     OpGt,
+		token.OP_GTE:    OpGe,
+	}
+)
+
+type lowerer struct {
+	info    *semantic.Info
+	globals map[*semantic.Symbol]*Global
+
+	// state of the function being lowered
+	fn    *Func
+	vars  map[*semantic.Symbol]*Var
+	block *Block          // nil after a terminator, until the next block starts
+	pos   reader.Position // of the statement being lowered
+}
+
+// Lower translates a checked program to the intermediate representation.
+func Lower(prog *ast.Prog, info *semantic.Info) *Program {
+	l := &lowerer{info: info, globals: make(map[*semantic.Symbol]*Global)}
+	p := &Program{}
+
+	var main *ast.FuncDecl
+	for _, stmt := range prog.Statements {
+		switch node := stmt.(type) {
+		case *ast.DeclStatement:
+			global := &Global{Name: node.ID.Value, Type: semantic.TypeOf(node.Type)}
+			l.globals[info.Defs[node.ID]] = global
+			p.Globals = append(p.Globals, global)
+		case *ast.FuncDecl:
+			if node.Name.Value == entryPoint && len(node.Parameters) == 0 {
+				main = node
+			}
+		}
+	}
+
+	for _, stmt := range prog.Statements {
+		if fn, ok := stmt.(*ast.FuncDecl); ok {
+			p.Funcs = append(p.Funcs, l.lowerFunction(fn))
+		}
+	}
+
+	l.begin(NewFunc(EntryName, semantic.Void))
+	for _, stmt := range prog.Statements {
+		if _, ok := stmt.(*ast.FuncDecl); !ok {
+			l.lowerStatement(stmt)
+		}
+	}
+	if main != nil {
+		l.emit(&Instr{Op: OpCall, Name: main.Name.Value})
+	}
+	p.Entry = l.end()
+	p.Funcs = append(p.Funcs, p.Entry)
+	return p
+}
+
+func (l *lowerer) begin(fn *Func) {
+	l.fn = fn
+	l.vars = make(map[*semantic.Symbol]*Var)
+	l.start(&Block{})
+}
+
+// end terminates the last block and computes the control-flow graph.
+func (l *lowerer) end() *Func {
+	if l.block != nil {
+		if l.fn.Result == semantic.Void {
+			l.emit(&Instr{Op: OpReturn})
+		} else {
+			// the checker guarantees that every path returned
+			l.emit(&Instr{Op: OpUnreachable})
+		}
+	}
+	l.fn.ComputeCFG()
+	return l.fn
+}
+
+func (l *lowerer) lowerFunction(decl *ast.FuncDecl) *Func {
+	l.begin(NewFunc(decl.Name.Value, semantic.TypeOf(decl.ReturnType)))
+	for _, param := range decl.Parameters {
+		v := l.fn.NewVar(param.ID.Value, semantic.TypeOf(param.Type))
+		l.vars[l.info.Defs[param.ID]] = v
+		l.fn.Params = append(l.fn.Params, v)
+	}
+	l.lowerStatements(decl.Body.Statements)
+	return l.end()
+}
+
+// start makes b the current block, falling through from the previous one.
+func (l *lowerer) start(b *Block) {
+	if l.block != nil {
+		l.jump(b)
+	}
+	l.fn.AddBlock(b)
+	l.block = b
+}
+
+func (l *lowerer) emit(instr *Instr) {
+	if l.block == nil {
+		// code after a return still goes somewhere, even if it never runs
+		l.start(&Block{})
+	}
+	if instr.Pos == (reader.Position{}) {
+		instr.Pos = l.pos
+	}
+	l.block.Instrs = append(l.block.Instrs, instr)
+	if instr.Op.IsTerminator() {
+		l.block = nil
+	}
+}
+
+// emitValue emits an instruction that defines a new temporary.
+func (l *lowerer) emitValue(t semantic.Type, instr *Instr) Operand {
+	instr.Dst = l.fn.NewTemp(t)
+	l.emit(instr)
+	return instr.Dst
+}
+
+func (l *lowerer) jump(target *Block) {
+	l.emit(&Instr{Op: OpJump, Blocks: []*Block{target}})
+}
+
+func (l *lowerer) branch(cond Operand, then, otherwise *Block) {
+	l.emit(&Instr{Op: OpBranch, Args: []Operand{cond}, Blocks: []*Block{then, otherwise}})
+}
+
+func (l *lowerer) lowerStatements(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		l.lowerStatement(stmt)
+	}
+}
+
+func (l *lowerer) lowerStatement(stmt ast.Stmt) {
+	defer func(pos reader.Position) { l.pos = pos }(l.pos)
+	l.pos = statementPos(stmt)
+
+	switch node := stmt.(type) {
+	case *ast.DeclStatement:
+		value := l.lowerExpression(node.Value)
+		sym := l.info.Defs[node.ID]
+		if global, ok := l.globals[sym]; ok {
+			l.emit(&Instr{Op: OpStore, Name: global.Name, Args: []Operand{value}})
+			return
+		}
+		v := l.fn.NewVar(node.ID.Value, semantic.TypeOf(node.Type))
+		l.vars[sym] = v
+		l.emit(&Instr{Op: OpCopy, Dst: v, Args: []Operand{value}})
+	case *ast.AssignStatement:
+		value := l.lowerExpression(node.Value)
+		sym := l.info.Uses[node.Target]
+		if global, ok := l.globals[sym]; ok {
+			l.emit(&Instr{Op: OpStore, Name: global.Name, Args: []Operand{value}})
+			return
+		}
+		l.emit(&Instr{Op: OpCopy, Dst: l.vars[sym], Args: []Operand{value}})
+	case *ast.ReturnStatement:
+		if node.Expr == nil {
+			l.emit(&Instr{Op: OpReturn})
+			return
+		}
+		l.emit(&Instr{Op: OpReturn, Args: []Operand{l.lowerExpression(node.Expr)}})
+	case *ast.ExprStatement:
+		l.lowerExpression(node.Expr)
+	case *ast.WhileStatement:
+		cond, body, end := &Block{}, &Block{}, &Block{}
+		l.start(cond)
+		l.branch(l.lowerExpression(node.Condition), body, end)
+		l.start(body)
+		l.lowerStatements(node.Body.Statements)
+		if l.block != nil {
+			l.jump(cond)
+		}
+		l.start(end)
+	case *ast.BlockStatement:
+		l.lowerStatements(node.Statements)
+	}
+}
+
+// statementPos returns the position of the first token of a statement.
+func statementPos(stmt ast.Stmt) reader.Position {
+	switch node := stmt.(type) {
+	case *ast.DeclStatement:
+		return node.Token.Position
+	case *ast.AssignStatement:
+		return node.Target.Token.Position
+	case *ast.ReturnStatement:
+		return node.Token.Position
+	case *ast.ExprStatement:
+		return node.Token.Position
+	case *ast.WhileStatement:
+		return node.Token.Position
+	case *ast.BlockStatement:
+		return node.Token.Position
+	}
+	return reader.Position{}
+}
+
+func (l *lowerer) lowerIf(node *ast.IfExpression) {
+	then, end := &Block{}, &Block{}
+	otherwise := end
+	if node.FalseBlockCondition != nil {
+		otherwise = &Block{}
+	}
+	l.branch(l.lowerExpression(node.Condition), then, otherwise)
+	l.start(then)
+	l.lowerStatements(node.TrueBlockCondition.Statements)
+	if node.FalseBlockCondition != nil {
+		if l.block != nil {
+			l.jump(end)
+		}
+		l.start(otherwise)
+		l.lowerStatements(node.FalseBlockCondition.Statements)
+	}
+	l.start(end)
+}
+
+// lowerExpression returns the operand holding the value of the expression,
+// or nil for expressions without a value.
+func (l *lowerer) lowerExpression(expr ast.Expr) Operand {
+	switch node := expr.(type) {
+	case *ast.IntegerLiteral:
+		return IntConst(node.Value)
+	case *ast.DecimalLiteral:
+		return DecimalConst(node.Value)
+	case *ast.Boolean:
+		return BoolConst(node.Value)
+	case *ast.StringLiteral:
+		return StringConst(node.Value)
+	case *ast.CharLiteral:
+		return CharConst(node.Value)
+	case *ast.InterpolatedString:
+		// the values are converted and concatenated from left to right
+		var str Operand
+		for _, part := range node.Parts {
+			value := l.lowerExpression(part)
+			if TypeOf(value) != semantic.String {
+				value = l.emitValue(semantic.String, &Instr{Op: OpStr, Args: []Operand{value}})
+			}
+			if str == nil {
+				str = value
+				continue
+			}
+			str = l.emitValue(semantic.String, &Instr{Op: OpConcat, Args: []Operand{str, value}})
+		}
+		return str
+	case *ast.Identifier:
+		sym := l.info.Uses[node]
+		if global, ok := l.globals[sym]; ok {
+			return l.emitValue(global.Type, &Instr{Op: OpLoad, Name: global.Name})
+		}
+		return l.vars[sym]
+	case *ast.PrefixExpression:
+		right := l.lowerExpression(node.Right)
+		return l.emitValue(TypeOf(right), &Instr{Op: prefixOps[node.Token.Kind], Args: []Operand{right}})
+	case *ast.InfixExpression:
+		if node.Token.Kind == token.AND || node.Token.Kind == token.OR {
+			return l.lowerLogical(node)
+		}
+		left := l.lowerExpression(node.Left)
+		right := l.lowerExpression(node.Right)
+		op := infixOps[node.Token.Kind]
+		t := TypeOf(left)
+		if op.IsComparison() {
+			t = semantic.Boolean
+		}
+		return l.emitValue(t, &Instr{Op: op, Args: []Operand{left, right}, Pos: node.Token.Position})
+	case *ast.CallExpression:
+		return l.lowerCall(node)
+	case *ast.IfExpression:
+		l.lowerIf(node)
+	}
+	return nil
+}
+
+// lowerLogical evaluates the right operand of && and || in a block of its
+// own, which runs only when the left operand does not decide the result.
+func (l *lowerer) lowerLogical(node *ast.InfixExpression) Operand {
+	result := l.fn.NewTemp(semantic.Boolean)
+	right, end := &Block{}, &Block{}
+	left := l.lowerExpression(node.Left)
+	l.emit(&Instr{Op: OpCopy, Dst: result, Args: []Operand{left}})
+	// a right operand that never runs is warned about where it starts
+	defer func(pos reader.Position) { l.pos = pos }(l.pos)
+	l.pos = semantic.StartToken(node.Right).Position
+	if node.Token.Kind == token.AND {
+		l.branch(left, right, end)
+	} else {
+		l.branch(left, end, right)
+	}
+	l.start(right)
+	l.emit(&Instr{Op: OpCopy, Dst: result, Args: []Operand{l.lowerExpression(node.Right)}})
+	l.start(end)
+	return result
+}
+
+func (l *lowerer) lowerCall(call *ast.CallExpression) Operand {
+	args := make([]Operand, len(call.Arguments))
+	for i, arg := range call.Arguments {
+		args[i] = l.lowerExpression(arg)
+	}
+	id := call.Function.(*ast.Identifier)
+	sym := l.info.Uses[id]
+	if sym.Kind == semantic.BUILTIN {
+		l.emit(&Instr{Op: OpPrint, Args: args})
+		return nil
+	}
+	instr := &Instr{Op: OpCall, Name: id.Value, Args: args}
+	if t := semantic.TypeOf(sym.Type); t != semantic.Void {
+		return l.emitValue(t, instr)
+	}
+	l.emit(instr)
+	return nil
+}
@@ -0,0 +1,253 @@
+package opt
+
+import (
+	"fmt"
+	"github.com/wevertonbruno/wb-compiler/analyzers/diagnostic"
+	"github.com/wevertonbruno/wb-compiler/analyzers/reader"
+	"github.com/wevertonbruno/wb-compiler/analyzers/semantic"
+	"github.com/wevertonbruno/wb-compiler/ir"
+)
+
+const unreachableCode = "unreachable code"
+
+// EliminateDeadCode turns branches on constant conditions into jumps,
+// removes the blocks that can no longer be reached, forwards copies and
+// phis that merge a single value to their uses, and deletes the
+// instructions whose results are never used and that have no effect.
+// Unused locals go away with the copies of their initial values.
+//
+// It returns a warning for each region of code the user wrote that can
+// never run.
+func EliminateDeadCode(fn *ir.Func) []diagnostic.Diagnostic {
+	for _, b := range fn.Blocks {
+		term := b.Terminator()
+		if term == nil || term.Op != ir.OpBranch {
+			continue
+		}
+		target := -1
+		if cond, ok := term.Args[0].(*ir.Const); ok {
+			target = 1
+			if cond.Int != 0 {
+				target = 0
+			}
+		} else if term.Blocks[0] == term.Blocks[1] {
+			target = 0
+		}
+		if target >= 0 {
+			term.Op = ir.OpJump
+			term.Args = nil
+			term.Blocks = []*ir.Block{term.Blocks[target]}
+		}
+	}
+	fn.ComputeCFG()
+	warnings := RemoveUnreachable(fn)
+	forwardCopies(fn)
+	removeUnused(fn)
+	return warnings
+}
+
+// RemoveUnreachable removes the blocks that cannot be reached from the
+// entry, along with the phi arguments coming from them. It returns a
+// warning for the start of the first statement in each region of
+// connected unreachable blocks.
+func RemoveUnreachable(fn *ir.Func) []diagnostic.Diagnostic {
+	fn.ComputeCFG()
+	reachable := fn.Reachable()
+	var warnings []diagnostic.Diagnostic
+	seen := make(map[*ir.Block]bool)
+	for _, b := range fn.Blocks {
+		if reachable[b] || seen[b] {
+			continue
+		}
+		// collect the region of unreachable blocks connected to b
+		var first reader.Position
+		work := []*ir.Block{b}
+		seen[b] = true
+		for len(work) > 0 {
+			block := work[len(work)-1]
+			work = work[:len(work)-1]
+			if pos, ok := firstPos(block); ok && (first == reader.Position{} || before(pos, first)) {
+				first = pos
+			}
+			for _, next := range append(append([]*ir.Block(nil), block.Preds...), block.Succs...) {
+				if !reachable[next] && !seen[next] {
+					seen[next] = true
+					work = append(work, next)
+				}
+			}
+		}
+		if first != (reader.Position{}) {
+			warnings = append(warnings, diagnostic.At(fmt.Sprintf(warning, unreachableCode), first))
+		}
+	}
+
+	fn.RemoveUnreachable()
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if instr.Op != ir.OpPhi {
+				break
+			}
+			var args []ir.Operand
+			var blocks []*ir.Block
+			for j, pred := range instr.Blocks {
+				if reachable[pred] {
+					args = append(args, instr.Args[j])
+					blocks = append(blocks, pred)
+				}
+			}
+			instr.Args, instr.Blocks = args, blocks
+		}
+	}
+	return warnings
+}
+
+// firstPos returns the earliest position of the instructions of a block.
+// The instructions of a division are located at its operator, after the
+// start of the statement, and jumps belong to no statement.
+func firstPos(b *ir.Block) (reader.Position, bool) {
+	var first reader.Position
+	found := false
+	for _, instr := range b.Instrs {
+		if instr.Op == ir.OpJump || instr.Pos == (reader.Position{}) {
+			continue
+		}
+		if !found || before(instr.Pos, first) {
+			first, found = instr.Pos, true
+		}
+	}
+	return first, found
+}
+
+func before(a, b reader.Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
+
+// forwardCopies replaces the uses of copies and of phis that merge a
+// single value, ignoring themselves, with that value.
+func forwardCopies(fn *ir.Func) {
+	forward := make(map[*ir.Var]ir.Operand)
+	resolve := func(op ir.Operand) ir.Operand {
+		for {
+			v, ok := op.(*ir.Var)
+			if !ok || forward[v] == nil {
+				return op
+			}
+			op = forward[v]
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				if instr.Dst == nil || forward[instr.Dst] != nil {
+					continue
+				}
+				var value ir.Operand
+				switch instr.Op {
+				case ir.OpCopy:
+					value = resolve(instr.Args[0])
+				case ir.OpPhi:
+					value = singleValue(instr, resolve)
+				}
+				if value != nil && value != ir.Operand(instr.Dst) {
+					forward[instr.Dst] = value
+					changed = true
+				}
+			}
+		}
+	}
+
+	for _, b := range fn.Blocks {
+		instrs := b.Instrs[:0]
+		for _, instr := range b.Instrs {
+			if instr.Dst != nil && forward[instr.Dst] != nil {
+				continue
+			}
+			for i, arg := range instr.Args {
+				instr.Args[i] = resolve(arg)
+			}
+			instrs = append(instrs, instr)
+		}
+		b.Instrs = instrs
+	}
+}
+
+// singleValue returns the only value a phi merges, or nil.
+func singleValue(phi *ir.Instr, resolve func(ir.Operand) ir.Operand) ir.Operand {
+	var value ir.Operand
+	for _, arg := range phi.Args {
+		arg = resolve(arg)
+		if arg == ir.Operand(phi.Dst) {
+			continue
+		}
+		if value != nil && !sameOperand(arg, value) {
+			return nil
+		}
+		value = arg
+	}
+	return value
+}
+
+func sameOperand(a, b ir.Operand) bool {
+	if a == b {
+		return true
+	}
+	x, ok := a.(*ir.Const)
+	y, ok2 := b.(*ir.Const)
+	return ok && ok2 && sameConst(x, y)
+}
+
+// removeUnused deletes the instructions that define values nobody reads,
+// keeping those with effects: stores, prints, calls, terminators and the
+// divisions that may fail.
+func removeUnused(fn *ir.Func) {
+	live := make(map[*ir.Instr]bool)
+	defs := make(map[*ir.Var]*ir.Instr)
+	var work []*ir.Instr
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if instr.Dst != nil {
+				defs[instr.Dst] = instr
+			}
+			if hasEffect(instr) {
+				live[instr] = true
+				work = append(work, instr)
+			}
+		}
+	}
+	for len(work) > 0 {
+		instr := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, arg := range instr.Args {
+			v, ok := arg.(*ir.Var)
+			if !ok {
+				continue
+			}
+			if def := defs[v]; def != nil && !live[def] {
+				live[def] = true
+				work = append(work, def)
+			}
+		}
+	}
+
+	for _, b := range fn.Blocks {
+		instrs := b.Instrs[:0]
+		for _, instr := range b.Instrs {
+			if live[instr] {
+				instrs = append(instrs, instr)
+			}
+		}
+		b.Instrs = instrs
+	}
+}
+
+func hasEffect(instr *ir.Instr) bool {
+	switch instr.Op {
+	case ir.OpStore, ir.OpPrint, ir.OpCall:
+		return true
+	case ir.OpDiv:
+		divisor, ok := instr.Args[1].(*ir.Const)
+		return ir.TypeOf(instr.Args[0]) == semantic.Integer && (!ok || divisor.Int == 0)
+	}
+	return instr.Op.IsTerminator()
+}
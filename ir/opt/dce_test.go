@@ -0,0 +1,50 @@
+package opt
+
+import (
+	"github.com/wevertonbruno/wb-compiler/analyzers/diagnostic"
+	"github.com/wevertonbruno/wb-compiler/ir"
+	"github.com/wevertonbruno/wb-compiler/ir/irtest"
+	"testing"
+)
+
+func TestEliminateDeadCode(t *testing.T) {
+	tests := []struct {
+		input    string
+		code     string
+		warnings string
+	}{
+		{"if (false) {\n print(1)\n}\nprint(2)", "jmp b2\nprint 2\nret", "2:2: warning. unreachable code"},
+		{"if (1 < 2) {\n print(1)\n} else {\n print(2)\n}", "jmp b1\nprint 1\njmp b3\nret", "4:2: warning. unreachable code"},
+		{"while 2 < 1 {\n print(1)\n}", "jmp b1\njmp b3\nret", "2:2: warning. unreachable code"},
+		{"func f(n : Integer) {\n var a : Integer = n * 2\n var b : Integer = n / 2\n var c : Integer = 2 / n\n print(n)\n}", "%3 = div 2, n\nprint n\nret", ""},
+		{"func f(n : Integer) : Integer {\n var a : Integer = n\n if (n > 0) {\n  return a\n } else {\n  return 0\n }\n print(1)\n return 2\n}",
+			"%1 = gt n, 0\nbr %1, b1, b2\nret n\nret 0", "8:2: warning. unreachable code"},
+		{"func f(n : Integer) {\n while true {\n  print(n)\n }\n var a : Integer = 1\n while n > a {\n  print(a)\n }\n}",
+			"jmp b1\njmp b2\nprint n\njmp b1", "5:2: warning. unreachable code"},
+	}
+	for _, tt := range tests {
+		var warnings []diagnostic.Diagnostic
+		p := irtest.Lower(t, tt.input)
+		for _, fn := range p.Funcs {
+			warnings = append(warnings, RemoveUnreachable(fn)...)
+			fn.BuildSSA()
+			FoldConstants(fn)
+			warnings = append(warnings, EliminateDeadCode(fn)...)
+		}
+		fn := p.Entry
+		if len(p.Funcs) > 1 {
+			fn = p.Funcs[0]
+		}
+		if got := body(fn); got != tt.code {
+			t.Errorf("wrong code for %q. expected=\n%s\ngot=\n%s", tt.input, tt.code, got)
+		}
+		if got := messages(warnings); got != tt.warnings {
+			t.Errorf("wrong warnings for %q. expected=\n%s\ngot=\n%s", tt.input, tt.warnings, got)
+		}
+	}
+
+	checkPrograms(t, func(fn *ir.Func) {
+		FoldConstants(fn)
+		EliminateDeadCode(fn)
+	})
+}
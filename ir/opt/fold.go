@@ -0,0 +1,99 @@
+package opt
+
+import (
+	"fmt"
+	"github.com/wevertonbruno/wb-compiler/analyzers/diagnostic"
+	"github.com/wevertonbruno/wb-compiler/ir"
+	"math"
+)
+
+// FoldConstants computes the instructions whose operands are all known
+// constants and replaces the uses of their results with the constants.
+// Phis merging the same constant from every predecessor are folded too,
+// which propagates constants through the control flow. The definitions
+// left unused are removed by EliminateDeadCode, which keeps them until it
+// knows whether their block can run.
+//
+// An integer division by a known zero is kept, so that it still fails at
+// run time, and reported as a warning.
+func FoldConstants(fn *ir.Func) []diagnostic.Diagnostic {
+	values := make(map[*ir.Var]*ir.Const)
+	constant := func(op ir.Operand) *ir.Const {
+		if c, ok := op.(*ir.Const); ok {
+			return c
+		}
+		return values[op.(*ir.Var)]
+	}
+
+	var warnings []diagnostic.Diagnostic
+	reported := make(map[*ir.Instr]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				if instr.Dst == nil || values[instr.Dst] != nil {
+					continue
+				}
+				var value *ir.Const
+				if instr.Op == ir.OpPhi {
+					value = foldPhi(instr, constant)
+				} else if args := constants(instr.Args, constant); args != nil {
+					var err error
+					value, err = ir.Eval(instr.Op, args...)
+					if err != nil && !reported[instr] {
+						reported[instr] = true
+						warnings = append(warnings, diagnostic.At(fmt.Sprintf(warning, err), instr.Pos))
+					}
+				}
+				if value != nil {
+					values[instr.Dst] = value
+					changed = true
+				}
+			}
+		}
+	}
+
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			for i, arg := range instr.Args {
+				if c := constant(arg); c != nil {
+					instr.Args[i] = c
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// constants returns the values of the operands, or nil if one is unknown.
+func constants(ops []ir.Operand, constant func(ir.Operand) *ir.Const) []*ir.Const {
+	args := make([]*ir.Const, len(ops))
+	for i, op := range ops {
+		if args[i] = constant(op); args[i] == nil {
+			return nil
+		}
+	}
+	return args
+}
+
+// foldPhi returns the constant a phi merges from every predecessor, or nil.
+// Arguments that are the result of the phi itself, coming from a loop that
+// does not change it, are ignored.
+func foldPhi(phi *ir.Instr, constant func(ir.Operand) *ir.Const) *ir.Const {
+	var value *ir.Const
+	for _, arg := range phi.Args {
+		if arg == ir.Operand(phi.Dst) {
+			continue
+		}
+		c := constant(arg)
+		if c == nil || value != nil && !sameConst(c, value) {
+			return nil
+		}
+		value = c
+	}
+	return value
+}
+
+func sameConst(a, b *ir.Const) bool {
+	return a.Type == b.Type && a.Int == b.Int && math.Float64bits(a.Decimal) == math.Float64bits(b.Decimal) && a.Str == b.Str
+}
@@ -0,0 +1,61 @@
+package opt
+
+import (
+	"github.com/wevertonbruno/wb-compiler/ir"
+	"github.com/wevertonbruno/wb-compiler/ir/irtest"
+	"testing"
+)
+
+// fold folds the constants and removes the definitions left unused.
+func fold(fn *ir.Func) {
+	FoldConstants(fn)
+	removeUnused(fn)
+}
+
+func TestFoldConstants(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"print(2 * 3 + 1)", "print 7\nret"},
+		{"print(!(1 < 2))\nprint(-2.5 * 2.0)", "print false\nprint -5.0\nret"},
+		{"print(1.0 / 0.0)", "print +Inf\nret"},
+		{"print(-9223372036854775807 - 1 / -1)", "print -9223372036854775806\nret"},
+		{"func f() {\n var a : Integer = 4\n var b : Integer = a * 2\n print(b > a)\n}\nf()", "call f()\nret"},
+		{"func f(n : Integer) {\n var a : Integer = 2\n print(n * a)\n}\nf(1)", "call f(1)\nret"},
+		{`print("wb" == "wb")` + "\n" + `print("a" != "a")` + "\n" + `print('a' < 'b')`, "print true\nprint false\nprint true\nret"},
+		{`print("${1 + 1} ${0.5} ${'c'} ${1 > 2} ${"${0.0 / 0.0}"}" == "2 0.5 c false nan")`, "print true\nret"},
+	}
+	for _, tt := range tests {
+		p := optimize(t, tt.input, fold)
+		if got := body(p.Entry); got != tt.expected {
+			t.Errorf("wrong code for %q. expected=\n%s\ngot=\n%s", tt.input, tt.expected, got)
+		}
+	}
+
+	p := optimize(t, "func f(n : Integer) {\n var a : Integer = 2\n print(n * a)\n}", fold)
+	if got := body(p.Func("f")); got != "%1 = mul n, 2\nprint %1\nret" {
+		t.Errorf("constant variable not propagated. got=\n%s", got)
+	}
+
+	p = optimize(t, "func f() {\n var s : String = \"wb\"\n print(s == \"\")\n print(s)\n}", fold)
+	if got := body(p.Func("f")); got != "print false\nprint \"wb\"\nret" {
+		t.Errorf("string constant not propagated. got=\n%s", got)
+	}
+
+	checkPrograms(t, fold)
+}
+
+func TestDivisionByZero(t *testing.T) {
+	p := irtest.Lower(t, "func f() : Integer {\n var z : Integer = 1 - 1\n return 10 / z\n}")
+	warnings := Optimize(p, Basic)
+	if len(warnings) != 1 || warnings[0].String() != "3:12: warning. integer division by zero" {
+		t.Fatalf("wrong warnings. got=%v", warnings)
+	}
+	if got := body(p.Func("f")); got != "%2 = div 10, 0\nret %2" {
+		t.Errorf("division by zero was folded. got=\n%s", got)
+	}
+	if _, err := irtest.Execute(p); err != nil {
+		t.Errorf("f is never called, expected no error. got=%v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package opt
+
+import "github.com/wevertonbruno/wb-compiler/ir"
+
+const (
+	// inlineLimit is the largest number of instructions of a function
+	// inlined at its calls.
+	inlineLimit = 40
+	// growthLimit stops inlining into a function once it is that large.
+	growthLimit = 1000
+)
+
+// Inline replaces the calls to small functions that do not call themselves
+// with a copy of their body. The arguments are copied to fresh variables
+// standing for the parameters, and the returns become jumps to the code
+// after the call. Calls found in the inlined code are inlined in turn,
+// until the caller grows past a limit. The functions must be out of SSA
+// form.
+func Inline(p *ir.Program) {
+	for _, fn := range p.Funcs {
+		for done := false; !done; {
+			done = true
+			for _, b := range fn.Blocks {
+				for i, instr := range b.Instrs {
+					if instr.Op != ir.OpCall {
+						continue
+					}
+					callee := p.Func(instr.Name)
+					if callee == nil || callee == fn || !inlinable(callee) || size(fn) > growthLimit {
+						continue
+					}
+					inline(fn, b, i, callee)
+					done = false
+					break
+				}
+				if !done {
+					break
+				}
+			}
+		}
+	}
+}
+
+// inlinable reports whether a function is small and calls no function
+// with its own name, which would inline it again.
+func inlinable(fn *ir.Func) bool {
+	if size(fn) > inlineLimit {
+		return false
+	}
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if instr.Op == ir.OpCall && instr.Name == fn.Name {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func size(fn *ir.Func) int {
+	n := 0
+	for _, b := range fn.Blocks {
+		n += len(b.Instrs)
+	}
+	return n
+}
+
+// inline replaces the call at index i of block b with the body of callee.
+func inline(fn *ir.Func, b *ir.Block, i int, callee *ir.Func) {
+	call := b.Instrs[i]
+	after := fn.SplitBlock(b, i+1)
+	b.Instrs = b.Instrs[:i]
+
+	vars := make(map[*ir.Var]*ir.Var)
+	rename := func(v *ir.Var) *ir.Var {
+		if vars[v] == nil {
+			vars[v] = fn.NewVar(v.Name, v.Type)
+		}
+		return vars[v]
+	}
+	for j, param := range callee.Params {
+		b.Instrs = append(b.Instrs, &ir.Instr{Op: ir.OpCopy, Dst: rename(param), Args: []ir.Operand{call.Args[j]}, Pos: call.Pos})
+	}
+
+	blocks := make(map[*ir.Block]*ir.Block)
+	prev := b
+	for _, original := range callee.Blocks {
+		blocks[original] = fn.NewBlockAfter(prev)
+		prev = blocks[original]
+	}
+	b.Instrs = append(b.Instrs, &ir.Instr{Op: ir.OpJump, Blocks: []*ir.Block{blocks[callee.Entry()]}, Pos: call.Pos})
+
+	for _, original := range callee.Blocks {
+		copied := blocks[original]
+		for _, instr := range original.Instrs {
+			c := &ir.Instr{Op: instr.Op, Name: instr.Name, Pos: instr.Pos}
+			for _, arg := range instr.Args {
+				if v, ok := arg.(*ir.Var); ok {
+					arg = rename(v)
+				}
+				c.Args = append(c.Args, arg)
+			}
+			for _, target := range instr.Blocks {
+				c.Blocks = append(c.Blocks, blocks[target])
+			}
+			if instr.Dst != nil {
+				c.Dst = rename(instr.Dst)
+			}
+			if c.Op == ir.OpReturn {
+				// the result goes where the call put it
+				if call.Dst != nil {
+					copied.Instrs = append(copied.Instrs, &ir.Instr{Op: ir.OpCopy, Dst: call.Dst, Args: c.Args, Pos: instr.Pos})
+				}
+				c = &ir.Instr{Op: ir.OpJump, Blocks: []*ir.Block{after}}
+			}
+			copied.Instrs = append(copied.Instrs, c)
+		}
+	}
+	fn.ComputeCFG()
+}
@@ -0,0 +1,86 @@
+package opt
+
+import (
+	"github.com/wevertonbruno/wb-compiler/ir/irtest"
+	"strings"
+	"testing"
+)
+
+func TestInline(t *testing.T) {
+	p := irtest.Lower(t, `func square(x : Integer) : Integer {
+		return x * x
+	}
+	func twice(x : Integer) : Integer {
+		var a : Integer = square(x)
+		return a + square(x + 1)
+	}
+	func fib(n : Integer) : Integer {
+		if (n < 2) {
+			return n
+		}
+		return fib(n - 1) + fib(n - 2)
+	}
+	func show(n : Integer) {
+		if (n > 0) {
+			print(n)
+			return
+		}
+		print(0)
+	}
+	print(twice(3))
+	show(twice(1))
+	show(0)
+	print(fib(10))`)
+	expected, err := irtest.Execute(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Inline(p)
+	if got := body(p.Entry); strings.Count(got, "call ") != 1 || !strings.Contains(got, "call fib") {
+		t.Errorf("wrong calls left in the entry. got=\n%s", got)
+	}
+	if got := body(p.Func("fib")); strings.Count(got, "call fib") != 2 {
+		t.Errorf("recursive function changed. got=\n%s", got)
+	}
+	if out, err := irtest.Execute(p); err != nil || out != expected {
+		t.Errorf("wrong output. expected=%q, got=%q, %v\n%s", expected, out, err, p)
+	}
+	for _, fn := range p.Funcs {
+		fn.BuildSSA()
+	}
+	if out, err := irtest.Execute(p); err != nil || out != expected {
+		t.Errorf("wrong output in SSA form. expected=%q, got=%q, %v\n%s", expected, out, err, p)
+	}
+
+	for _, src := range programs {
+		p := irtest.Lower(t, src)
+		expected, err := irtest.Execute(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		Inline(p)
+		if out, err := irtest.Execute(p); err != nil || out != expected {
+			t.Errorf("wrong output. expected=%q, got=%q, %v\n%s", expected, out, err, p)
+		}
+	}
+}
+
+func TestInlineWarnings(t *testing.T) {
+	// what the inlined code folds to at a call is not reported
+	p := irtest.Lower(t, `func ratio(n : Integer) : Integer {
+		if (n > 0) {
+			return 10 / n
+		}
+		return 0
+	}
+	print(ratio(0))
+	print(ratio(2))
+	print(1 / 0)`)
+	expected := "9:10: warning. integer division by zero"
+	if got := messages(Optimize(p, Full)); got != expected {
+		t.Errorf("wrong warnings. expected=\n%s\ngot=\n%s", expected, got)
+	}
+	if got := body(p.Entry); strings.Contains(got, "call ") {
+		t.Errorf("call not inlined. got=\n%s", got)
+	}
+}
@@ -0,0 +1,77 @@
+package opt
+
+import (
+	"github.com/wevertonbruno/wb-compiler/analyzers/semantic"
+	"github.com/wevertonbruno/wb-compiler/ir"
+)
+
+// HoistInvariants moves the computations of a loop whose operands do not
+// change while it runs to its preheader, so that they run once before the
+// loop. Inner loops go first, which lets code move out of several loops.
+// The loop may never run the code moved, so only the instructions that
+// cannot fail and have no effects move.
+func HoistInvariants(fn *ir.Func) {
+	fn.ComputeCFG()
+	for _, l := range fn.Loops(fn.Dominators()) {
+		if l.Header == fn.Entry() {
+			continue
+		}
+		defined := make(map[*ir.Var]bool)
+		for _, b := range l.Blocks {
+			for _, instr := range b.Instrs {
+				if instr.Dst != nil {
+					defined[instr.Dst] = true
+				}
+			}
+		}
+		invariant := func(instr *ir.Instr) bool {
+			if !movable(instr) {
+				return false
+			}
+			for _, arg := range instr.Args {
+				if v, ok := arg.(*ir.Var); ok && defined[v] {
+					return false
+				}
+			}
+			return true
+		}
+
+		// the operands of an instruction move before it
+		var hoisted []*ir.Instr
+		for changed := true; changed; {
+			changed = false
+			for _, b := range l.Blocks {
+				instrs := b.Instrs[:0]
+				for _, instr := range b.Instrs {
+					if invariant(instr) {
+						hoisted = append(hoisted, instr)
+						delete(defined, instr.Dst)
+						changed = true
+						continue
+					}
+					instrs = append(instrs, instr)
+				}
+				b.Instrs = instrs
+			}
+		}
+		if len(hoisted) == 0 {
+			continue
+		}
+		pre := fn.Preheader(l)
+		term := pre.Instrs[len(pre.Instrs)-1]
+		pre.Instrs = append(append(pre.Instrs[:len(pre.Instrs)-1], hoisted...), term)
+	}
+}
+
+// movable reports whether an instruction computes a value from its
+// operands alone, without failing.
+func movable(instr *ir.Instr) bool {
+	switch instr.Op {
+	case ir.OpCopy, ir.OpNeg, ir.OpNot, ir.OpStr, ir.OpConcat:
+		return true
+	case ir.OpDiv:
+		divisor, ok := instr.Args[1].(*ir.Const)
+		return ir.TypeOf(instr.Args[0]) == semantic.Decimal || ok && divisor.Int != 0
+	}
+	return instr.Op.IsBinary()
+}
@@ -0,0 +1,71 @@
+package opt
+
+import (
+	"github.com/wevertonbruno/wb-compiler/ir"
+	"github.com/wevertonbruno/wb-compiler/ir/irtest"
+	"strings"
+	"testing"
+)
+
+// loops lowers a source, turns its self tail calls into loops and runs
+// pass over the functions in SSA form, after the copies are forwarded.
+func loops(t *testing.T, input string, pass func(fn *ir.Func)) *ir.Program {
+	p := irtest.Lower(t, input)
+	for _, fn := range p.Funcs {
+		EliminateTailCalls(fn)
+		fn.BuildSSA()
+		FoldConstants(fn)
+		EliminateDeadCode(fn)
+		pass(fn)
+	}
+	return p
+}
+
+// loopBody returns the instructions of the loops of a function, one per
+// line.
+func loopBody(fn *ir.Func) string {
+	fn.ComputeCFG()
+	var lines []string
+	for _, l := range fn.Loops(fn.Dominators()) {
+		for _, b := range l.Blocks {
+			for _, instr := range b.Instrs {
+				lines = append(lines, instr.String())
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestHoistInvariants(t *testing.T) {
+	src := `func f(i : Integer, n : Integer, d : Decimal) {
+		if (i < n) {
+			print(i + n * 3 - 1)
+			print(-d / 2.0)
+			if (i > 5) {
+				print(10 / (n - 3))
+			}
+			f(i + 1, n, d)
+		}
+	}
+	f(0, 3, 1.5)`
+	expected, err := irtest.Execute(irtest.Lower(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := loops(t, src, HoistInvariants)
+	body := loopBody(p.Func("f"))
+	for _, moved := range []string{"mul n, 3", "neg d", "div %", "sub n, 3"} {
+		if strings.Contains(body, moved) {
+			t.Errorf("%q was left in the loop\n%s", moved, p.Func("f"))
+		}
+	}
+	// the division may fail, so it stays under its condition
+	if !strings.Contains(body, "div 10") {
+		t.Errorf("the guarded division was moved out of the loop\n%s", p.Func("f"))
+	}
+	if out, err := irtest.Execute(p); err != nil || out != expected {
+		t.Errorf("wrong output. expected=%q, got=%q, %v\n%s", expected, out, err, p)
+	}
+
+	checkPrograms(t, HoistInvariants)
+}
@@ -0,0 +1,73 @@
+// Package opt implements the optimizations over the intermediate
+// representation. The passes work on functions in SSA form, unless noted
+// otherwise, and never reject a program: what they find is reported as
+// warnings.
+package opt
+
+import (
+	"github.com/wevertonbruno/wb-compiler/analyzers/diagnostic"
+	"github.com/wevertonbruno/wb-compiler/ir"
+	"sort"
+)
+
+const warning = "warning. %v"
+
+// The optimization levels.
+const (
+	// None leaves the program as lowered.
+	None = iota
+	// Basic folds constants and removes dead code.
+	Basic
+	// Full also inlines small functions, turns self tail calls into
+	// loops, moves invariant code out of the loops and reduces the
+	// multiplications of their induction variables to additions.
+	Full
+)
+
+// Optimize runs the passes of an optimization level over the functions of
+// a program as lowered and returns the warnings found, in source order.
+// The warnings come from the code as written: the functions are folded
+// and cleaned before inlining and again, with the loop passes, after it,
+// without reporting what the second round finds. The functions are left
+// out of SSA form, ready for the code generators.
+func Optimize(p *ir.Program, level int) []diagnostic.Diagnostic {
+	if level <= None {
+		return nil
+	}
+	var warnings []diagnostic.Diagnostic
+	for _, fn := range p.Funcs {
+		// SSA construction would drop the unreachable blocks silently
+		warnings = append(warnings, RemoveUnreachable(fn)...)
+		warnings = append(warnings, simplify(fn)...)
+	}
+	if level >= Full {
+		for _, fn := range p.Funcs {
+			EliminateTailCalls(fn)
+		}
+		Inline(p)
+		for _, fn := range p.Funcs {
+			fn.BuildSSA()
+			FoldConstants(fn)
+			EliminateDeadCode(fn)
+			ReduceStrength(fn)
+			HoistInvariants(fn)
+			FoldConstants(fn)
+			EliminateDeadCode(fn)
+			fn.DestroySSA()
+		}
+	}
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return before(warnings[i].Position, warnings[j].Position)
+	})
+	return warnings
+}
+
+// simplify folds the constants and removes the dead code of a function
+// out of SSA form.
+func simplify(fn *ir.Func) []diagnostic.Diagnostic {
+	fn.BuildSSA()
+	warnings := FoldConstants(fn)
+	warnings = append(warnings, EliminateDeadCode(fn)...)
+	fn.DestroySSA()
+	return warnings
+}
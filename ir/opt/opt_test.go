@@ -0,0 +1,147 @@
+package opt
+
+import (
+	"github.com/wevertonbruno/wb-compiler/analyzers/diagnostic"
+	"github.com/wevertonbruno/wb-compiler/ir"
+	"github.com/wevertonbruno/wb-compiler/ir/irtest"
+	"strings"
+	"testing"
+)
+
+// programs are run before and after the passes, which must keep their
+// output.
+var programs = []string{
+	`func fib(n : Integer) : Integer {
+		if (n < 2) {
+			return n
+		}
+		return fib(n - 1) + fib(n - 2)
+	}
+	print(fib(10))`,
+	`var limit : Integer = 3
+	func count(step : Decimal) {
+		var i : Integer = 0
+		var total : Decimal = 0.0
+		while i < limit {
+			var i : Integer = limit
+			if (total >= step) {
+				print(true)
+			} else {
+				print(i / 2)
+			}
+			return
+		}
+	}
+	count(0.5)`,
+	`func f(n : Integer) : Boolean {
+		var k : Integer = 6 * 7
+		while n > k {
+			var d : Decimal = 1.5 * 2.0
+			print(d)
+			return !(n == k + 1)
+		}
+		return k / 2 == 21
+	}
+	print(f(43))
+	print(f(1))
+	print(-(1.0 / 0.0) < 0.0)
+	print(0.0 / 0.0 == 0.0 / 0.0)`,
+	`func steps(i : Integer, n : Integer, scale : Integer) {
+		if (i < n) {
+			print(i * 3 + scale * 2)
+			steps(i + 1, n, scale)
+		}
+	}
+	steps(0, 4, 5)
+	steps(-2, 1, -1)`,
+	`func loop(n : Integer) : Integer {
+		var k : Integer = 0
+		var acc : Integer = 0
+		while k < n {
+			var twice : Integer = n * 2
+			acc = acc + k * 4 + twice
+			k = k + 1
+		}
+		return acc
+	}
+	print(loop(5))
+	print(loop(0))`,
+}
+
+// optimize lowers a source and runs pass over its functions in SSA form.
+func optimize(t *testing.T, input string, pass func(fn *ir.Func)) *ir.Program {
+	p := irtest.Lower(t, input)
+	for _, fn := range p.Funcs {
+		fn.BuildSSA()
+		pass(fn)
+	}
+	return p
+}
+
+// checkPrograms runs the programs before and after a pass.
+func checkPrograms(t *testing.T, pass func(fn *ir.Func)) {
+	for _, src := range programs {
+		expected, err := irtest.Execute(irtest.Lower(t, src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := optimize(t, src, pass)
+		if out, err := irtest.Execute(p); err != nil || out != expected {
+			t.Errorf("wrong output. expected=%q, got=%q, %v\n%s", expected, out, err, p)
+		}
+		for _, fn := range p.Funcs {
+			fn.DestroySSA()
+		}
+		if out, err := irtest.Execute(p); err != nil || out != expected {
+			t.Errorf("wrong output out of SSA. expected=%q, got=%q, %v\n%s", expected, out, err, p)
+		}
+	}
+}
+
+// body returns the instructions of a function, one per line.
+func body(fn *ir.Func) string {
+	var lines []string
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			lines = append(lines, instr.String())
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// messages formats diagnostics the way the driver reports them.
+func messages(diagnostics []diagnostic.Diagnostic) string {
+	lines := make([]string, len(diagnostics))
+	for i, d := range diagnostics {
+		lines[i] = d.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestOptimize(t *testing.T) {
+	for _, src := range programs {
+		expected, err := irtest.Execute(irtest.Lower(t, src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := irtest.Lower(t, src)
+		if warnings := Optimize(p, Full); len(warnings) > 0 {
+			t.Errorf("unexpected warnings. got=%v", warnings)
+		}
+		if out, err := irtest.Execute(p); err != nil || out != expected {
+			t.Errorf("wrong output. expected=%q, got=%q, %v\n%s", expected, out, err, p)
+		}
+	}
+
+	p := irtest.Lower(t, `func f() {
+		return
+		print(1 / 0)
+	}
+	if (1 > 2) {
+		f()
+	}`)
+	expected := "3:3: warning. unreachable code\n6:3: warning. unreachable code"
+	if got := messages(Optimize(p, Full)); got != expected {
+		t.Errorf("wrong warnings. expected=\n%s\ngot=\n%s", expected, got)
+	}
+}
@@ -0,0 +1,160 @@
+package opt
+
+import (
+	"github.com/wevertonbruno/wb-compiler/analyzers/semantic"
+	"github.com/wevertonbruno/wb-compiler/ir"
+)
+
+// induction is a variable that a loop steps by a constant on every
+// iteration: a phi of the header that takes init on entry and next, the
+// phi plus step, along the back edge.
+type induction struct {
+	phi  *ir.Instr
+	init ir.Operand
+	next *ir.Instr
+	step int64
+}
+
+// ReduceStrength replaces the multiplications of the induction variables
+// of a loop by a constant with variables of their own, which take the
+// product on entry and are stepped by an addition along with the
+// induction variable. The integers wrap around, so the sums always match
+// the products.
+func ReduceStrength(fn *ir.Func) {
+	fn.ComputeCFG()
+	for _, l := range fn.Loops(fn.Dominators()) {
+		if l.Header == fn.Entry() || len(l.Latches) != 1 {
+			continue
+		}
+		inductions := findInductions(l)
+		var muls []*ir.Instr
+		for _, b := range l.Blocks {
+			for _, instr := range b.Instrs {
+				if instr.Op == ir.OpMul && ir.TypeOf(instr.Args[0]) == semantic.Integer {
+					muls = append(muls, instr)
+				}
+			}
+		}
+		reduced := make(map[*ir.Var]map[int64]*ir.Var)
+		for _, instr := range muls {
+			v, factor := scaled(instr)
+			iv := inductions[v]
+			if iv == nil {
+				continue
+			}
+			if reduced[v] == nil {
+				reduced[v] = make(map[int64]*ir.Var)
+			}
+			product := reduced[v][factor]
+			if product == nil {
+				product = reduce(fn, l, iv, factor, instr)
+				reduced[v][factor] = product
+			}
+			*instr = ir.Instr{Op: ir.OpCopy, Dst: instr.Dst, Args: []ir.Operand{product}, Pos: instr.Pos}
+		}
+	}
+}
+
+// findInductions returns the induction variables of a loop by their phi.
+func findInductions(l *ir.Loop) map[*ir.Var]*induction {
+	defs := make(map[*ir.Var]*ir.Instr)
+	for _, b := range l.Blocks {
+		for _, instr := range b.Instrs {
+			if instr.Dst != nil {
+				defs[instr.Dst] = instr
+			}
+		}
+	}
+	inductions := make(map[*ir.Var]*induction)
+	for _, phi := range l.Header.Instrs {
+		if phi.Op != ir.OpPhi {
+			break
+		}
+		if phi.Dst.Type != semantic.Integer || len(phi.Args) != 2 {
+			continue
+		}
+		latch := 0
+		if phi.Blocks[1] == l.Latches[0] {
+			latch = 1
+		}
+		v, ok := phi.Args[latch].(*ir.Var)
+		if !ok || defs[v] == nil {
+			continue
+		}
+		next := defs[v]
+		var step *ir.Const
+		switch {
+		case next.Op == ir.OpAdd && next.Args[0] == ir.Operand(phi.Dst):
+			step, _ = next.Args[1].(*ir.Const)
+		case next.Op == ir.OpAdd && next.Args[1] == ir.Operand(phi.Dst):
+			step, _ = next.Args[0].(*ir.Const)
+		case next.Op == ir.OpSub && next.Args[0] == ir.Operand(phi.Dst):
+			if c, ok := next.Args[1].(*ir.Const); ok {
+				step = ir.IntConst(-c.Int)
+			}
+		}
+		if step != nil {
+			inductions[phi.Dst] = &induction{phi: phi, init: phi.Args[1-latch], next: next, step: step.Int}
+		}
+	}
+	return inductions
+}
+
+// scaled returns the variable and the constant factor of a multiplication,
+// or a nil variable when it multiplies no variable by a constant.
+func scaled(mul *ir.Instr) (*ir.Var, int64) {
+	for i, arg := range mul.Args {
+		v, ok := arg.(*ir.Var)
+		factor, ok2 := mul.Args[1-i].(*ir.Const)
+		if ok && ok2 {
+			return v, factor.Int
+		}
+	}
+	return nil, 0
+}
+
+// reduce adds the variable holding the product of an induction variable
+// and a factor, and returns it.
+func reduce(fn *ir.Func, l *ir.Loop, iv *induction, factor int64, mul *ir.Instr) *ir.Var {
+	product := fn.NewTemp(semantic.Integer)
+	next := fn.NewTemp(semantic.Integer)
+
+	// the preheader may be new, with its own phi for the entry value
+	pre := fn.Preheader(l)
+	for i, from := range iv.phi.Blocks {
+		if from == pre {
+			iv.init = iv.phi.Args[i]
+		}
+	}
+	var init ir.Operand
+	if c, ok := iv.init.(*ir.Const); ok {
+		init = ir.IntConst(c.Int * factor)
+	} else {
+		start := fn.NewTemp(semantic.Integer)
+		term := pre.Instrs[len(pre.Instrs)-1]
+		pre.Instrs = append(pre.Instrs[:len(pre.Instrs)-1],
+			&ir.Instr{Op: ir.OpMul, Dst: start, Args: []ir.Operand{iv.init, ir.IntConst(factor)}, Pos: mul.Pos},
+			term)
+		init = start
+	}
+
+	phi := &ir.Instr{
+		Op:     ir.OpPhi,
+		Dst:    product,
+		Args:   []ir.Operand{init, next},
+		Blocks: []*ir.Block{pre, l.Latches[0]},
+		Pos:    mul.Pos,
+	}
+	l.Header.Instrs = append([]*ir.Instr{phi}, l.Header.Instrs...)
+
+	step := &ir.Instr{Op: ir.OpAdd, Dst: next, Args: []ir.Operand{product, ir.IntConst(iv.step * factor)}, Pos: mul.Pos}
+	for _, b := range l.Blocks {
+		for i, instr := range b.Instrs {
+			if instr == iv.next {
+				b.Instrs = append(b.Instrs[:i+1], append([]*ir.Instr{step}, b.Instrs[i+1:]...)...)
+				return product
+			}
+		}
+	}
+	return product
+}
@@ -0,0 +1,54 @@
+package opt
+
+import (
+	"github.com/wevertonbruno/wb-compiler/ir"
+	"github.com/wevertonbruno/wb-compiler/ir/irtest"
+	"strings"
+	"testing"
+)
+
+func TestReduceStrength(t *testing.T) {
+	src := `func f(i : Integer, n : Integer) {
+		if (i < n) {
+			print(i * 4)
+			print(3 * i)
+			print(i * 4 + 1)
+			print(i * n)
+			f(i + 2, n)
+		}
+	}
+	func g(i : Integer) {
+		if (i > 0) {
+			print(i * -5)
+			g(i - 1)
+		}
+	}
+	f(1, 7)
+	g(3)
+	g(0)`
+	expected, err := irtest.Execute(irtest.Lower(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := loops(t, src, ReduceStrength)
+	if body := loopBody(p.Func("f")); strings.Count(body, "mul ") != 1 || !strings.Contains(body, "mul i.1, n") {
+		t.Errorf("wrong multiplications left in the loop\n%s", p.Func("f"))
+	}
+	if body := loopBody(p.Func("g")); strings.Contains(body, "mul ") || !strings.Contains(p.Func("g").String(), "mul i, -5") {
+		t.Errorf("wrong multiplications left in the loop\n%s", p.Func("g"))
+	}
+	if out, err := irtest.Execute(p); err != nil || out != expected {
+		t.Errorf("wrong output. expected=%q, got=%q, %v\n%s", expected, out, err, p)
+	}
+	for _, fn := range p.Funcs {
+		fn.DestroySSA()
+	}
+	if out, err := irtest.Execute(p); err != nil || out != expected {
+		t.Errorf("wrong output out of SSA. expected=%q, got=%q, %v\n%s", expected, out, err, p)
+	}
+
+	checkPrograms(t, func(fn *ir.Func) {
+		ReduceStrength(fn)
+		EliminateDeadCode(fn)
+	})
+}
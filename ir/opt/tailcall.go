@@ -0,0 +1,53 @@
+package opt
+
+import "github.com/wevertonbruno/wb-compiler/ir"
+
+// EliminateTailCalls turns the calls a function makes to itself right
+// before returning, or before jumping to a return, into jumps back to its
+// start, after assigning the arguments to the parameters. A new entry
+// block is laid out first, so that the old one can become the header of
+// the loop. The function must be out of SSA form.
+func EliminateTailCalls(fn *ir.Func) {
+	var header *ir.Block
+	for _, b := range fn.Blocks {
+		for i := 0; i+1 < len(b.Instrs); i++ {
+			call := b.Instrs[i]
+			if call.Op != ir.OpCall || call.Name != fn.Name || !returns(call, b.Instrs[i+1]) {
+				continue
+			}
+			if header == nil {
+				header = fn.Entry()
+			}
+			// the arguments may read the parameters they replace
+			var copies []*ir.Instr
+			temps := make([]*ir.Var, len(call.Args))
+			for j, arg := range call.Args {
+				temps[j] = fn.NewTemp(fn.Params[j].Type)
+				copies = append(copies, &ir.Instr{Op: ir.OpCopy, Dst: temps[j], Args: []ir.Operand{arg}, Pos: call.Pos})
+			}
+			for j, param := range fn.Params {
+				copies = append(copies, &ir.Instr{Op: ir.OpCopy, Dst: param, Args: []ir.Operand{temps[j]}, Pos: call.Pos})
+			}
+			copies = append(copies, &ir.Instr{Op: ir.OpJump, Blocks: []*ir.Block{header}, Pos: call.Pos})
+			b.Instrs = append(b.Instrs[:i:i], copies...)
+			break
+		}
+	}
+	if header != nil {
+		entry := fn.NewBlockAfter(nil)
+		entry.Instrs = []*ir.Instr{{Op: ir.OpJump, Blocks: []*ir.Block{header}}}
+		fn.ComputeCFG()
+	}
+}
+
+// returns reports whether the instruction after a call returns its result,
+// or returns nothing, directly or by jumping to a lone return.
+func returns(call, next *ir.Instr) bool {
+	if next.Op == ir.OpJump && len(next.Blocks[0].Instrs) == 1 {
+		next = next.Blocks[0].Instrs[0]
+	}
+	if next.Op != ir.OpReturn {
+		return false
+	}
+	return len(next.Args) == 0 || call.Dst != nil && next.Args[0] == ir.Operand(call.Dst)
+}
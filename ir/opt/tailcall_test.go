@@ -0,0 +1,61 @@
+package opt
+
+import (
+	"github.com/wevertonbruno/wb-compiler/ir/irtest"
+	"strings"
+	"testing"
+)
+
+func TestEliminateTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		// calls is whether a call to the function is left
+		calls bool
+	}{
+		{`func sum(n : Integer, acc : Integer) : Integer {
+			if (n == 0) {
+				return acc
+			}
+			return sum(n - 1, acc + n)
+		}
+		print(sum(100, 0))`, "5050\n", false},
+		{`func swap(a : Integer, b : Integer, n : Integer) : Integer {
+			if (n == 0) {
+				return a - b
+			}
+			return swap(b, a, n - 1)
+		}
+		print(swap(1, 2, 3))
+		print(swap(1, 2, 4))`, "1\n-1\n", false},
+		{`func count(n : Integer) {
+			if (n > 0) {
+				print(n)
+				count(n - 1)
+			}
+		}
+		count(3)`, "3\n2\n1\n", false},
+		{`func fact(n : Integer) : Integer {
+			if (n < 2) {
+				return 1
+			}
+			return n * fact(n - 1)
+		}
+		print(fact(5))`, "120\n", true},
+	}
+	for _, tt := range tests {
+		p := irtest.Lower(t, tt.input)
+		fn := p.Funcs[0]
+		EliminateTailCalls(fn)
+		if calls := strings.Contains(body(fn), "call "+fn.Name); calls != tt.calls {
+			t.Errorf("wrong calls left. expected=%v, got=%v\n%s", tt.calls, calls, fn)
+		}
+		if out, err := irtest.Execute(p); err != nil || out != tt.expected {
+			t.Errorf("wrong output. expected=%q, got=%q, %v\n%s", tt.expected, out, err, p)
+		}
+		fn.BuildSSA()
+		if out, err := irtest.Execute(p); err != nil || out != tt.expected {
+			t.Errorf("wrong output in SSA form. expected=%q, got=%q, %v\n%s", tt.expected, out, err, p)
+		}
+	}
+}
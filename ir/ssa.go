@@ -0,0 +1,260 @@
+package ir
+
+// BuildSSA rewrites the function in static single assignment form. Blocks
+// unreachable from the entry are removed first, since they have no place
+// in the dominator tree. Phis are placed at the iterated dominance
+// frontiers of the definitions of every variable that is live across
+// blocks, and every definition after the first gets a fresh variable.
+func (f *Func) BuildSSA() {
+	f.ComputeCFG()
+	f.RemoveUnreachable()
+	dom := f.Dominators()
+	phis := f.insertPhis(dom)
+
+	r := &renamer{fn: f, phis: phis, stacks: make(map[*Var][]Operand), defined: make(map[*Var]bool)}
+	for _, param := range f.Params {
+		r.define(param)
+	}
+	r.rename(f.Entry(), dom)
+}
+
+// insertPhis places empty phis and returns the variable each one merges.
+func (f *Func) insertPhis(dom *DomTree) map[*Instr]*Var {
+	// Only variables read in a block before being written in it need
+	// phis, the others never flow from one block to another.
+	var order []*Var
+	defs := make(map[*Var][]*Block)
+	global := make(map[*Var]bool)
+	for _, b := range f.Blocks {
+		written := make(map[*Var]bool)
+		for _, instr := range b.Instrs {
+			for _, arg := range instr.Args {
+				if v, ok := arg.(*Var); ok && !written[v] {
+					global[v] = true
+				}
+			}
+			if v := instr.Dst; v != nil {
+				written[v] = true
+				if len(defs[v]) == 0 {
+					order = append(order, v)
+				}
+				if !contains(defs[v], b) {
+					defs[v] = append(defs[v], b)
+				}
+			}
+		}
+	}
+	for _, param := range f.Params {
+		if len(defs[param]) == 0 {
+			order = append(order, param)
+		}
+		defs[param] = append(defs[param], f.Entry())
+	}
+
+	frontiers := dom.Frontiers()
+	phis := make(map[*Instr]*Var)
+	for _, v := range order {
+		if !global[v] {
+			continue
+		}
+		placed := make(map[*Block]bool)
+		work := append([]*Block(nil), defs[v]...)
+		for len(work) > 0 {
+			b := work[len(work)-1]
+			work = work[:len(work)-1]
+			for _, join := range frontiers[b] {
+				if placed[join] {
+					continue
+				}
+				placed[join] = true
+				phi := &Instr{
+					Op:     OpPhi,
+					Dst:    v,
+					Args:   make([]Operand, len(join.Preds)),
+					Blocks: append([]*Block(nil), join.Preds...),
+				}
+				join.Instrs = append(join.Instrs[:phiCount(join)], append([]*Instr{phi}, join.Instrs[phiCount(join):]...)...)
+				phis[phi] = v
+				if !contains(defs[v], join) {
+					work = append(work, join)
+				}
+			}
+		}
+	}
+	return phis
+}
+
+// phiCount returns the number of phis at the start of the block.
+func phiCount(b *Block) int {
+	n := 0
+	for n < len(b.Instrs) && b.Instrs[n].Op == OpPhi {
+		n++
+	}
+	return n
+}
+
+type renamer struct {
+	fn   *Func
+	phis map[*Instr]*Var
+	// stacks holds the reaching definitions of every original variable,
+	// the innermost last.
+	stacks  map[*Var][]Operand
+	defined map[*Var]bool
+}
+
+// define returns the variable that holds a new definition of v. The first
+// definition keeps v itself.
+func (r *renamer) define(v *Var) *Var {
+	version := v
+	if r.defined[v] {
+		version = r.fn.NewVar(v.Name, v.Type)
+	}
+	r.defined[v] = true
+	r.stacks[v] = append(r.stacks[v], version)
+	return version
+}
+
+// current returns the definition of v reaching the point being renamed.
+// A variable read before any definition reads the zero value of its type.
+func (r *renamer) current(v *Var) Operand {
+	stack := r.stacks[v]
+	if len(stack) == 0 {
+		return &Const{Type: v.Type}
+	}
+	return stack[len(stack)-1]
+}
+
+func (r *renamer) rename(b *Block, dom *DomTree) {
+	depths := make(map[*Var]int)
+	for _, instr := range b.Instrs {
+		if instr.Op != OpPhi {
+			for i, arg := range instr.Args {
+				if v, ok := arg.(*Var); ok {
+					instr.Args[i] = r.current(v)
+				}
+			}
+		}
+		if instr.Dst != nil {
+			original := instr.Dst
+			if v, ok := r.phis[instr]; ok {
+				original = v
+			}
+			if _, ok := depths[original]; !ok {
+				depths[original] = len(r.stacks[original])
+			}
+			instr.Dst = r.define(original)
+		}
+	}
+
+	for _, succ := range b.Succs {
+		for _, instr := range succ.Instrs {
+			if instr.Op != OpPhi {
+				break
+			}
+			for i, pred := range instr.Blocks {
+				if pred == b {
+					instr.Args[i] = r.current(r.phis[instr])
+				}
+			}
+		}
+	}
+
+	for _, child := range dom.Children(b) {
+		r.rename(child, dom)
+	}
+	for v, depth := range depths {
+		r.stacks[v] = r.stacks[v][:depth]
+	}
+}
+
+// DestroySSA replaces the phis of a function in SSA form with copies at the
+// end of the predecessors. Critical edges are split first, so that a copy
+// only runs on the edge its phi argument belongs to, and the copies of an
+// edge are ordered so that none of them overwrites a value another still
+// reads.
+func (f *Func) DestroySSA() {
+	f.ComputeCFG()
+	for _, b := range append([]*Block(nil), f.Blocks...) {
+		phis := b.Instrs[:phiCount(b)]
+		if len(phis) == 0 {
+			continue
+		}
+		b.Instrs = b.Instrs[len(phis):]
+
+		for _, pred := range b.Preds {
+			var copies []*Instr
+			for _, phi := range phis {
+				for i, from := range phi.Blocks {
+					if from == pred {
+						copies = append(copies, &Instr{Op: OpCopy, Dst: phi.Dst, Args: []Operand{phi.Args[i]}})
+						break
+					}
+				}
+			}
+			at := pred
+			if len(pred.Succs) > 1 {
+				at = f.splitEdge(pred, b)
+			}
+			term := at.Instrs[len(at.Instrs)-1]
+			at.Instrs = append(at.Instrs[:len(at.Instrs)-1], f.sequentialize(copies)...)
+			at.Instrs = append(at.Instrs, term)
+		}
+	}
+	f.ComputeCFG()
+}
+
+// splitEdge inserts a new block on the edge from pred to succ and returns it.
+func (f *Func) splitEdge(pred, succ *Block) *Block {
+	b := f.NewBlock()
+	b.Instrs = []*Instr{{Op: OpJump, Blocks: []*Block{succ}}}
+	term := pred.Terminator()
+	for i, target := range term.Blocks {
+		if target == succ {
+			term.Blocks[i] = b
+		}
+	}
+	return b
+}
+
+// sequentialize orders copies that conceptually run at the same time. A
+// copy goes first when no other copy reads its destination. When only
+// cycles remain, such as a swap, one destination is saved in a temporary.
+func (f *Func) sequentialize(copies []*Instr) []*Instr {
+	var pending, out []*Instr
+	for _, c := range copies {
+		if c.Args[0] != c.Dst {
+			pending = append(pending, c)
+		}
+	}
+	reads := func(v *Var, except *Instr) bool {
+		for _, c := range pending {
+			if c != except && c.Args[0] == v {
+				return true
+			}
+		}
+		return false
+	}
+	for len(pending) > 0 {
+		ready := -1
+		for i, c := range pending {
+			if !reads(c.Dst, c) {
+				ready = i
+				break
+			}
+		}
+		if ready < 0 {
+			dst := pending[0].Dst
+			tmp := f.NewTemp(dst.Type)
+			out = append(out, &Instr{Op: OpCopy, Dst: tmp, Args: []Operand{dst}})
+			for _, c := range pending {
+				if c.Args[0] == dst {
+					c.Args[0] = tmp
+				}
+			}
+			continue
+		}
+		out = append(out, pending[ready])
+		pending = append(pending[:ready], pending[ready+1:]...)
+	}
+	return out
+}
@@ -0,0 +1,196 @@
+package ir_test
+
+import (
+	"github.com/wevertonbruno/wb-compiler/analyzers/semantic"
+	"github.com/wevertonbruno/wb-compiler/ir"
+	"github.com/wevertonbruno/wb-compiler/ir/irtest"
+	"strings"
+	"testing"
+)
+
+// swapLoop builds a loop that prints its two variables while swapping them
+// three times, the kind of code that assignments produce.
+func swapLoop() *ir.Program {
+	fn := ir.NewFunc(ir.EntryName, semantic.Void)
+	i := fn.NewVar("i", semantic.Integer)
+	x := fn.NewVar("x", semantic.Integer)
+	y := fn.NewVar("y", semantic.Integer)
+	t := fn.NewVar("t", semantic.Integer)
+	c := fn.NewVar("c", semantic.Boolean)
+	entry, cond, body, end := fn.NewBlock(), fn.NewBlock(), fn.NewBlock(), fn.NewBlock()
+	entry.Instrs = []*ir.Instr{
+		{Op: ir.OpCopy, Dst: i, Args: []ir.Operand{ir.IntConst(0)}},
+		{Op: ir.OpCopy, Dst: x, Args: []ir.Operand{ir.IntConst(1)}},
+		{Op: ir.OpCopy, Dst: y, Args: []ir.Operand{ir.IntConst(2)}},
+		{Op: ir.OpJump, Blocks: []*ir.Block{cond}},
+	}
+	cond.Instrs = []*ir.Instr{
+		{Op: ir.OpLt, Dst: c, Args: []ir.Operand{i, ir.IntConst(3)}},
+		{Op: ir.OpBranch, Args: []ir.Operand{c}, Blocks: []*ir.Block{body, end}},
+	}
+	body.Instrs = []*ir.Instr{
+		{Op: ir.OpPrint, Args: []ir.Operand{x}},
+		{Op: ir.OpCopy, Dst: t, Args: []ir.Operand{x}},
+		{Op: ir.OpCopy, Dst: x, Args: []ir.Operand{y}},
+		{Op: ir.OpCopy, Dst: y, Args: []ir.Operand{t}},
+		{Op: ir.OpAdd, Dst: i, Args: []ir.Operand{i, ir.IntConst(1)}},
+		{Op: ir.OpJump, Blocks: []*ir.Block{cond}},
+	}
+	end.Instrs = []*ir.Instr{
+		{Op: ir.OpPrint, Args: []ir.Operand{x, y}},
+		{Op: ir.OpReturn},
+	}
+	fn.ComputeCFG()
+	return &ir.Program{Funcs: []*ir.Func{fn}, Entry: fn}
+}
+
+func TestDominators(t *testing.T) {
+	fn := irtest.Lower(t, `func f(n : Integer) {
+		if (n > 0) {
+			print(1)
+		} else {
+			print(2)
+		}
+		while n > 1 {
+			print(3)
+			return
+		}
+	}`).Func("f")
+	// b0 branches to b1 and b2, which join in b3 and fall through to b4,
+	// the loop header. b5 is the loop body and b6 the exit.
+	dom := fn.Dominators()
+	b := fn.Blocks
+	idoms := map[*ir.Block]*ir.Block{b[0]: nil, b[1]: b[0], b[2]: b[0], b[3]: b[0], b[4]: b[3], b[5]: b[4], b[6]: b[4]}
+	for block, idom := range idoms {
+		if got := dom.Idom(block); got != idom {
+			t.Errorf("wrong immediate dominator of %s. expected=%v, got=%v", block, idom, got)
+		}
+	}
+	if !dom.Dominates(b[3], b[6]) || dom.Dominates(b[1], b[3]) || !dom.Dominates(b[2], b[2]) {
+		t.Errorf("wrong dominance relation")
+	}
+
+	frontiers := dom.Frontiers()
+	expected := map[*ir.Block][]*ir.Block{b[1]: {b[3]}, b[2]: {b[3]}}
+	for _, block := range b {
+		if blocksString(frontiers[block]) != blocksString(expected[block]) {
+			t.Errorf("wrong dominance frontier of %s. expected=%v, got=%v", block, expected[block], frontiers[block])
+		}
+	}
+}
+
+func TestBuildSSA(t *testing.T) {
+	p := swapLoop()
+	p.Entry.BuildSSA()
+
+	defined := make(map[*ir.Var]bool)
+	for _, b := range p.Entry.Blocks {
+		for _, instr := range b.Instrs {
+			if instr.Dst == nil {
+				continue
+			}
+			if defined[instr.Dst] {
+				t.Errorf("%s is defined twice in\n%s", instr.Dst, p.Entry)
+			}
+			defined[instr.Dst] = true
+		}
+	}
+	header := p.Entry.Blocks[1].Instrs
+	expected := []string{
+		"i.1 = phi [i, b0], [i.2, b2]",
+		"x.1 = phi [x, b0], [x.2, b2]",
+		"y.1 = phi [y, b0], [y.2, b2]",
+	}
+	for i, want := range expected {
+		if i >= len(header) || header[i].String() != want {
+			t.Fatalf("wrong phis in the loop header. expected %q, got\n%s", want, p.Entry)
+		}
+	}
+	if strings.Contains(p.Entry.String(), "phi [t") {
+		t.Errorf("phi placed for a variable local to a block\n%s", p.Entry)
+	}
+
+	out, err := irtest.Execute(p)
+	if err != nil || out != "1\n2\n1\n2\n1\n" {
+		t.Errorf("wrong output in SSA form. got=%q, %v", out, err)
+	}
+}
+
+func TestDestroySSA(t *testing.T) {
+	p := swapLoop()
+	p.Entry.BuildSSA()
+	// propagate the copies of the swap, leaving phis that read each other
+	body := p.Entry.Blocks[2]
+	var kept []*ir.Instr
+	for _, instr := range body.Instrs {
+		if instr.Op != ir.OpCopy {
+			kept = append(kept, instr)
+		}
+	}
+	body.Instrs = kept
+	header := p.Entry.Blocks[1].Instrs
+	header[1].Args[1], header[2].Args[1] = header[2].Dst, header[1].Dst
+
+	p.Entry.DestroySSA()
+	for _, b := range p.Entry.Blocks {
+		for _, instr := range b.Instrs {
+			if instr.Op == ir.OpPhi {
+				t.Fatalf("phi left after DestroySSA\n%s", p.Entry)
+			}
+		}
+	}
+	out, err := irtest.Execute(p)
+	if err != nil || out != "1\n2\n1\n2\n1\n" {
+		t.Errorf("wrong output after DestroySSA. got=%q, %v\n%s", out, err, p.Entry)
+	}
+}
+
+func TestSSARoundTrip(t *testing.T) {
+	sources := []string{
+		`func fib(n : Integer) : Integer {
+			if (n < 2) {
+				return n
+			}
+			return fib(n - 1) + fib(n - 2)
+		}
+		print(fib(10))`,
+		`func f(n : Integer) {
+			while n > 0 {
+				var x : Integer = n * 2
+				if (x == 4) {
+					print(x)
+					return
+				}
+				print(x)
+			}
+		}
+		f(2)`,
+	}
+	for _, src := range sources {
+		p := irtest.Lower(t, src)
+		expected, err := irtest.Execute(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, fn := range p.Funcs {
+			fn.BuildSSA()
+		}
+		if out, err := irtest.Execute(p); err != nil || out != expected {
+			t.Errorf("wrong output in SSA form. expected=%q, got=%q, %v", expected, out, err)
+		}
+		for _, fn := range p.Funcs {
+			fn.DestroySSA()
+		}
+		if out, err := irtest.Execute(p); err != nil || out != expected {
+			t.Errorf("wrong output after DestroySSA. expected=%q, got=%q, %v", expected, out, err)
+		}
+	}
+}
+
+func blocksString(blocks []*ir.Block) string {
+	names := make([]string, len(blocks))
+	for i, b := range blocks {
+		names[i] = b.String()
+	}
+	return strings.Join(names, " ")
+}
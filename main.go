@@ -1,14 +1,140 @@
 package main
 
 import (
-	"github.com/wevertonbruno/wb-compiler/analyzers/lexer"
-	"github.com/wevertonbruno/wb-compiler/analyzers/parser"
-	"github.com/wevertonbruno/wb-compiler/analyzers/reader"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
 )
 
+const (
+	exitOK = iota
+	// exitCompile is returned when the source has lexical, syntax or
+	// semantic errors.
+	exitCompile
+	exitUsage
+	exitRuntime
+	exitIO
+
+	usage = `wbc is the WBlang compiler.
+
+Usage:
+
+	wbc <command> [flags] [file]
+
+The source is read from file, or from the standard input when file is
+omitted or is "-".
+
+Commands:
+
+`
+)
+
+type command struct {
+	name        string
+	description string
+	run         func(c *context, args []string) int
+}
+
+// context carries the streams of one invocation of the driver.
+type context struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"lex", "print the tokens of the source", lexCommand},
+		{"parse", "print the syntax tree of the source", parseCommand},
+		{"check", "report lexical, syntax and semantic errors, and warnings", checkCommand},
+		{"run", "interpret the program", runCommand},
+		{"disasm", "print the bytecode of the program", disasmCommand},
+		{"ir", "print the intermediate representation of the program", irCommand},
+		{"build", "compile the program", buildCommand},
+	}
+}
+
 func main() {
-	_reader := reader.NewFile("test_code.wb")
-	_lexer := lexer.NewLexer(_reader)
-	_parser := parser.NewParser(_lexer)
-	_parser.Parse()
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &context{stdin: stdin, stdout: stdout, stderr: stderr}
+	if len(args) == 0 {
+		c.usage()
+		return exitUsage
+	}
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		c.usage()
+		return exitOK
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(c, args[1:])
+		}
+	}
+	fmt.Fprintf(stderr, "wbc: unknown command %q\n", name)
+	c.usage()
+	return exitUsage
+}
+
+func (c *context) usage() {
+	out := &strings.Builder{}
+	out.WriteString(usage)
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "\t%-8s %s\n", cmd.name, cmd.description)
+	}
+	out.WriteString("\nTargets for build:\n\n")
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "\t%-12s %s\n", name, targets[name].description)
+	}
+	out.WriteString("\nOnly the x86-64 targets are optimized, at the -O level of build; the others\nare generated from the syntax tree as written.\n")
+	fmt.Fprint(c.stderr, out.String())
+}
+
+func (c *context) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: wbc %s [flags] [file]\n", name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// source reads the program named by the positional arguments of a command.
+func (c *context) source(fs *flag.FlagSet) (name string, src string, code int) {
+	switch fs.NArg() {
+	case 0:
+		name = "-"
+	case 1:
+		name = fs.Arg(0)
+	default:
+		fs.Usage()
+		return "", "", exitUsage
+	}
+
+	var data []byte
+	var err error
+	if name == "-" {
+		data, err = io.ReadAll(c.stdin)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		fmt.Fprintf(c.stderr, "wbc: %v\n", err)
+		return "", "", exitIO
+	}
+	return name, string(data), exitOK
 }
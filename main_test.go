@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		args   []string
+		input  string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{[]string{"run"}, "print(1 + 2)", exitOK, "3\n", ""},
+		{[]string{"run", "-engine", "vm"}, "func main() {\n print(2 * 3)\n}", exitOK, "6\n", ""},
+		{[]string{"run", "-engine", "vm"}, "print(1 / 0)", exitRuntime, "", "<stdin>:1:9: runtime error. integer division by zero\n"},
+		{[]string{"run", "-engine", "jit"}, "", exitUsage, "", "wbc: unknown engine \"jit\"\n"},
+		{[]string{"disasm"}, "print(1)", exitOK, "constants:\n0000 Integer 1\n\n<main>:\n0000 OpConstant 0\n0003 OpPrint\n0004 OpPop\n", ""},
+		{[]string{"ir"}, "print(1 + 2)", exitOK, "func <main>() {\nb0:\n    %1 = add 1, 2\n    print %1\n    ret\n}\n", ""},
+		{[]string{"ir", "-O", "1"}, "print(2 * 3 + 1 / 0)", exitOK, "func <main>() {\nb0:\n    %2 = div 1, 0\n    %3 = add 6, %2\n    print %3\n    ret\n}\n",
+			"<stdin>:1:17: warning. integer division by zero\n"},
+		{[]string{"ir", "-O", "1"}, "if (false) {\n print(1)\n}", exitOK, "func <main>() {\nb0:\n    jmp b2\nb2: ; preds b0\n    ret\n}\n",
+			"<stdin>:2:2: warning. unreachable code\n"},
+		{[]string{"ir", "-O", "3"}, "print(1)", exitUsage, "", "wbc: unknown optimization level 3\n"},
+		{[]string{"ir", "-intervals"}, "print(2)", exitOK, "func <main>\nb0:\n   1  print 2\n   2  ret\nintervals:\n", ""},
+		{[]string{"run", "-"}, "func main() {\n print(true)\n}", exitOK, "true\n", ""},
+		{[]string{"parse"}, "var a : Integer = 1 + 2 * 3\na", exitOK, "var a : Integer = (1 + (2 * 3))\na\n", ""},
+		{[]string{"lex"}, "a = 1", exitOK,
+			"{Kind: <identifier>, Spelling: a, Position: {1 1}}\n" +
+				"{Kind: =, Spelling: =, Position: {1 3}}\n" +
+				"{Kind: <integer>, Spelling: 1, Position: {1 5}}\n", ""},
+		{[]string{"lex"}, "$", exitCompile, "", "<stdin>:1:1: lexical error. Unknown token: $\n"},
+		{[]string{"lex"}, `'a' ""`, exitOK,
+			"{Kind: <char>, Spelling: a, Position: {1 1}}\n" +
+				"{Kind: <string>, Spelling: , Position: {1 5}}\n", ""},
+		{[]string{"lex"}, "'ab'", exitCompile, "{Kind: <char>, Spelling: a, Position: {1 1}}\n",
+			"<stdin>:1:1: lexical error. Char literal must hold a single character: 'ab'\n"},
+		{[]string{"lex"}, "'a", exitCompile, "{Kind: <char>, Spelling: a, Position: {1 1}}\n",
+			"<stdin>:1:1: lexical error. Unterminated char literal\n"},
+		{[]string{"lex"}, `"a\tb\u{e9}" "\"`, exitCompile,
+			"{Kind: <string>, Spelling: a\tb\u00e9, Position: {1 1}}\n" +
+				"{Kind: <string>, Spelling: \", Position: {1 14}}\n",
+			"<stdin>:1:14: lexical error. Unterminated string literal\n"},
+		{[]string{"lex"}, `"\q"`, exitCompile, "{Kind: <string>, Spelling: , Position: {1 1}}\n",
+			"<stdin>:1:2: lexical error. Unknown escape sequence: \\q\n"},
+		{[]string{"lex"}, "`a\n\\n`\n`", exitCompile,
+			"{Kind: <string>, Spelling: a\n\\n, Position: {1 1}}\n" +
+				"{Kind: <new line>, Spelling: <new line>, Position: {3 0}}\n" +
+				"{Kind: <string>, Spelling: , Position: {3 1}}\n",
+			"<stdin>:3:1: lexical error. Unterminated raw string literal\n"},
+		{[]string{"lex"}, `"a${b}c\${d}"`, exitOK,
+			"{Kind: <string start>, Spelling: a, Position: {1 1}}\n" +
+				"{Kind: <identifier>, Spelling: b, Position: {1 5}}\n" +
+				"{Kind: <string end>, Spelling: c${d}, Position: {1 6}}\n", ""},
+		{[]string{"lex"}, `"${ {} }"`, exitOK,
+			"{Kind: <string start>, Spelling: , Position: {1 1}}\n" +
+				"{Kind: {, Spelling: {, Position: {1 5}}\n" +
+				"{Kind: }, Spelling: }, Position: {1 6}}\n" +
+				"{Kind: <string end>, Spelling: , Position: {1 8}}\n", ""},
+		{[]string{"lex"}, "\"${a", exitCompile,
+			"{Kind: <string start>, Spelling: , Position: {1 1}}\n" +
+				"{Kind: <identifier>, Spelling: a, Position: {1 4}}\n" +
+				"{Kind: <string end>, Spelling: , Position: {1 4}}\n",
+			"<stdin>:1:1: lexical error. Unterminated string literal\n"},
+		{[]string{"lex"}, "a && b || c & d", exitCompile,
+			"{Kind: <identifier>, Spelling: a, Position: {1 1}}\n" +
+				"{Kind: &&, Spelling: &&, Position: {1 3}}\n" +
+				"{Kind: <identifier>, Spelling: b, Position: {1 6}}\n" +
+				"{Kind: ||, Spelling: ||, Position: {1 8}}\n" +
+				"{Kind: <identifier>, Spelling: c, Position: {1 11}}\n" +
+				"{Kind: <identifier>, Spelling: d, Position: {1 15}}\n",
+			"<stdin>:1:13: lexical error. Unknown token: &\n"},
+		{[]string{"check"}, "var a : Integer = true", exitCompile, "",
+			"<stdin>:1:19: type error. cannot use Boolean as Integer in declaration of a\n"},
+		{[]string{"check"}, "var a : Integer = 1", exitOK, "", ""},
+		{[]string{"check"}, "func main() {\n print(1 / 0)\n return\n print(2)\n}", exitOK, "",
+			"<stdin>:2:10: warning. integer division by zero\n<stdin>:4:2: warning. unreachable code\n"},
+		{[]string{"build", "-target", "c", "-o", os.DevNull}, "if (false) {\n print(1)\n}", exitOK, "",
+			"<stdin>:2:2: warning. unreachable code\n"},
+		{[]string{"parse"}, "var a = 1", exitCompile, "", "<stdin>:1:7: parser error. expected :, got =\n"},
+		{[]string{"run"}, "print(1 / 0)", exitRuntime, "", "<stdin>:1:9: runtime error. integer division by zero\n"},
+		{[]string{"run", "a.wb", "b.wb"}, "", exitUsage, "", "Usage: wbc run [flags] [file]\n"},
+		{[]string{"run", "does-not-exist.wb"}, "", exitIO, "", "wbc: open does-not-exist.wb: no such file or directory\n"},
+		{[]string{"build", "-target", "nothing"}, "", exitUsage, "", "wbc: unknown target \"nothing\"\n"},
+		{[]string{"build", "-target", "x86-64-asm", "-o", "/nonexistent/out.s"}, "print(1)", exitIO, "",
+			"wbc: open /nonexistent/out.s: no such file or directory\n"},
+		{[]string{"frobnicate"}, "", exitUsage, "", "wbc: unknown command \"frobnicate\"\n"},
+		{[]string{}, "", exitUsage, "", "wbc is the WBlang compiler.\n"},
+	}
+	for _, tt := range tests {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		code := run(tt.args, strings.NewReader(tt.input), stdout, stderr)
+		if code != tt.code {
+			t.Errorf("wbc %v: wrong exit code. want=%d, got=%d (stderr=%q)", tt.args, tt.code, code, stderr)
+		}
+		if stdout.String() != tt.stdout {
+			t.Errorf("wbc %v: wrong stdout. want=%q, got=%q", tt.args, tt.stdout, stdout)
+		}
+		if !strings.HasPrefix(stderr.String(), tt.stderr) {
+			t.Errorf("wbc %v: wrong stderr. want prefix %q, got=%q", tt.args, tt.stderr, stderr)
+		}
+	}
+}
+
+func TestBuildKeepsSource(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "prog")
+	if err := os.WriteFile(src, []byte("print(1)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"build", "-target", "x86-64", src},
+		{"build", "-target", "c", "-o", src, src},
+	} {
+		stderr := &bytes.Buffer{}
+		if code := run(args, strings.NewReader(""), &bytes.Buffer{}, stderr); code != exitUsage {
+			t.Errorf("wbc %v: wrong exit code. want=%d, got=%d (stderr=%q)", args, exitUsage, code, stderr)
+		}
+		if b, err := os.ReadFile(src); err != nil || string(b) != "print(1)" {
+			t.Errorf("wbc %v: the source was replaced. got=%q, %v", args, b, err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package object
+
+import (
+	"fmt"
+	"github.com/wevertonbruno/wb-compiler/analyzers/token"
+	"github.com/wevertonbruno/wb-compiler/ast"
+	"github.com/wevertonbruno/wb-compiler/code"
+	"math"
+	"strconv"
+)
+
+type ObjectType string
+
+const (
+	INTEGER_OBJ  = "Integer"
+	DECIMAL_OBJ  = "Decimal"
+	STRING_OBJ   = "String"
+	CHAR_OBJ     = "Char"
+	BOOLEAN_OBJ  = "Boolean"
+	VOID_OBJ     = "Void"
+	RETURN_OBJ   = "Return"
+	FUNCTION_OBJ = "Function"
+	BUILTIN_OBJ  = "Builtin"
+
+	COMPILED_FUNCTION_OBJ = "CompiledFunction"
+)
+
+var (
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+	VOID  = &Void{}
+)
+
+type Object interface {
+	Type() ObjectType
+	Inspect() string
+}
+
+type Integer struct {
+	Value int64
+}
+
+type Decimal struct {
+	Value float64
+}
+
+type String struct {
+	Value string
+}
+
+type Char struct {
+	Value byte
+}
+
+type Boolean struct {
+	Value bool
+}
+
+type Void struct{}
+
+type ReturnValue struct {
+	Value Object
+}
+
+type Function struct {
+	Decl *ast.FuncDecl
+	Env  *Environment
+}
+
+type BuiltinFunction func(args ...Object) Object
+
+type Builtin struct {
+	Name string
+	Fn   BuiltinFunction
+}
+
+type CompiledFunction struct {
+	Name          string
+	Instructions  code.Instructions
+	NumLocals     int
+	NumParameters int
+	// Positions maps the offset of the instructions that may fail at runtime
+	// to the token they were compiled from.
+	Positions map[int]token.Token
+}
+
+func NativeBoolean(value bool) *Boolean {
+	if value {
+		return TRUE
+	}
+	return FALSE
+}
+
+// FormatDecimal formats a Decimal the way C's printf %g does, so that every
+// backend prints the same text for the same value.
+func FormatDecimal(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "nan"
+	case math.IsInf(value, 1):
+		return "inf"
+	case math.IsInf(value, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(value, 'g', 6, 64)
+}
+
+func (o *Integer) Type() ObjectType     { return INTEGER_OBJ }
+func (o *Decimal) Type() ObjectType     { return DECIMAL_OBJ }
+func (o *String) Type() ObjectType      { return STRING_OBJ }
+func (o *Char) Type() ObjectType        { return CHAR_OBJ }
+func (o *Boolean) Type() ObjectType     { return BOOLEAN_OBJ }
+func (o *Void) Type() ObjectType        { return VOID_OBJ }
+func (o *ReturnValue) Type() ObjectType { return RETURN_OBJ }
+func (o *Function) Type() ObjectType    { return FUNCTION_OBJ }
+func (o *Builtin) Type() ObjectType     { return BUILTIN_OBJ }
+func (o *CompiledFunction) Type() ObjectType {
+	return COMPILED_FUNCTION_OBJ
+}
+
+func (o *Integer) Inspect() string     { return strconv.FormatInt(o.Value, 10) }
+func (o *Decimal) Inspect() string     { return FormatDecimal(o.Value) }
+func (o *String) Inspect() string      { return o.Value }
+func (o *Char) Inspect() string        { return string([]byte{o.Value}) }
+func (o *Boolean) Inspect() string     { return strconv.FormatBool(o.Value) }
+func (o *Void) Inspect() string        { return "" }
+func (o *ReturnValue) Inspect() string { return o.Value.Inspect() }
+func (o *Function) Inspect() string    { return fmt.Sprintf("func %s", o.Decl.Name.Value) }
+func (o *Builtin) Inspect() string     { return fmt.Sprintf("builtin %s", o.Name) }
+func (o *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("compiled func %s", o.Name)
+}
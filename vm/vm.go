@@ -0,0 +1,326 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"github.com/wevertonbruno/wb-compiler/analyzers/diagnostic"
+	"github.com/wevertonbruno/wb-compiler/code"
+	"github.com/wevertonbruno/wb-compiler/compiler"
+	"github.com/wevertonbruno/wb-compiler/object"
+	"io"
+	"strings"
+)
+
+const (
+	StackSize   = 2048
+	GlobalsSize = 65536
+	MaxFrames   = 1024
+
+	runtimeError = "runtime error. %v"
+
+	divisionByZeroError  = "integer division by zero"
+	stackOverflowError   = "stack overflow"
+	unknownOperatorError = "unknown operator: %s %s %s"
+)
+
+type (
+	Frame struct {
+		fn          *object.CompiledFunction
+		ip          int
+		basePointer int
+	}
+
+	VM struct {
+		constants []object.Object
+		globals   []object.Object
+		out       io.Writer
+
+		stack []object.Object
+		sp    int // always points to the next free slot, the top is stack[sp-1]
+
+		frames      []*Frame
+		framesIndex int
+	}
+
+	// abort unwinds the vm when the program fails at runtime
+	abort struct {
+		message string
+	}
+)
+
+func NewFrame(fn *object.CompiledFunction, basePointer int) *Frame {
+	return &Frame{fn: fn, ip: -1, basePointer: basePointer}
+}
+
+func (f *Frame) Instructions() code.Instructions {
+	return f.fn.Instructions
+}
+
+func New(bytecode *compiler.Bytecode, out io.Writer) *VM {
+	frames := make([]*Frame, MaxFrames)
+	frames[0] = NewFrame(bytecode.Main, 0)
+	return &VM{
+		constants:   bytecode.Constants,
+		globals:     make([]object.Object, GlobalsSize),
+		out:         out,
+		stack:       make([]object.Object, StackSize),
+		frames:      frames,
+		framesIndex: 1,
+	}
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) {
+	if vm.framesIndex == MaxFrames {
+		vm.error(stackOverflowError)
+	}
+	vm.frames[vm.framesIndex] = f
+	vm.framesIndex++
+}
+
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
+
+func (vm *VM) error(message string) {
+	panic(abort{message: message})
+}
+
+// Run executes the program. Runtime errors carry the source position of the
+// failing instruction when the compiler recorded one.
+func (vm *VM) Run() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			a, ok := r.(abort)
+			if !ok {
+				panic(r)
+			}
+			message := fmt.Sprintf(runtimeError, a.message)
+			err = errors.New(message)
+			// callers wait on their OpCall, so the innermost recorded
+			// position is the closest source location of the failure
+			for i := vm.framesIndex - 1; i >= 0; i-- {
+				frame := vm.frames[i]
+				if tok, ok := frame.fn.Positions[frame.ip]; ok {
+					err = diagnostic.New(message, tok)
+					break
+				}
+			}
+		}
+	}()
+
+	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		vm.currentFrame().ip++
+		frame := vm.currentFrame()
+		ip := frame.ip
+		ins := frame.Instructions()
+		op := code.Opcode(ins[ip])
+
+		switch op {
+		case code.OpConstant:
+			index := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			vm.push(vm.constants[index])
+		case code.OpPop:
+			vm.pop()
+		case code.OpTrue:
+			vm.push(object.TRUE)
+		case code.OpFalse:
+			vm.push(object.FALSE)
+
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv,
+			code.OpEqual, code.OpNotEqual, code.OpLessThan, code.OpLessEqual,
+			code.OpGreaterThan, code.OpGreaterEqual:
+			right := vm.pop()
+			left := vm.pop()
+			vm.push(vm.executeBinaryOperation(op, left, right))
+		case code.OpMinus:
+			switch operand := vm.pop().(type) {
+			case *object.Integer:
+				vm.push(&object.Integer{Value: -operand.Value})
+			case *object.Decimal:
+				vm.push(&object.Decimal{Value: -operand.Value})
+			default:
+				vm.error(fmt.Sprintf(unknownOperatorError, "", "-", operand.Type()))
+			}
+		case code.OpNot:
+			vm.push(object.NativeBoolean(vm.pop() != object.TRUE))
+		case code.OpConcat:
+			count := int(code.ReadUint8(ins[ip+1:]))
+			frame.ip++
+			var out strings.Builder
+			for _, part := range vm.stack[vm.sp-count : vm.sp] {
+				out.WriteString(part.Inspect())
+			}
+			vm.sp -= count
+			vm.push(&object.String{Value: out.String()})
+
+		case code.OpJump:
+			frame.ip = int(code.ReadUint16(ins[ip+1:])) - 1
+		case code.OpJumpNotTruthy:
+			target := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+			if vm.pop() != object.TRUE {
+				frame.ip = target - 1
+			}
+
+		case code.OpGetGlobal:
+			index := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			vm.push(vm.globals[index])
+		case code.OpSetGlobal:
+			index := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			vm.globals[index] = vm.pop()
+		case code.OpGetLocal:
+			index := code.ReadUint8(ins[ip+1:])
+			frame.ip++
+			vm.push(vm.stack[frame.basePointer+int(index)])
+		case code.OpSetLocal:
+			index := code.ReadUint8(ins[ip+1:])
+			frame.ip++
+			vm.stack[frame.basePointer+int(index)] = vm.pop()
+
+		case code.OpCall:
+			numArgs := int(code.ReadUint8(ins[ip+1:]))
+			vm.callFunction(numArgs)
+		case code.OpReturnValue:
+			value := vm.pop()
+			vm.returnFrom(value)
+		case code.OpReturn:
+			vm.returnFrom(object.VOID)
+		case code.OpPrint:
+			fmt.Fprintln(vm.out, vm.pop().Inspect())
+			vm.push(object.VOID)
+		}
+	}
+	return nil
+}
+
+func (vm *VM) push(o object.Object) {
+	if vm.sp >= StackSize {
+		vm.error(stackOverflowError)
+	}
+	vm.stack[vm.sp] = o
+	vm.sp++
+}
+
+func (vm *VM) pop() object.Object {
+	o := vm.stack[vm.sp-1]
+	vm.sp--
+	return o
+}
+
+func (vm *VM) callFunction(numArgs int) {
+	fn := vm.stack[vm.sp-1-numArgs].(*object.CompiledFunction)
+	basePointer := vm.sp - numArgs
+	if basePointer+fn.NumLocals >= StackSize {
+		vm.error(stackOverflowError)
+	}
+	vm.pushFrame(NewFrame(fn, basePointer))
+	vm.sp = basePointer + fn.NumLocals
+}
+
+func (vm *VM) returnFrom(value object.Object) {
+	frame := vm.popFrame()
+	// drop the locals and the function itself
+	vm.sp = frame.basePointer - 1
+	vm.push(value)
+	// the caller resumes after the operand of its OpCall
+	vm.currentFrame().ip++
+}
+
+func (vm *VM) executeBinaryOperation(op code.Opcode, left, right object.Object) object.Object {
+	switch {
+	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
+		return vm.executeIntegerOperation(op, left.(*object.Integer).Value, right.(*object.Integer).Value)
+	case left.Type() == object.DECIMAL_OBJ && right.Type() == object.DECIMAL_OBJ:
+		return vm.executeDecimalOperation(op, left.(*object.Decimal).Value, right.(*object.Decimal).Value)
+	case left.Type() == object.CHAR_OBJ && right.Type() == object.CHAR_OBJ:
+		// chars are ordered by their code
+		l, r := int64(left.(*object.Char).Value), int64(right.(*object.Char).Value)
+		return vm.executeIntegerOperation(op, l, r)
+	case op == code.OpEqual && left.Type() == right.Type():
+		return object.NativeBoolean(left.Inspect() == right.Inspect())
+	case op == code.OpNotEqual && left.Type() == right.Type():
+		return object.NativeBoolean(left.Inspect() != right.Inspect())
+	}
+	def, _ := code.Lookup(byte(op))
+	vm.error(fmt.Sprintf(unknownOperatorError, left.Type(), def.Name, right.Type()))
+	return nil
+}
+
+func (vm *VM) executeIntegerOperation(op code.Opcode, left, right int64) object.Object {
+	switch op {
+	case code.OpAdd:
+		return &object.Integer{Value: left + right}
+	case code.OpSub:
+		return &object.Integer{Value: left - right}
+	case code.OpMul:
+		return &object.Integer{Value: left * right}
+	case code.OpDiv:
+		if right == 0 {
+			vm.error(divisionByZeroError)
+		}
+		return &object.Integer{Value: left / right}
+	}
+	return vm.compare(op, compareOrdered(left, right))
+}
+
+func (vm *VM) executeDecimalOperation(op code.Opcode, left, right float64) object.Object {
+	switch op {
+	case code.OpAdd:
+		return &object.Decimal{Value: left + right}
+	case code.OpSub:
+		return &object.Decimal{Value: left - right}
+	case code.OpMul:
+		return &object.Decimal{Value: left * right}
+	case code.OpDiv:
+		return &object.Decimal{Value: left / right}
+	case code.OpEqual:
+		return object.NativeBoolean(left == right)
+	case code.OpNotEqual:
+		return object.NativeBoolean(left != right)
+	case code.OpLessThan:
+		return object.NativeBoolean(left < right)
+	case code.OpLessEqual:
+		return object.NativeBoolean(left <= right)
+	case code.OpGreaterThan:
+		return object.NativeBoolean(left > right)
+	case code.OpGreaterEqual:
+		return object.NativeBoolean(left >= right)
+	}
+	return nil
+}
+
+func compareOrdered(left, right int64) int {
+	switch {
+	case left < right:
+		return -1
+	case left > right:
+		return 1
+	}
+	return 0
+}
+
+func (vm *VM) compare(op code.Opcode, cmp int) object.Object {
+	switch op {
+	case code.OpEqual:
+		return object.NativeBoolean(cmp == 0)
+	case code.OpNotEqual:
+		return object.NativeBoolean(cmp != 0)
+	case code.OpLessThan:
+		return object.NativeBoolean(cmp < 0)
+	case code.OpLessEqual:
+		return object.NativeBoolean(cmp <= 0)
+	case code.OpGreaterThan:
+		return object.NativeBoolean(cmp > 0)
+	case code.OpGreaterEqual:
+		return object.NativeBoolean(cmp >= 0)
+	}
+	return nil
+}
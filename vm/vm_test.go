@@ -0,0 +1,51 @@
+package vm
+
+import (
+	"bytes"
+	"github.com/wevertonbruno/wb-compiler/compiler"
+	"github.com/wevertonbruno/wb-compiler/wbtest"
+	"testing"
+)
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`func loop(n : Integer) : Integer {
+			return loop(n + 1)
+		}
+		print(loop(0))`, "2:15: runtime error. stack overflow"},
+	}
+	for _, tt := range tests {
+		err := New(compile(t, tt.input), &bytes.Buffer{}).Run()
+		if err == nil {
+			t.Fatalf("expected a runtime error for %q", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestPrograms(t *testing.T) {
+	wbtest.RunPrograms(t, func(t *testing.T, source string) (string, error) {
+		out := &bytes.Buffer{}
+		vm := New(compile(t, source), out)
+		err := vm.Run()
+		if err == nil && vm.sp != 0 {
+			t.Errorf("stack is not empty. sp=%d", vm.sp)
+		}
+		return out.String(), err
+	})
+}
+
+func compile(t *testing.T, input string) *compiler.Bytecode {
+	t.Helper()
+	prog, info := wbtest.Check(t, input)
+	c := compiler.New(info)
+	if err := c.Compile(prog); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	return c.Bytecode()
+}
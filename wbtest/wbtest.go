@@ -0,0 +1,124 @@
+// Package wbtest helps testing the interpreters and the code generators. It
+// checks sources and holds the programs that every one of them runs: each
+// program is a .wb file in the programs directory, next to a .out file of
+// the same name with the output it must print and, when it ends with a
+// runtime error, an .err file with the error.
+package wbtest
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/wevertonbruno/wb-compiler/analyzers/lexer"
+	"github.com/wevertonbruno/wb-compiler/analyzers/parser"
+	"github.com/wevertonbruno/wb-compiler/analyzers/reader"
+	"github.com/wevertonbruno/wb-compiler/analyzers/semantic"
+	"github.com/wevertonbruno/wb-compiler/ast"
+	"io/fs"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+)
+
+// exitRuntime is the exit status of a compiled program that fails at
+// runtime.
+const exitRuntime = 3
+
+//go:embed programs
+var files embed.FS
+
+// Program is a source and what running it must do.
+type Program struct {
+	Name   string
+	Source string
+	Output string
+	// Error is the runtime error the program ends with, if any
+	Error string
+}
+
+// Programs returns the programs of the corpus, sorted by name.
+func Programs() []Program {
+	sources, err := fs.Glob(files, "programs/*.wb")
+	if err != nil {
+		panic(err)
+	}
+	programs := make([]Program, len(sources))
+	for i, source := range sources {
+		name := strings.TrimSuffix(source, ".wb")
+		programs[i] = Program{
+			Name:   path.Base(name),
+			Source: read(source),
+			Output: read(name + ".out"),
+		}
+		if failure, err := files.ReadFile(name + ".err"); err == nil {
+			programs[i].Error = strings.TrimSuffix(string(failure), "\n")
+		}
+	}
+	return programs
+}
+
+func read(name string) string {
+	b, err := files.ReadFile(name)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+// RunPrograms runs every program of the corpus in a subtest of its own
+// with run, which returns what the program printed and the runtime error
+// it ended with, and checks them.
+func RunPrograms(t *testing.T, run func(t *testing.T, source string) (string, error)) {
+	t.Helper()
+	for _, program := range Programs() {
+		program := program
+		t.Run(program.Name, func(t *testing.T) {
+			output, err := run(t, program.Source)
+			failure := ""
+			if err != nil {
+				failure = err.Error()
+			}
+			if output != program.Output {
+				t.Errorf("wrong output. want=%q, got=%q", program.Output, output)
+			}
+			if failure != program.Error {
+				t.Errorf("wrong error. want=%q, got=%q", program.Error, failure)
+			}
+		})
+	}
+}
+
+// Check parses and checks a source, failing the test on errors.
+func Check(t *testing.T, input string) (*ast.Prog, *semantic.Info) {
+	t.Helper()
+	prog, errs := parser.NewParser(lexer.NewLexer(reader.NewInput(input))).Parse()
+	if len(errs) == 0 {
+		var info *semantic.Info
+		if info, errs = semantic.Check(prog); len(errs) == 0 {
+			return prog, info
+		}
+	}
+	for _, err := range errs {
+		t.Errorf("error: %v", err)
+	}
+	t.FailNow()
+	return nil, nil
+}
+
+// Exec runs a compiled program and returns what it printed. A program that
+// fails at runtime exits with status 3 after reporting the error, which is
+// returned; any other failure is returned with what the program reported.
+func Exec(cmd *exec.Cmd) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if exit, ok := err.(*exec.ExitError); ok && exit.ExitCode() == exitRuntime {
+		err = errors.New(strings.TrimSuffix(stderr.String(), "\n"))
+	} else if err != nil {
+		err = fmt.Errorf("%v: %s", err, stderr.String())
+	}
+	return stdout.String(), err
+}